@@ -0,0 +1,69 @@
+package wordpress
+
+import (
+	"github.com/elgris/sqrl"
+	"golang.org/x/net/context"
+)
+
+// ArchiveEntry represents the number of published posts in a month
+type ArchiveEntry struct {
+	Year  int `json:"year"`
+	Month int `json:"month"`
+	Count int `json:"count"`
+}
+
+// GetArchiveCounts returns the number of published objects of the type in each month, newest first
+//
+// Only the objects related to any of the terms are counted if term ids are given.
+func GetArchiveCounts(c context.Context, postType PostType, termIds ...int64) ([]ArchiveEntry, error) {
+	c, span := startSpan(c, "/wordpress.GetArchiveCounts")
+	defer span.End()
+
+	if postType == "" {
+		postType = PostTypePost
+	}
+
+	year, month := datePart(c, "YEAR", "p.post_date"), datePart(c, "MONTH", "p.post_date")
+
+	q := sqrl.Select(year, month, "COUNT(DISTINCT p.ID)").
+		From(table(c, "posts")+" AS p").
+		Where(sqrl.Eq{"p.post_type": string(postType), "p.post_status": string(PostStatusPublish)}).
+		GroupBy(year, month).
+		OrderBy(year+" DESC", month+" DESC")
+
+	if len(termIds) > 0 {
+		q = q.Join(table(c, "term_relationships") + " AS tr ON tr.object_id = p.ID").
+			Join(table(c, "term_taxonomy") + " AS tt ON tt.term_taxonomy_id = tr.term_taxonomy_id").
+			Where(sqrl.Eq{"tt.term_id": termIds})
+	}
+
+	stmt, args, err := q.ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	spanString(span, "wp/archive/query", stmt)
+
+	rows, err := database(c).QueryContext(c, stmt, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	var ret []ArchiveEntry
+	for rows.Next() {
+		var entry ArchiveEntry
+		if err := rows.Scan(&entry.Year, &entry.Month, &entry.Count); err != nil {
+			return nil, err
+		}
+
+		ret = append(ret, entry)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return ret, nil
+}
@@ -1,11 +1,27 @@
 package wordpress
 
 import (
-	"go.opencensus.io/trace"
+	"fmt"
 	"github.com/wulijun/go-php-serialize/phpserialize"
 	"golang.org/x/net/context"
+	"html"
+	"html/template"
+	"sort"
+	"strconv"
+	"strings"
 )
 
+// ImageSize represents an intermediate size of an image attachment
+type ImageSize struct {
+	// The file name of the crop, in the same directory as the full size image
+	File string `json:"file"`
+
+	Width  int `json:"width"`
+	Height int `json:"height"`
+
+	MimeType string `json:"mime_type"`
+}
+
 // Attachment represents a WordPress attachment
 type Attachment struct {
 	Object
@@ -19,11 +35,123 @@ type Attachment struct {
 	AltText string `json:"alt_text"`
 
 	Url string `json:"url,omitempty"`
+
+	// The intermediate sizes of the image keyed by name, i.e. thumbnail, medium, large
+	Sizes map[string]ImageSize `json:"sizes,omitempty"`
+}
+
+// SizeURL returns the url of the named size of the image
+//
+// Returns the url of the full size image if the size does not exist
+func (a *Attachment) SizeURL(size string) string {
+	s, ok := a.Sizes[size]
+	if !ok || s.File == "" {
+		return a.Url
+	}
+
+	return a.Url[:strings.LastIndex(a.Url, "/")+1] + s.File
+}
+
+// Srcset returns the value of a `srcset` attribute listing all of the image's sizes
+//
+// Sizes without a recorded width are left out, as are duplicate widths.
+func (a *Attachment) Srcset() string {
+	type candidate struct {
+		url   string
+		width int
+	}
+
+	var candidates []candidate
+	if a.Width > 0 {
+		candidates = append(candidates, candidate{a.Url, a.Width})
+	}
+
+	for name, size := range a.Sizes {
+		if size.Width > 0 && size.File != "" {
+			candidates = append(candidates, candidate{a.SizeURL(name), size.Width})
+		}
+	}
+
+	// sorted by url as well for a stable result when widths are equal
+	sort.Slice(candidates, func(i, j int) bool {
+		if candidates[i].width != candidates[j].width {
+			return candidates[i].width < candidates[j].width
+		}
+
+		return candidates[i].url < candidates[j].url
+	})
+
+	var parts []string
+	seen := make(map[int]bool)
+	for _, cand := range candidates {
+		if seen[cand.width] {
+			continue
+		}
+
+		seen[cand.width] = true
+		parts = append(parts, cand.url+" "+strconv.Itoa(cand.width)+"w")
+	}
+
+	return strings.Join(parts, ", ")
+}
+
+// ImgTag returns a responsive `<img>` element for the full size image
+func (a *Attachment) ImgTag(alt string) template.HTML {
+	attrs := []string{`src="` + html.EscapeString(a.Url) + `"`}
+
+	if a.Width > 0 && a.Height > 0 {
+		attrs = append(attrs, fmt.Sprintf(`width="%d" height="%d"`, a.Width, a.Height))
+	}
+
+	if srcset := a.Srcset(); srcset != "" {
+		attrs = append(attrs, `srcset="`+html.EscapeString(srcset)+`"`)
+
+		// like WordPress, the image fills the viewport up to its full width
+		if a.Width > 0 {
+			attrs = append(attrs, fmt.Sprintf(`sizes="(max-width: %dpx) 100vw, %dpx"`, a.Width, a.Width))
+		}
+	}
+
+	attrs = append(attrs, `alt="`+html.EscapeString(alt)+`"`)
+
+	return template.HTML("<img " + strings.Join(attrs, " ") + ">")
+}
+
+// decodeImageSizes decodes the `sizes` array of the attachment metadata
+func decodeImageSizes(sizes map[interface{}]interface{}) map[string]ImageSize {
+	ret := make(map[string]ImageSize)
+	for name, size := range sizes {
+		name, ok := name.(string)
+		if !ok {
+			continue
+		}
+
+		size, ok := size.(map[interface{}]interface{})
+		if !ok {
+			continue
+		}
+
+		var s ImageSize
+		s.File, _ = size["file"].(string)
+		s.MimeType, _ = size["mime-type"].(string)
+
+		if width, ok := size["width"].(int64); ok {
+			s.Width = int(width)
+		}
+
+		if height, ok := size["height"].(int64); ok {
+			s.Height = int(height)
+		}
+
+		ret[name] = s
+	}
+
+	return ret
 }
 
 // GetAttachments gets all attachment data from the database
 func GetAttachments(c context.Context, attachmentIds ...int64) ([]*Attachment, error) {
-	c, span := trace.StartSpan(c, "/wordpress.GetAttachments")
+	c, span := startSpan(c, "/wordpress.GetAttachments")
 	defer span.End()
 
 	if len(attachmentIds) == 0 {
@@ -72,6 +200,10 @@ func GetAttachments(c context.Context, attachmentIds ...int64) ([]*Attachment, e
 						att.Height = int(height)
 					}
 
+					if sizes, ok := meta["sizes"].(map[interface{}]interface{}); ok {
+						att.Sizes = decodeImageSizes(sizes)
+					}
+
 					if imageMeta, ok := meta["image_meta"].(map[interface{}]interface{}); ok {
 						if caption, ok := imageMeta["caption"].(string); ok {
 							att.Caption = caption
@@ -93,15 +225,89 @@ func GetAttachments(c context.Context, attachmentIds ...int64) ([]*Attachment, e
 		}
 	}
 
-	return ret, nil
+	return afterGetAttachmentsFilters.apply(c, ret)
 }
 
 // QueryAttachments returns the ids of the attachments that match the query
 func QueryAttachments(c context.Context, opts *ObjectQueryOptions) (Iterator, error) {
-	c, span := trace.StartSpan(c, "/wordpress.QueryAttachments")
+	c, span := startSpan(c, "/wordpress.QueryAttachments")
 	defer span.End()
 
+	opts = opts.clone()
 	opts.PostType = PostTypeAttachment
 
 	return queryObjects(c, opts)
 }
+
+// CountAttachments returns the total number of attachments that match the query
+//
+// Pagination options such as `After` and `Limit` are ignored
+func CountAttachments(c context.Context, opts *ObjectQueryOptions) (int, error) {
+	c, span := startSpan(c, "/wordpress.CountAttachments")
+	defer span.End()
+
+	opts = opts.clone()
+	opts.PostType = PostTypeAttachment
+
+	return countObjects(c, opts)
+}
+
+// GetFeaturedImage gets the post's featured image
+//
+// Returns nil if the post has no featured image or it has been deleted
+func (p *Post) GetFeaturedImage(c context.Context) (*Attachment, error) {
+	images, err := GetFeaturedImages(c, []*Post{p})
+	if err != nil {
+		return nil, err
+	}
+
+	return images[0], nil
+}
+
+// GetFeaturedImages gets the featured images of all the posts with a single lookup
+//
+// The returned slice is parallel to the posts,
+// with nil for the posts that have no featured image or whose image has been deleted
+func GetFeaturedImages(c context.Context, posts []*Post) ([]*Attachment, error) {
+	c, span := startSpan(c, "/wordpress.GetFeaturedImages")
+	defer span.End()
+
+	var ids []int64
+	for _, p := range posts {
+		if p.FeaturedMediaId != 0 {
+			ids = append(ids, p.FeaturedMediaId)
+		}
+	}
+
+	ret := make([]*Attachment, len(posts))
+	if len(ids) == 0 {
+		return ret, nil
+	}
+
+	ids, _ = dedupe(ids)
+
+	attachments, err := GetAttachments(c, ids...)
+	if mre, ok := err.(MissingResourcesError); ok {
+		if ids = without(ids, mre); len(ids) == 0 {
+			return ret, nil
+		}
+
+		attachments, err = GetAttachments(c, ids...)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	byId := make(map[int64]*Attachment)
+	for _, att := range attachments {
+		if att != nil {
+			byId[att.Id] = att
+		}
+	}
+
+	for i, p := range posts {
+		ret[i] = byId[p.FeaturedMediaId]
+	}
+
+	return ret, nil
+}
@@ -0,0 +1,139 @@
+package wordpress
+
+import (
+	"crypto/md5"
+	"crypto/subtle"
+	"database/sql"
+	"encoding/hex"
+	"strings"
+
+	"github.com/elgris/sqrl"
+	"golang.org/x/crypto/bcrypt"
+	"golang.org/x/net/context"
+)
+
+// phpassItoa64 is the alphabet of phpass's base64 variant
+const phpassItoa64 = "./0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
+
+// maxPasswordLength is the longest password WordPress will hash
+const maxPasswordLength = 4096
+
+// dummyPasswordHash is checked when the login does not exist,
+// so that unknown logins take as long to reject as wrong passwords
+const dummyPasswordHash = "$P$BZnKCqb7vnbj085xgiQL2GDVh1xhQ00"
+
+// CheckPassword returns the user with the login if the password matches their hash
+//
+// Portable phpass (`$P$`) hashes, bcrypt (`$2y$`) hashes, and the plain md5 hashes
+// of very old installations are supported.
+// Returns an `*AuthenticationError` if there is no such user or the password does not match
+func CheckPassword(c context.Context, login, password string) (*User, error) {
+	c, span := startSpan(c, "/wordpress.CheckPassword")
+	defer span.End()
+
+	stmt, args, err := sqrl.Select("ID", "user_pass").
+		From(table(c, "users")).
+		Where(sqrl.Eq{"user_login": login}).ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	spanString(span, "wp/user/query", stmt)
+
+	var id int64
+	var hash string
+	if err := database(c).QueryRowContext(c, stmt, args...).Scan(&id, &hash); err == sql.ErrNoRows {
+		checkPasswordHash(password, dummyPasswordHash)
+		return nil, &AuthenticationError{Login: login}
+	} else if err != nil {
+		return nil, err
+	}
+
+	if !checkPasswordHash(password, hash) {
+		return nil, &AuthenticationError{Login: login}
+	}
+
+	users, err := GetUsers(c, id)
+	if err != nil {
+		return nil, err
+	}
+
+	return users[0], nil
+}
+
+// checkPasswordHash returns whether the password matches the hash
+func checkPasswordHash(password, hash string) bool {
+	if len(password) > maxPasswordLength {
+		return false
+	}
+
+	switch {
+	case strings.HasPrefix(hash, "$2"):
+		return bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)) == nil
+	case strings.HasPrefix(hash, "$P$"), strings.HasPrefix(hash, "$H$"):
+		computed := phpassCrypt(password, hash)
+		return computed != "" && subtle.ConstantTimeCompare([]byte(computed), []byte(hash)) == 1
+	case len(hash) == 32:
+		sum := md5.Sum([]byte(password))
+		return subtle.ConstantTimeCompare([]byte(hex.EncodeToString(sum[:])), []byte(strings.ToLower(hash))) == 1
+	}
+
+	return false
+}
+
+// phpassCrypt hashes the password with the iteration count and salt of the setting,
+// which is usually an existing hash
+//
+// Returns an empty string if the setting is invalid.
+func phpassCrypt(password, setting string) string {
+	if len(setting) < 12 {
+		return ""
+	}
+
+	countLog2 := strings.IndexByte(phpassItoa64, setting[3])
+	if countLog2 < 7 || countLog2 > 30 {
+		return ""
+	}
+
+	salt := setting[4:12]
+
+	sum := md5.Sum([]byte(salt + password))
+	for count := 1 << uint(countLog2); count > 0; count-- {
+		sum = md5.Sum(append(sum[:], password...))
+	}
+
+	return setting[:12] + phpassEncode64(sum[:])
+}
+
+// phpassEncode64 encodes the input with phpass's base64 variant,
+// which differs from the standard encoding in its alphabet and bit order
+func phpassEncode64(input []byte) string {
+	var out strings.Builder
+	for i := 0; i < len(input); {
+		value := uint(input[i])
+		i++
+		out.WriteByte(phpassItoa64[value&0x3f])
+
+		if i < len(input) {
+			value |= uint(input[i]) << 8
+		}
+		out.WriteByte(phpassItoa64[(value>>6)&0x3f])
+		if i >= len(input) {
+			break
+		}
+		i++
+
+		if i < len(input) {
+			value |= uint(input[i]) << 16
+		}
+		out.WriteByte(phpassItoa64[(value>>12)&0x3f])
+		if i >= len(input) {
+			break
+		}
+		i++
+
+		out.WriteByte(phpassItoa64[(value>>18)&0x3f])
+	}
+
+	return out.String()
+}
@@ -0,0 +1,63 @@
+package wordpress
+
+import (
+	"testing"
+)
+
+func TestPhpassCrypt(t *testing.T) {
+	tests := []struct {
+		password string
+		hash     string
+	}{
+		{"test12345", "$P$9IQRaTwmfeRo7ud9Fh4E2PdI0S3r.L0"},
+		{"not a real password", dummyPasswordHash},
+	}
+
+	for _, tt := range tests {
+		if got := phpassCrypt(tt.password, tt.hash); got != tt.hash {
+			t.Errorf("phpassCrypt(%q) = %s, expected %s", tt.password, got, tt.hash)
+		}
+
+		if !checkPasswordHash(tt.password, tt.hash) {
+			t.Errorf("expected %q to match %s", tt.password, tt.hash)
+		}
+
+		if checkPasswordHash(tt.password+"!", tt.hash) {
+			t.Errorf("expected %q not to match %s", tt.password+"!", tt.hash)
+		}
+	}
+}
+
+func TestPhpassCryptInvalidSetting(t *testing.T) {
+	for _, setting := range []string{
+		"",
+		"$P$9IQRaTwm",
+		// iteration counts outside of [7, 30]
+		"$P$.IQRaTwmfeRo7ud9Fh4E2PdI0S3r.L0",
+		"$P$zIQRaTwmfeRo7ud9Fh4E2PdI0S3r.L0",
+	} {
+		if got := phpassCrypt("test12345", setting); got != "" {
+			t.Errorf("expected %q to be an invalid setting, got %s", setting, got)
+		}
+	}
+}
+
+func TestPhpassEncode64(t *testing.T) {
+	tests := []struct {
+		input []byte
+		want  string
+	}{
+		{[]byte{}, ""},
+		{[]byte{0x00}, ".."},
+		{[]byte{0xff}, "z1"},
+		{[]byte{0x01, 0x02}, "/6."},
+		{[]byte{0x01, 0x02, 0x03}, "/6k."},
+		{[]byte{0xff, 0xff, 0xff, 0xff}, "zzzzz1"},
+	}
+
+	for _, tt := range tests {
+		if got := phpassEncode64(tt.input); got != tt.want {
+			t.Errorf("phpassEncode64(%v) = %q, expected %q", tt.input, got, tt.want)
+		}
+	}
+}
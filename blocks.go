@@ -0,0 +1,122 @@
+package wordpress
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"golang.org/x/net/context"
+)
+
+// Block represents a parsed Gutenberg block
+type Block struct {
+	// The block's fully qualified name (i.e. `core/paragraph`),
+	// or empty for freeform html between blocks
+	Name string `json:"blockName"`
+
+	// The block's attributes from the opening delimiter
+	Attrs map[string]interface{} `json:"attrs"`
+
+	// The block's html, excluding the html of its inner blocks
+	InnerHTML string `json:"innerHTML"`
+
+	// The block's nested blocks
+	InnerBlocks []Block `json:"innerBlocks"`
+}
+
+var blockDelimiter = regexp.MustCompile(`(?s)<!--\s+(/)?wp:([a-z][a-z0-9_-]*/)?([a-z][a-z0-9_-]*)\s+(\{.*?\}\s+)?(/)?-->`)
+
+// ParseBlocks parses the block delimiters in the content into a tree of blocks
+//
+// Html outside of any block is returned as a block with no name.
+// Like WordPress, malformed content does not fail to parse: closing delimiters that do not match
+// the innermost open block are kept as html, and blocks that are never closed end with the content.
+func ParseBlocks(content string) ([]Block, error) {
+	var ret []Block
+	var stack []*Block
+
+	appendBlock := func(b Block) {
+		if len(stack) == 0 {
+			ret = append(ret, b)
+		} else {
+			parent := stack[len(stack)-1]
+			parent.InnerBlocks = append(parent.InnerBlocks, b)
+		}
+	}
+
+	appendHTML := func(html string) {
+		if len(stack) > 0 {
+			stack[len(stack)-1].InnerHTML += html
+		} else if strings.TrimSpace(html) != "" {
+			ret = append(ret, Block{InnerHTML: html})
+		}
+	}
+
+	offset := 0
+	for _, m := range blockDelimiter.FindAllStringSubmatchIndex(content, -1) {
+		namespace := "core/"
+		if m[4] >= 0 {
+			namespace = content[m[4]:m[5]]
+		}
+
+		name := namespace + content[m[6]:m[7]]
+
+		if m[2] >= 0 && (len(stack) == 0 || stack[len(stack)-1].Name != name) {
+			// the closing delimiter does not close the innermost open block, so it is kept as html
+			continue
+		}
+
+		appendHTML(content[offset:m[0]])
+		offset = m[1]
+
+		if m[2] >= 0 {
+			// closing delimiter
+			b := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			appendBlock(*b)
+
+			continue
+		}
+
+		b := &Block{Name: name, Attrs: map[string]interface{}{}}
+		if m[8] >= 0 {
+			if err := json.Unmarshal([]byte(content[m[8]:m[9]]), &b.Attrs); err != nil {
+				return nil, fmt.Errorf("wordpress: invalid attributes for block %s: %v", name, err)
+			}
+		}
+
+		if m[10] >= 0 {
+			// self-closing delimiter
+			appendBlock(*b)
+		} else {
+			stack = append(stack, b)
+		}
+	}
+
+	appendHTML(content[offset:])
+
+	// blocks that are never closed end with the content
+	for len(stack) > 0 {
+		b := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		appendBlock(*b)
+	}
+
+	return ret, nil
+}
+
+// GetPostContentBlocks gets the post's content parsed into blocks
+func GetPostContentBlocks(c context.Context, postId int64) ([]Block, error) {
+	c, span := startSpan(c, "/wordpress.GetPostContentBlocks")
+	defer span.End()
+
+	objects, err := getObjects(c, postId)
+	if err != nil {
+		return nil, err
+	} else if len(objects) == 0 || objects[0] == nil {
+		return nil, MissingResourcesError{postId}
+	}
+
+	return ParseBlocks(objects[0].Content)
+}
@@ -0,0 +1,55 @@
+package wordpress
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseBlocksMalformed(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    []Block
+	}{
+		{
+			name:    "unmatched closer",
+			content: "<p>a</p><!-- /wp:paragraph -->",
+			want:    []Block{{InnerHTML: "<p>a</p><!-- /wp:paragraph -->"}},
+		},
+		{
+			name:    "mismatched closer",
+			content: "<!-- wp:group --><p>a</p><!-- /wp:paragraph --><!-- /wp:group -->",
+			want: []Block{{
+				Name:      "core/group",
+				Attrs:     map[string]interface{}{},
+				InnerHTML: "<p>a</p><!-- /wp:paragraph -->",
+			}},
+		},
+		{
+			name:    "unclosed",
+			content: "<!-- wp:group --><!-- wp:paragraph --><p>a</p>",
+			want: []Block{{
+				Name:  "core/group",
+				Attrs: map[string]interface{}{},
+				InnerBlocks: []Block{{
+					Name:      "core/paragraph",
+					Attrs:     map[string]interface{}{},
+					InnerHTML: "<p>a</p>",
+				}},
+			}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseBlocks(tt.content)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("expected %#v, got %#v", tt.want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,178 @@
+package wordpress
+
+import (
+	"database/sql"
+	"fmt"
+
+	"golang.org/x/net/context"
+)
+
+// Breadcrumb represents a single link in a breadcrumb trail
+type Breadcrumb struct {
+	Title string `json:"title"`
+	URL   string `json:"url"`
+}
+
+// GetBreadcrumbs returns the trail of links from the home page to the post
+//
+// Posts are preceded by their primary category and its ancestors, like in their permalinks,
+// and pages are preceded by their ancestor pages. All URLs are site-relative.
+func GetBreadcrumbs(c context.Context, postId int64) ([]Breadcrumb, error) {
+	c, span := startSpan(c, "/wordpress.GetBreadcrumbs")
+	defer span.End()
+
+	posts, err := GetPosts(c, postId)
+	if err != nil {
+		return nil, err
+	}
+
+	p := posts[0]
+
+	home, err := homeBreadcrumb(c)
+	if err != nil {
+		return nil, err
+	}
+
+	trail := []Breadcrumb{home}
+	if p.Type == PostTypePage {
+		ancestors, err := pageAncestry(c, int64(p.ParentId))
+		if err != nil {
+			return nil, err
+		}
+
+		for _, ancestor := range ancestors {
+			link, err := Permalink(c, ancestor)
+			if err != nil {
+				return nil, err
+			}
+
+			trail = append(trail, Breadcrumb{Title: ancestor.Title, URL: link})
+		}
+	} else {
+		categoryId, err := primaryCategoryId(c, p.Id, p.CategoryIds)
+		if err != nil {
+			return nil, err
+		}
+
+		if categoryId != 0 {
+			crumbs, err := termBreadcrumbs(c, categoryId)
+			if err != nil {
+				return nil, err
+			}
+
+			trail = append(trail, crumbs...)
+		}
+	}
+
+	link, err := Permalink(c, p)
+	if err != nil {
+		return nil, err
+	}
+
+	return append(trail, Breadcrumb{Title: p.Title, URL: link}), nil
+}
+
+// GetTermBreadcrumbs returns the trail of links from the home page to the term,
+// through each of its ancestors
+//
+// Terms of taxonomies other than categories and tags are linked under the taxonomy's name.
+func GetTermBreadcrumbs(c context.Context, termId int64) ([]Breadcrumb, error) {
+	c, span := startSpan(c, "/wordpress.GetTermBreadcrumbs")
+	defer span.End()
+
+	home, err := homeBreadcrumb(c)
+	if err != nil {
+		return nil, err
+	}
+
+	crumbs, err := termBreadcrumbs(c, termId)
+	if err != nil {
+		return nil, err
+	}
+
+	return append([]Breadcrumb{home}, crumbs...), nil
+}
+
+// homeBreadcrumb returns the link to the home page, titled with the site's name
+func homeBreadcrumb(c context.Context) (Breadcrumb, error) {
+	title, err := GetOption(c, "blogname")
+	if err != nil && err != sql.ErrNoRows {
+		return Breadcrumb{}, err
+	}
+
+	if title == "" {
+		title = "Home"
+	}
+
+	return Breadcrumb{Title: title, URL: "/"}, nil
+}
+
+// termBreadcrumbs returns the links to the term and its ancestors, starting from the top-level term
+func termBreadcrumbs(c context.Context, termId int64) ([]Breadcrumb, error) {
+	ancestry, err := termAncestry(c, termId)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(ancestry) == 0 {
+		return nil, nil
+	}
+
+	taxonomy := Taxonomy(ancestry[0].Taxonomy)
+
+	base := "/" + string(taxonomy)
+	if taxonomy == TaxonomyCategory || taxonomy == TaxonomyPostTag {
+		if base, err = termBase(c, taxonomy); err != nil {
+			return nil, err
+		}
+	}
+
+	crumbs := make([]Breadcrumb, 0, len(ancestry))
+	for _, term := range ancestry {
+		base += "/" + term.Slug
+		crumbs = append(crumbs, Breadcrumb{Title: term.Name, URL: base})
+	}
+
+	return crumbs, nil
+}
+
+// termAncestry returns the term and its ancestors, starting from the top-level term
+func termAncestry(c context.Context, termId int64) ([]*Term, error) {
+	var ancestry []*Term
+
+	// guard against corrupted cyclic hierarchies
+	seen := make(map[int64]bool)
+	for id := termId; id != 0 && !seen[id]; {
+		terms, err := getTerms(c, id)
+		if _, missing := err.(MissingResourcesError); missing && len(ancestry) > 0 {
+			return nil, fmt.Errorf("parent term for %d not found: %d", ancestry[0].Id, id)
+		} else if err != nil {
+			return nil, err
+		}
+
+		ancestry = append([]*Term{terms[0]}, ancestry...)
+		seen[id] = true
+		id = terms[0].Parent
+	}
+
+	return ancestry, nil
+}
+
+// pageAncestry returns the page and its ancestors, starting from the top-level page
+func pageAncestry(c context.Context, pageId int64) ([]*Post, error) {
+	var ancestry []*Post
+
+	seen := make(map[int64]bool)
+	for id := pageId; id != 0 && !seen[id]; {
+		pages, err := GetPosts(c, id)
+		if err != nil {
+			return nil, err
+		}
+
+		ancestry = append([]*Post{pages[0]}, ancestry...)
+		seen[id] = true
+		id = int64(pages[0].ParentId)
+	}
+
+	return ancestry, nil
+}
@@ -0,0 +1,207 @@
+package wordpress
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"golang.org/x/net/context"
+)
+
+// cache key formats of each resource type
+const (
+	objectCacheKey = "wp_object_%d"
+	termCacheKey   = "wp_term_%d"
+	userCacheKey   = "wp_user_%d"
+
+	// queryCacheKeyPrefix is followed by a hash of the table and query options
+	queryCacheKeyPrefix = "wp_query_"
+)
+
+// queryResult is the cached result of a query
+type queryResult struct {
+	Ids     []int64
+	Cursors []string
+}
+
+// ErrCacheMiss is returned by a `CacheManager` when the key is not cached
+var ErrCacheMiss = errors.New("wordpress: cache miss")
+
+// CacheManager represents a cache of database results
+//
+// Implementations must be safe for concurrent use.
+type CacheManager interface {
+	// Get stores the cached value of the key in dst, which must be a pointer
+	//
+	// Returns `ErrCacheMiss` if the key is not cached
+	Get(c context.Context, key string, dst interface{}) error
+
+	// GetMulti appends the cached values of the keys to dst, which must be a pointer to a slice
+	//
+	// Returns the keys that were cached, in the same order as they were given
+	GetMulti(c context.Context, keys []string, dst interface{}) ([]string, error)
+
+	// Set caches the value for the key
+	Set(c context.Context, key string, value interface{}) error
+
+	// Delete removes the keys from the cache, ignoring keys that are not cached
+	Delete(c context.Context, keys ...string) error
+}
+
+// WithCache returns a derived context whose objects, terms, and users
+// are looked up in the cache before the database
+func WithCache(parent context.Context, cm CacheManager) context.Context {
+	return context.WithValue(parent, cacheKey, cm)
+}
+
+// InvalidatePost removes the posts (or any other objects) from the context's cache
+func InvalidatePost(c context.Context, ids ...int64) error {
+	return invalidate(c, objectCacheKey, ids)
+}
+
+// InvalidateTerm removes the terms from the context's cache
+func InvalidateTerm(c context.Context, ids ...int64) error {
+	return invalidate(c, termCacheKey, ids)
+}
+
+// InvalidateUser removes the users from the context's cache
+func InvalidateUser(c context.Context, ids ...int64) error {
+	return invalidate(c, userCacheKey, ids)
+}
+
+func invalidate(c context.Context, format string, ids []int64) error {
+	cm := cacheManager(c)
+	if cm == nil || len(ids) == 0 {
+		return nil
+	}
+
+	keys := make([]string, len(ids))
+	for i, id := range ids {
+		keys[i] = cacheKeyOf(c, format, id)
+	}
+
+	return cm.Delete(c, keys...)
+}
+
+// cacheKeyOf returns the cache key of the id
+//
+// The keys of objects and terms on a multisite's other blogs are suffixed with the blog id,
+// while users are shared by every blog.
+func cacheKeyOf(c context.Context, format string, id int64) string {
+	key := fmt.Sprintf(format, id)
+	if blogId := blog(c); blogId > 1 && format != userCacheKey {
+		key += fmt.Sprintf("_blog_%d", blogId)
+	}
+
+	return key
+}
+
+func cacheManager(c context.Context) CacheManager {
+	cm, _ := c.Value(cacheKey).(CacheManager)
+	return cm
+}
+
+// getCached appends the cached values of the ids to dst, which must be a pointer to a slice,
+// and returns the ids that were cached in the same order
+//
+// Cache errors are treated as misses so that the database is used instead.
+func getCached(c context.Context, format string, ids []int64, dst interface{}) []int64 {
+	cm := cacheManager(c)
+	if cm == nil || len(ids) == 0 {
+		return nil
+	}
+
+	keys := make([]string, len(ids))
+	keyIds := make(map[string]int64, len(ids))
+	for i, id := range ids {
+		keys[i] = cacheKeyOf(c, format, id)
+		keyIds[keys[i]] = id
+	}
+
+	hits, err := cm.GetMulti(c, keys, dst)
+	if err != nil {
+		spanString(spanFromContext(c), "wp/cache/error", err.Error())
+		return nil
+	}
+
+	hitIds := make([]int64, len(hits))
+	for i, key := range hits {
+		hitIds[i] = keyIds[key]
+	}
+
+	spanInt64(spanFromContext(c), "wp/cache/hits", int64(len(hits)))
+
+	return hitIds
+}
+
+// setCached caches the value of the id, ignoring any errors
+func setCached(c context.Context, format string, id int64, value interface{}) {
+	cm := cacheManager(c)
+	if cm == nil {
+		return
+	}
+
+	if err := cm.Set(c, cacheKeyOf(c, format, id), value); err != nil {
+		spanString(spanFromContext(c), "wp/cache/error", err.Error())
+	}
+}
+
+// WithQueryCache returns a derived context whose post queries are cached
+// along with the objects, terms, and users if enabled is true
+//
+// Query results are not invalidated by writes, so the cache should expire
+// keys with the `wp_query_` prefix quickly. Disable it for queries that must be fresh.
+func WithQueryCache(parent context.Context, enabled bool) context.Context {
+	return context.WithValue(parent, queryCacheKey, enabled)
+}
+
+// queryResultKey returns the cache key of the query's result,
+// or an empty string if query results are not cached
+func queryResultKey(c context.Context, tableName string, opts interface{}) string {
+	if enabled, _ := c.Value(queryCacheKey).(bool); !enabled || cacheManager(c) == nil {
+		return ""
+	}
+
+	data, err := json.Marshal(opts)
+	if err != nil {
+		return ""
+	}
+
+	hash := sha1.New()
+	hash.Write([]byte(table(c, tableName)))
+	hash.Write(data)
+
+	return queryCacheKeyPrefix + hex.EncodeToString(hash.Sum(nil))
+}
+
+// getCachedQuery stores the cached result in dst and returns whether it was cached
+func getCachedQuery(c context.Context, key string, dst *queryResult) bool {
+	if key == "" {
+		return false
+	}
+
+	if err := cacheManager(c).Get(c, key, dst); err != nil {
+		if err != ErrCacheMiss {
+			spanString(spanFromContext(c), "wp/cache/error", err.Error())
+		}
+
+		return false
+	}
+
+	spanInt64(spanFromContext(c), "wp/cache/hits", 1)
+
+	return true
+}
+
+// setCachedQuery caches the result, ignoring any errors
+func setCachedQuery(c context.Context, key string, result *queryResult) {
+	if key == "" {
+		return
+	}
+
+	if err := cacheManager(c).Set(c, key, result); err != nil {
+		spanString(spanFromContext(c), "wp/cache/error", err.Error())
+	}
+}
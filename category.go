@@ -1,7 +1,6 @@
 package wordpress
 
 import (
-	"go.opencensus.io/trace"
 	"database/sql"
 	"encoding/json"
 	"errors"
@@ -16,35 +15,48 @@ type Category struct {
 	Term
 
 	Link string `json:"url"`
+
+	// Children is only populated by `GetCategoryTree`
+	Children []*Category `json:"children,omitempty"`
 }
 
 // MarshalJSON marshals itself into json
 func (cat *Category) MarshalJSON() ([]byte, error) {
-	return json.Marshal(map[string]interface{}{
+	data := map[string]interface{}{
 		"id":     cat.Id,
 		"parent": cat.Parent,
 		"name":   cat.Name,
-		"url":    cat.Link})
+		"url":    cat.Link}
+
+	if len(cat.Children) > 0 {
+		data["children"] = cat.Children
+	}
+
+	return json.Marshal(data)
 }
 
 // GetChildId returns the category id of the child looked up by it's slug
+//
+// Returns `ErrNotFound` if the category has no child with the slug
 func (cat *Category) GetChildId(c context.Context, slug string) (int64, error) {
-	c, span := trace.StartSpan(c, "/wordpress.Category.GetChildId")
+	c, span := startSpan(c, "/wordpress.Category.GetChildId")
 	defer span.End()
 
-	stmt, args, err := sqrl.Select("term_id").
+	stmt, args, err := sqrl.Select("t.term_id").
 		From(table(c, "terms") + " AS t").
 		Join(table(c, "term_taxonomy") + " AS tt ON t.term_id = tt.term_id").
-		Where(sqrl.Eq{"tt.parent": cat.Id, "t.slug": slug}).ToSql()
+		Where(sqrl.Eq{"tt.taxonomy": string(TaxonomyCategory), "tt.parent": cat.Id, "t.slug": slug}).ToSql()
 	if err != nil {
 		return 0, err
 	}
 
-	span.AddAttributes(trace.StringAttribute("wp/query", stmt))
+	spanString(span, "wp/query", stmt)
 
 	var id int64
-	if err := database(c).QueryRow(stmt, args...).Scan(&id); err != nil && err != sql.ErrNoRows {
-		return 0, nil
+	if err := database(c).QueryRowContext(c, stmt, args...).Scan(&id); err == sql.ErrNoRows {
+		return 0, ErrNotFound
+	} else if err != nil {
+		return 0, err
 	}
 
 	return id, nil
@@ -52,36 +64,203 @@ func (cat *Category) GetChildId(c context.Context, slug string) (int64, error) {
 
 // GetChildrenIds returns all the ids of the category and it's children
 func (cat *Category) GetChildrenIds(c context.Context) ([]int64, error) {
-	c, span := trace.StartSpan(c, "/wordpress.Category.GetChildrenIds")
+	c, span := startSpan(c, "/wordpress.Category.GetChildrenIds")
 	defer span.End()
 
-	ret := []int64{cat.Id}
+	children, err := categoryChildren(c)
+	if err != nil {
+		return nil, err
+	}
 
-	ids := ret[:]
-	for len(ids) > 0 {
-		stmt, args, err := sqrl.Select("term_id").
-			From(table(c, "term_taxonomy")).
-			Where(sqrl.Eq{"parent": ids}).ToSql()
-		if err != nil {
+	return children.descendants(cat.Id), nil
+}
+
+// categoryHierarchy maps the ids of categories to the ids of their children
+type categoryHierarchy map[int64][]int64
+
+// categoryChildren loads the whole category hierarchy at once instead of querying each level
+func categoryChildren(c context.Context) (categoryHierarchy, error) {
+	stmt, args, err := sqrl.Select("term_id", "parent").
+		From(table(c, "term_taxonomy")).
+		Where(sqrl.Eq{"taxonomy": string(TaxonomyCategory)}).ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	spanString(spanFromContext(c), "wp/query", stmt)
+
+	rows, err := database(c).QueryContext(c, stmt, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	children := make(categoryHierarchy)
+	for rows.Next() {
+		var id, parent int64
+		if err := rows.Scan(&id, &parent); err != nil {
 			return nil, err
 		}
 
-		rows, err := database(c).Query(stmt, args...)
-		if err != nil {
-			return nil, err
+		children[parent] = append(children[parent], id)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return children, nil
+}
+
+// descendants returns the ids followed by the ids of all of their descendants, without duplicates
+func (children categoryHierarchy) descendants(ids ...int64) []int64 {
+	// guard against corrupted cyclic hierarchies
+	seen := make(map[int64]bool, len(ids))
+
+	var ret []int64
+	for _, id := range ids {
+		if !seen[id] {
+			seen[id] = true
+			ret = append(ret, id)
 		}
+	}
 
-		ids = nil
-		for rows.Next() {
-			var id int64
-			if err := rows.Scan(&id); err != nil {
-				return nil, err
+	for i := 0; i < len(ret); i++ {
+		for _, id := range children[ret[i]] {
+			if !seen[id] {
+				seen[id] = true
+				ret = append(ret, id)
 			}
+		}
+	}
 
-			ids = append(ids, id)
+	return ret
+}
+
+// GetCategoryTree returns the children of the root category, or the top-level categories
+// if the root id is zero, with all of their descendants populated in `Children`
+//
+// The whole hierarchy is loaded in a single query.
+// Siblings are ordered by name, and `ErrNotFound` is returned if the root category does not exist.
+func GetCategoryTree(c context.Context, rootId int64) ([]*Category, error) {
+	c, span := startSpan(c, "/wordpress.GetCategoryTree")
+	defer span.End()
+
+	stmt, args, err := sqrl.Select(
+		"t.term_id",
+		"t.name",
+		"t.slug",
+		"t.term_group",
+		"tt.term_taxonomy_id",
+		"tt.taxonomy",
+		"tt.description",
+		"tt.parent",
+		"tt.count").
+		From(table(c, "term_taxonomy")+" AS tt").
+		Join(table(c, "terms")+" AS t ON t.term_id = tt.term_id").
+		Where(sqrl.Eq{"tt.taxonomy": string(TaxonomyCategory)}).
+		OrderBy("t.name ASC", "t.term_id ASC").ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	spanString(span, "wp/term/query", stmt)
+
+	rows, err := database(c).QueryContext(c, stmt, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	var ordered []*Category
+	categories := make(map[int64]*Category)
+	for rows.Next() {
+		var cat Category
+		if err := rows.Scan(
+			&cat.Id,
+			&cat.Name,
+			&cat.Slug,
+			&cat.Group,
+			&cat.TaxonomyId,
+			&cat.Taxonomy,
+			&cat.Description,
+			&cat.Parent,
+			&cat.Count); err != nil {
+			return nil, fmt.Errorf("unable to read term data: %w", err)
 		}
 
-		ret = append(ret, ids...)
+		ordered = append(ordered, &cat)
+		categories[cat.Id] = &cat
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	spanInt64(span, "wp/term/count", int64(len(ordered)))
+
+	if _, ok := categories[rootId]; rootId != 0 && !ok {
+		return nil, ErrNotFound
+	}
+
+	// rows are ordered by name, so children are appended in order
+	for _, cat := range ordered {
+		if parent, ok := categories[cat.Parent]; ok && cat.Parent != cat.Id {
+			parent.Children = append(parent.Children, cat)
+		}
+	}
+
+	base, err := termBase(c, TaxonomyCategory)
+	if err != nil {
+		return nil, err
+	}
+
+	// the root's link is the prefix of its descendants' links
+	prefix := base
+	if root, ok := categories[rootId]; ok {
+		link := ""
+		seen := map[int64]bool{}
+		for cat := root; cat != nil && !seen[cat.Id]; cat = categories[cat.Parent] {
+			link = "/" + cat.Slug + link
+			seen[cat.Id] = true
+		}
+
+		prefix = base + link
+	}
+
+	var ret []*Category
+	if rootId != 0 {
+		ret = categories[rootId].Children
+	} else {
+		for _, cat := range ordered {
+			if cat.Parent == 0 {
+				ret = append(ret, cat)
+			}
+		}
+	}
+
+	// links are built top-down, guarding against corrupted cyclic hierarchies
+	seen := map[int64]bool{rootId: true}
+
+	var link func(cats []*Category, prefix string)
+	link = func(cats []*Category, prefix string) {
+		for _, cat := range cats {
+			if seen[cat.Id] {
+				continue
+			}
+
+			seen[cat.Id] = true
+			cat.Link = prefix + "/" + cat.Slug
+			link(cat.Children, cat.Link)
+		}
+	}
+
+	link(ret, prefix)
+
+	if ret == nil {
+		ret = []*Category{}
 	}
 
 	return ret, nil
@@ -89,17 +268,21 @@ func (cat *Category) GetChildrenIds(c context.Context) ([]int64, error) {
 
 // GetCategoryIdBySlug returns the id of the category that matches the given slug
 func GetCategoryIdBySlug(c context.Context, slug string) (int64, error) {
-	c, span := trace.StartSpan(c, "/wordpress.GetCategoryIdBySlug")
+	c, span := startSpan(c, "/wordpress.GetCategoryIdBySlug")
 	defer span.End()
 
 	parts := strings.Split(slug, "/")
 
 	var catId int64
 	for _, part := range parts {
-		it, err := queryTerms(c, &TermQueryOptions{
+		opts := &TermQueryOptions{
 			Taxonomy: TaxonomyCategory,
-			Slug:     part,
-			ParentId: catId})
+			Slug:     part}
+		if catId != 0 {
+			opts.ParentId = &catId
+		}
+
+		it, err := queryTerms(c, opts)
 		if err != nil {
 			return 0, err
 		}
@@ -112,9 +295,26 @@ func GetCategoryIdBySlug(c context.Context, slug string) (int64, error) {
 	return catId, nil
 }
 
+// QueryCategories returns the ids of the categories that match the query
+func QueryCategories(c context.Context, opts *TermQueryOptions) (Iterator, error) {
+	c, span := startSpan(c, "/wordpress.QueryCategories")
+	defer span.End()
+
+	opts.Taxonomy = TaxonomyCategory
+
+	return queryTerms(c, opts)
+}
+
+// CreateCategory inserts the category into the database and returns the new term id
+func CreateCategory(c context.Context, cat *Term) (int64, error) {
+	cat.Taxonomy = string(TaxonomyCategory)
+
+	return CreateTerm(c, cat)
+}
+
 // GetCategories gets all category data from the database
 func GetCategories(c context.Context, categoryIds ...int64) ([]*Category, error) {
-	c, span := trace.StartSpan(c, "/wordpress.GetCategories")
+	c, span := startSpan(c, "/wordpress.GetCategories")
 	defer span.End()
 
 	if len(categoryIds) == 0 {
@@ -124,50 +324,196 @@ func GetCategories(c context.Context, categoryIds ...int64) ([]*Category, error)
 	ids, idMap := dedupe(categoryIds)
 
 	terms, err := getTerms(c, ids...)
+	mre, missing := err.(MissingResourcesError)
+	if missing && partialResults(c) {
+		ids = without(ids, mre)
+		terms, err = getTerms(c, ids...)
+	}
 	if err != nil {
 		return nil, err
 	}
 
-	counter := 0
-	done := make(chan error)
+	base, err := termBase(c, TaxonomyCategory)
+	if err != nil {
+		return nil, err
+	}
 
-	ret := make([]*Category, len(categoryIds))
+	// the ancestors are loaded a level at a time for all of the categories at once,
+	// so there are only as many queries as the hierarchy is deep
+	loaded := make(map[int64]*Term, len(terms))
 	for _, term := range terms {
-		cat := Category{Term: *term}
+		loaded[term.Id] = term
+	}
+
+	for pending := terms; len(pending) > 0; {
+		var parentIds []int64
+		for _, term := range pending {
+			if _, ok := loaded[term.Parent]; term.Parent > 0 && !ok {
+				parentIds = append(parentIds, term.Parent)
+			}
+		}
+
+		if pending, err = getTerms(c, parentIds...); err != nil {
+			if mre, ok := err.(MissingResourcesError); ok {
+				return nil, fmt.Errorf("parent category not found: %d", mre[0])
+			}
 
-		if cat.Parent > 0 {
-			counter++
-			go func() {
-				parents, err := GetCategories(c, cat.Parent)
-				if err != nil {
-					done <- fmt.Errorf("failed to get parent category for %d: %d\n%v", cat.Id, cat.Parent, err)
-					return
-				}
+			return nil, err
+		}
+
+		for _, term := range pending {
+			loaded[term.Id] = term
+		}
+	}
 
-				if len(parents) == 0 {
-					done <- fmt.Errorf("parent category for %d not found: %d", cat.Id, cat.Parent)
-					return
-				}
+	ret := make([]*Category, len(categoryIds))
+	for _, term := range terms {
+		cat := &Category{Term: *term}
 
-				cat.Link = parents[0].Link + "/" + cat.Slug
+		// walk up to the root, guarding against corrupted cyclic hierarchies
+		link := "/" + cat.Slug
+		seen := map[int64]bool{cat.Id: true}
+		for parentId := cat.Parent; parentId > 0 && !seen[parentId]; {
+			parent := loaded[parentId]
 
-				done <- nil
-			}()
-		} else {
-			cat.Link = "/category/" + cat.Slug
+			link = "/" + parent.Slug + link
+			seen[parentId] = true
+			parentId = parent.Parent
 		}
 
+		cat.Link = base + link
+
 		// insert into return set
 		for _, index := range idMap[cat.Id] {
-			ret[index] = &cat
+			ret[index] = cat
 		}
 	}
 
-	for ; counter > 0; counter-- {
-		if err := <-done; err != nil {
+	if missing && partialResults(c) {
+		found := make([]*Category, 0, len(ret))
+		for _, cat := range ret {
+			if cat != nil {
+				found = append(found, cat)
+			}
+		}
+
+		if found, err = afterGetCategoriesFilters.apply(c, found); err != nil {
 			return nil, err
 		}
+
+		return found, mre
 	}
 
-	return ret, nil
+	return afterGetCategoriesFilters.apply(c, ret)
+}
+
+// GetCategoriesWithLinks gets all category data from the database
+//
+// Unlike `GetCategories`, the categories and all of their ancestors are loaded
+// in a single recursive query, so the links are built without a query per parent.
+// Requires a database that supports recursive common table expressions (i.e. MySQL 8).
+func GetCategoriesWithLinks(c context.Context, categoryIds ...int64) ([]*Category, error) {
+	c, span := startSpan(c, "/wordpress.GetCategoriesWithLinks")
+	defer span.End()
+
+	if len(categoryIds) == 0 {
+		return []*Category{}, nil
+	}
+
+	ids, idMap := dedupe(categoryIds)
+
+	in, args := expandIn("tt.term_id", ids)
+
+	stmt := "WITH RECURSIVE ancestry AS (" +
+		"SELECT tt.term_id, tt.parent FROM " + table(c, "term_taxonomy") + " AS tt " +
+		"WHERE tt.taxonomy = 'category' AND " + in + " " +
+		"UNION " +
+		"SELECT tt.term_id, tt.parent FROM " + table(c, "term_taxonomy") + " AS tt " +
+		"JOIN ancestry AS a ON tt.term_id = a.parent " +
+		"WHERE tt.taxonomy = 'category') " +
+		"SELECT t.term_id, t.name, t.slug, t.term_group, tt.term_taxonomy_id, tt.taxonomy, tt.description, tt.parent, tt.count " +
+		"FROM ancestry AS a " +
+		"JOIN " + table(c, "terms") + " AS t ON t.term_id = a.term_id " +
+		"JOIN " + table(c, "term_taxonomy") + " AS tt ON tt.term_id = t.term_id AND tt.taxonomy = 'category'"
+
+	spanString(span, "wp/term/query", stmt)
+
+	rows, err := database(c).QueryContext(c, stmt, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	categories := make(map[int64]*Category)
+	for rows.Next() {
+		var cat Category
+		if err := rows.Scan(
+			&cat.Id,
+			&cat.Name,
+			&cat.Slug,
+			&cat.Group,
+			&cat.TaxonomyId,
+			&cat.Taxonomy,
+			&cat.Description,
+			&cat.Parent,
+			&cat.Count); err != nil {
+			return nil, fmt.Errorf("unable to read term data: %w", err)
+		}
+
+		categories[cat.Id] = &cat
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	spanInt64(span, "wp/term/count", int64(len(categories)))
+
+	base, err := termBase(c, TaxonomyCategory)
+	if err != nil {
+		return nil, err
+	}
+
+	ret := make([]*Category, len(categoryIds))
+	for _, id := range ids {
+		cat, ok := categories[id]
+		if !ok {
+			continue
+		}
+
+		// walk up to the root, guarding against corrupted cyclic hierarchies
+		link := "/" + cat.Slug
+		seen := map[int64]bool{cat.Id: true}
+		for parentId := cat.Parent; parentId > 0 && !seen[parentId]; {
+			parent, ok := categories[parentId]
+			if !ok {
+				return nil, fmt.Errorf("parent category for %d not found: %d", cat.Id, parentId)
+			}
+
+			link = "/" + parent.Slug + link
+			seen[parentId] = true
+			parentId = parent.Parent
+		}
+
+		cat.Link = base + link
+
+		// insert into return set
+		for _, index := range idMap[id] {
+			ret[index] = cat
+		}
+	}
+
+	var mre MissingResourcesError
+	for i, cat := range ret {
+		if cat == nil {
+			mre = append(mre, categoryIds[i])
+		}
+	}
+
+	if len(mre) > 0 {
+		return nil, mre
+	}
+
+	return afterGetCategoriesFilters.apply(c, ret)
 }
@@ -0,0 +1,196 @@
+package wordpress
+
+import (
+	"reflect"
+	"strconv"
+	"sync"
+	"testing"
+
+	"golang.org/x/net/context"
+)
+
+func TestGetChildId(t *testing.T) {
+	c := newTestContext(t)
+
+	parent := testTerm(t, c, TaxonomyCategory, "Parent", 0)
+	child := testTerm(t, c, TaxonomyCategory, "Child", parent)
+
+	// tags with the same parent are not child categories
+	testTerm(t, c, TaxonomyPostTag, "Tag", parent)
+
+	cat := &Category{Term: Term{Id: parent}}
+
+	if id, err := cat.GetChildId(c, "child"); err != nil || id != child {
+		t.Errorf("expected %d, got %d, %v", child, id, err)
+	}
+
+	if id, err := cat.GetChildId(c, "tag"); err != ErrNotFound {
+		t.Errorf("expected ErrNotFound, got %d, %v", id, err)
+	}
+
+	// query errors must not be mistaken for a missing child
+	testExec(t, c, "DROP TABLE wp_term_taxonomy")
+
+	if id, err := cat.GetChildId(c, "child"); err == nil || err == ErrNotFound {
+		t.Errorf("expected a query error, got %d, %v", id, err)
+	}
+}
+
+func TestCategoryHierarchyDescendants(t *testing.T) {
+	children := categoryHierarchy{
+		1: {2, 3},
+		2: {4},
+		5: {6},
+		// a corrupted cycle
+		6: {5},
+	}
+
+	tests := []struct {
+		name string
+		ids  []int64
+		want []int64
+	}{
+		{name: "none"},
+		{name: "leaf", ids: []int64{4}, want: []int64{4}},
+		{name: "nested", ids: []int64{1}, want: []int64{1, 2, 3, 4}},
+		{name: "several", ids: []int64{2, 5}, want: []int64{2, 5, 4, 6}},
+		{name: "overlapping", ids: []int64{2, 1, 2}, want: []int64{2, 1, 4, 3}},
+		{name: "cyclic", ids: []int64{6}, want: []int64{6, 5}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if ids := children.descendants(tt.ids...); !reflect.DeepEqual(ids, tt.want) {
+				t.Errorf("expected %v, got %v", tt.want, ids)
+			}
+		})
+	}
+}
+
+func TestGetCategoriesAncestors(t *testing.T) {
+	c := newTestContext(t)
+
+	// a chain of five levels, each the child of the one before
+	var ids []int64
+	var parent int64
+	for i := 1; i <= 5; i++ {
+		parent = testTerm(t, c, TaxonomyCategory, "Level "+strconv.Itoa(i), parent)
+		ids = append(ids, parent)
+	}
+
+	want := make([]string, len(ids))
+	for i := range ids {
+		link := "/category"
+		for j := 0; j <= i; j++ {
+			link += "/level-" + strconv.Itoa(j+1)
+		}
+
+		want[i] = link
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			// the deepest category first, so its ancestors are not already loaded
+			cats, err := GetCategories(c, ids[4], ids[2], ids[0], ids[3], ids[1])
+			if err != nil {
+				t.Errorf("unexpected error: %v", err)
+				return
+			}
+
+			for i, index := range []int{4, 2, 0, 3, 1} {
+				if cats[i].Link != want[index] {
+					t.Errorf("expected the link of category %d to be %q, got %q", ids[index], want[index], cats[i].Link)
+				}
+			}
+		}()
+	}
+
+	wg.Wait()
+}
+
+// getCategoriesPerParent loads the categories the way `GetCategories` used to,
+// with a query for the parent of every category
+func getCategoriesPerParent(c context.Context, categoryIds ...int64) ([]*Category, error) {
+	terms, err := getTerms(c, categoryIds...)
+	if err != nil {
+		return nil, err
+	}
+
+	ret := make([]*Category, len(terms))
+	for i, term := range terms {
+		cat := &Category{Term: *term}
+		cat.Link = "/category/" + cat.Slug
+
+		if cat.Parent > 0 {
+			parents, err := getCategoriesPerParent(c, cat.Parent)
+			if err != nil {
+				return nil, err
+			}
+
+			cat.Link = parents[0].Link + "/" + cat.Slug
+		}
+
+		ret[i] = cat
+	}
+
+	return ret, nil
+}
+
+func BenchmarkGetCategoriesDeepTree(b *testing.B) {
+	c := newTestContext(b)
+
+	// a binary tree eight levels deep, whose leaves are loaded
+	level := []int64{0}
+	for depth := 1; depth <= 8; depth++ {
+		var next []int64
+		for _, parent := range level {
+			for i := 0; i < 2; i++ {
+				next = append(next, testTerm(b, c, TaxonomyCategory, "Category "+strconv.Itoa(len(next))+" "+strconv.Itoa(depth), parent))
+			}
+		}
+
+		level = next
+	}
+
+	loaders := []struct {
+		name string
+		load func(c context.Context, categoryIds ...int64) ([]*Category, error)
+	}{
+		{name: "per parent", load: getCategoriesPerParent},
+		{name: "per level", load: GetCategories},
+		{name: "recursive query", load: GetCategoriesWithLinks},
+	}
+
+	want, err := getCategoriesPerParent(c, level...)
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	for _, bl := range loaders {
+		b.Run(bl.name, func(b *testing.B) {
+			cats, err := bl.load(c, level...)
+			if err != nil {
+				b.Fatal(err)
+			}
+
+			for i, cat := range cats {
+				if cat.Link != want[i].Link {
+					b.Fatalf("expected the link of category %d to be %q, got %q", cat.Id, want[i].Link, cat.Link)
+				}
+			}
+
+			b.ReportAllocs()
+			b.ResetTimer()
+
+			for i := 0; i < b.N; i++ {
+				if _, err := bl.load(c, level...); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
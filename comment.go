@@ -0,0 +1,235 @@
+package wordpress
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/elgris/sqrl"
+	"golang.org/x/net/context"
+)
+
+// Comment represents a WordPress comment
+type Comment struct {
+	Id     int64 `json:"id"`
+	PostId int64 `json:"post"`
+
+	// The id of the comment being replied to, or zero for top-level comments
+	Parent int64 `json:"parent"`
+
+	// The id of the registered user who wrote the comment, or zero for guests
+	UserId int64 `json:"author"`
+
+	Author      string `json:"author_name"`
+	AuthorEmail string `json:"-"`
+	AuthorUrl   string `json:"author_url"`
+
+	Content string `json:"content"`
+
+	Date    time.Time `json:"date"`
+	DateGmt time.Time `json:"date_gmt"`
+
+	Approved CommentStatus `json:"status"`
+	Karma    int           `json:"karma"`
+}
+
+// CommentQueryOptions represents the available parameters for querying comments
+type CommentQueryOptions struct {
+	After string `param:"after"`
+	Limit int    `param:"limit"`
+
+	PostId   int64   `param:"post_id"`
+	PostIdIn []int64 `param:"post_id__in"`
+
+	// ParentId is a pointer so that top-level comments can be matched with a zero id
+	ParentId   *int64  `param:"parent_id"`
+	ParentIdIn []int64 `param:"parent_id__in"`
+
+	// Only approved comments are matched if no status is given
+	Status   CommentStatus   `param:"status"`
+	StatusIn []CommentStatus `param:"status__in"`
+}
+
+// GetComments gets all comment data from the database
+func GetComments(c context.Context, commentIds ...int64) ([]*Comment, error) {
+	c, span := startSpan(c, "/wordpress.GetComments")
+	defer span.End()
+
+	if len(commentIds) == 0 {
+		return []*Comment{}, nil
+	}
+
+	ids, idMap := dedupe(commentIds)
+
+	stmt, args, err := sqrl.Select(
+		"comment_ID",
+		"comment_post_ID",
+		"comment_parent",
+		"user_id",
+		"comment_author",
+		"comment_author_email",
+		"comment_author_url",
+		"comment_content",
+		"comment_date",
+		"comment_date_gmt",
+		"comment_approved",
+		"comment_karma").
+		From(table(c, "comments")).
+		Where(sqrl.Eq{"comment_ID": ids}).ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	spanString(span, "wp/comment/query", stmt)
+
+	rows, err := database(c).QueryContext(c, stmt, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	ret := make([]*Comment, len(commentIds))
+	for rows.Next() {
+		var cmt Comment
+		if err := rows.Scan(
+			&cmt.Id,
+			&cmt.PostId,
+			&cmt.Parent,
+			&cmt.UserId,
+			&cmt.Author,
+			&cmt.AuthorEmail,
+			&cmt.AuthorUrl,
+			&cmt.Content,
+			(*nullTime)(&cmt.Date),
+			(*nullTime)(&cmt.DateGmt),
+			&cmt.Approved,
+			&cmt.Karma); err != nil {
+			return nil, err
+		}
+
+		// insert into return set
+		for _, index := range idMap[cmt.Id] {
+			ret[index] = &cmt
+		}
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	var mre MissingResourcesError
+	for i, cmt := range ret {
+		if cmt == nil {
+			mre = append(mre, commentIds[i])
+		}
+	}
+
+	if len(mre) > 0 {
+		return nil, mre
+	}
+
+	return ret, nil
+}
+
+// QueryComments returns the ids of the comments that match the query
+//
+// Comments are ordered from oldest to newest, so replies always follow their parents.
+func QueryComments(c context.Context, opts *CommentQueryOptions) (Iterator, error) {
+	c, span := startSpan(c, "/wordpress.QueryComments")
+	defer span.End()
+
+	if opts == nil {
+		opts = &CommentQueryOptions{}
+	}
+
+	q := sqrl.Select("comment_ID").From(table(c, "comments")).OrderBy("comment_ID ASC")
+
+	if opts.PostId != 0 {
+		q = q.Where(sqrl.Eq{"comment_post_ID": opts.PostId})
+	} else if len(opts.PostIdIn) > 0 {
+		q = q.Where(sqrl.Eq{"comment_post_ID": opts.PostIdIn})
+	}
+
+	if opts.ParentId != nil {
+		q = q.Where(sqrl.Eq{"comment_parent": *opts.ParentId})
+	} else if len(opts.ParentIdIn) > 0 {
+		q = q.Where(sqrl.Eq{"comment_parent": opts.ParentIdIn})
+	}
+
+	if len(opts.StatusIn) > 0 {
+		statuses := make([]string, 0, len(opts.StatusIn))
+		for _, status := range opts.StatusIn {
+			statuses = append(statuses, string(status))
+		}
+
+		q = q.Where(sqrl.Eq{"comment_approved": statuses})
+	} else if opts.Status == "" {
+		q = q.Where(sqrl.Eq{"comment_approved": string(CommentStatusApproved)})
+	} else if opts.Status != CommentStatusAny {
+		q = q.Where(sqrl.Eq{"comment_approved": string(opts.Status)})
+	}
+
+	if opts.After != "" {
+		cur, err := decodeCursor(opts.After, "comment_ID")
+		if err != nil {
+			return nil, err
+		}
+
+		q = q.Where("comment_ID > ?", cur.Id)
+	}
+
+	if opts.Limit == 0 {
+		opts.Limit = 10
+	}
+
+	if opts.Limit > 0 {
+		q = q.Limit(uint64(opts.Limit))
+	}
+
+	stmt, args, err := q.ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	spanString(span, "wp/comment/query", stmt)
+
+	rows, err := database(c).QueryContext(c, stmt, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		if err = rows.Scan(&id); err != nil {
+			return nil, err
+		}
+
+		ids = append(ids, id)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	spanInt64(span, "wp/comment/count", int64(len(ids)))
+
+	it := iteratorImpl{cursor: opts.After, length: len(ids)}
+
+	var counter int
+	it.next = func() (id int64, err error) {
+		if counter < len(ids) {
+			id = ids[counter]
+			it.cursor = encodeCursor("comment_ID", strconv.FormatInt(id, 10), id)
+			counter++
+		} else {
+			return it.exit(Done)
+		}
+
+		return id, err
+	}
+
+	return &it, nil
+}
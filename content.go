@@ -0,0 +1,268 @@
+package wordpress
+
+import (
+	"html"
+	"html/template"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// DefaultExcerptLength is the number of words in generated excerpts, like WordPress's `excerpt_length`
+const DefaultExcerptLength = 55
+
+// excerptMore is appended to generated excerpts that were truncated, like WordPress's `excerpt_more`
+const excerptMore = " […]"
+
+var (
+	// moreTag matches the tag that splits the teaser from the rest of the content,
+	// with the same pattern as WordPress's `get_the_content`
+	moreTag = regexp.MustCompile(`<!--more(.*?)?-->`)
+
+	// moreBlockDelimiter matches the delimiters of the `core/more` block that wraps the more tag
+	moreBlockDelimiter = regexp.MustCompile(`<!-- /?wp:more(.*?) -->`)
+
+	// shortcodeTag matches the opening, closing, and self-closing tags of shortcodes,
+	// so that only the tags are stripped and the content between them is kept
+	shortcodeTag = regexp.MustCompile(`\[/?[a-zA-Z][\w-]*(?:\s[^\]]*)?/?\]`)
+
+	// hiddenElement matches the elements whose content is not text
+	hiddenElement = regexp.MustCompile(`(?is)<(script|style)[^>]*>.*?</(script|style)>`)
+
+	htmlTag = regexp.MustCompile(`(?s)<!--.*?-->|<[^>]*>`)
+)
+
+// GetExcerpt returns the post's excerpt, or generates one from the content if it has none
+//
+// Like WordPress, the generated excerpt is made from the content before the `<!--more-->` tag,
+// stripped of shortcodes and html, and truncated to the number of words with an ellipsis.
+// The generated excerpt is plain text, and `DefaultExcerptLength` is used if wordLimit is not positive.
+func (p *Post) GetExcerpt(wordLimit int) string {
+	if p.Excerpt != "" {
+		return p.Excerpt
+	}
+
+	if wordLimit <= 0 {
+		wordLimit = DefaultExcerptLength
+	}
+
+	content, _, _ := p.SplitMore()
+
+	content = shortcodeTag.ReplaceAllString(content, "")
+	content = hiddenElement.ReplaceAllString(content, "")
+	content = htmlTag.ReplaceAllString(content, "")
+
+	words := strings.Fields(html.UnescapeString(content))
+	if len(words) > wordLimit {
+		return strings.Join(words[:wordLimit], " ") + excerptMore
+	}
+
+	return strings.Join(words, " ")
+}
+
+// SplitMore splits the post's content at the first `<!--more-->` tag,
+// which may contain custom link text (i.e. `<!--more Keep reading-->`)
+//
+// Like WordPress, the delimiters of the `core/more` block are removed from both parts.
+// The whole content is the teaser if it has no more tag.
+func (p *Post) SplitMore() (teaser, rest string, hasMore bool) {
+	content := p.Content
+
+	tag := moreTag.FindString(content)
+	if tag == "" {
+		return content, "", false
+	}
+
+	if strings.Contains(content, "<!-- wp:more") {
+		content = moreBlockDelimiter.ReplaceAllString(content, "")
+	}
+
+	parts := strings.SplitN(content, tag, 2)
+
+	return parts[0], parts[1], true
+}
+
+// RenderContent returns the post's content with paragraphs and line breaks, like WordPress's `the_content`
+//
+// Like WordPress, content made of blocks is left alone,
+// since the html of blocks already contains their paragraphs.
+func (p *Post) RenderContent() template.HTML {
+	if strings.Contains(p.Content, "<!-- wp:") {
+		return template.HTML(p.Content)
+	}
+
+	return template.HTML(autop(p.Content))
+}
+
+// autopBlocks are the names of the block-level elements that are never wrapped in paragraphs
+const autopBlocks = `(?:table|thead|tfoot|caption|col|colgroup|tbody|tr|td|th|div|dl|dd|dt|ul|ol|li|pre|form|map|area|blockquote|address|style|p|h[1-6]|hr|fieldset|legend|section|article|aside|hgroup|header|footer|nav|figure|figcaption|details|menu|summary)`
+
+// the patterns of `autop`, named after what they match in WordPress's `wpautop`
+var (
+	autopDoubleBreak      = regexp.MustCompile(`<br\s*/?>\s*<br\s*/?>`)
+	autopBlockOpen        = regexp.MustCompile(`(<` + autopBlocks + `[\s/>])`)
+	autopBlockClose       = regexp.MustCompile(`(</` + autopBlocks + `>)`)
+	autopHr               = regexp.MustCompile(`(<hr\s*?/?>)`)
+	autopOptionOpen       = regexp.MustCompile(`\s*<option`)
+	autopOptionClose      = regexp.MustCompile(`</option>\s*`)
+	autopObjectOpen       = regexp.MustCompile(`(<object[^>]*>)\s*`)
+	autopObjectClose      = regexp.MustCompile(`\s*</object>`)
+	autopObjectParam      = regexp.MustCompile(`\s*(</?(?:param|embed)[^>]*>)\s*`)
+	autopMediaOpen        = regexp.MustCompile(`([<\[](?:audio|video)[^>\]]*[>\]])\s*`)
+	autopMediaClose       = regexp.MustCompile(`\s*([<\[]/(?:audio|video)[>\]])`)
+	autopMediaSource      = regexp.MustCompile(`\s*(<(?:source|track)[^>]*>)\s*`)
+	autopFigcaptionOpen   = regexp.MustCompile(`\s*(<figcaption[^>]*>)`)
+	autopFigcaptionClose  = regexp.MustCompile(`</figcaption>\s*`)
+	autopBreaks           = regexp.MustCompile(`\n\n+`)
+	autopParagraphs       = regexp.MustCompile(`\n\s*\n`)
+	autopEmptyParagraph   = regexp.MustCompile(`<p>\s*</p>`)
+	autopUnclosed         = regexp.MustCompile(`<p>([^<]+)</(div|address|form)>`)
+	autopWrappedBlock     = regexp.MustCompile(`<p>\s*(</?` + autopBlocks + `[^>]*>)\s*</p>`)
+	autopWrappedLi        = regexp.MustCompile(`<p>(<li.+?)</p>`)
+	autopBlockquote       = regexp.MustCompile(`(?i)<p><blockquote([^>]*)>`)
+	autopOpenBeforeBlock  = regexp.MustCompile(`<p>\s*(</?` + autopBlocks + `[^>]*>)`)
+	autopCloseAfterBlock  = regexp.MustCompile(`(</?` + autopBlocks + `[^>]*>)\s*</p>`)
+	autopNewline          = regexp.MustCompile(`\s*\n`)
+	autopBrAfterBlock     = regexp.MustCompile(`(</?` + autopBlocks + `[^>]*>)\s*<br />`)
+	autopBrBeforeBlock    = regexp.MustCompile(`<br />(\s*</?(?:p|li|div|dl|dd|dt|th|pre|td|ul|ol)[^>]*>)`)
+	autopTrailingNewline  = regexp.MustCompile(`\n</p>(\n?)$`)
+	autopHtmlElement      = regexp.MustCompile(`(?s)<!--.*?-->|<[^>]*>`)
+	autopPreservedElement = []*regexp.Regexp{
+		regexp.MustCompile(`(?s)<script.*?</script>`),
+		regexp.MustCompile(`(?s)<style.*?</style>`),
+		regexp.MustCompile(`(?s)<svg.*?</svg>`),
+		regexp.MustCompile(`(?s)<math.*?</math>`),
+	}
+)
+
+// autop replaces the double line breaks in the text with paragraphs and the single ones with `<br />`,
+// without wrapping block-level elements in paragraphs
+//
+// It is a port of WordPress's `wpautop`.
+func autop(text string) string {
+	if strings.TrimSpace(text) == "" {
+		return ""
+	}
+
+	text += "\n"
+
+	// pre elements are replaced by placeholders so that they are not touched
+	var preElements []string
+	if strings.Contains(text, "<pre") {
+		parts := strings.Split(text, "</pre>")
+
+		text = ""
+		for _, part := range parts[:len(parts)-1] {
+			start := strings.Index(part, "<pre")
+			if start == -1 {
+				text += part
+				continue
+			}
+
+			text += part[:start] + "<pre wp-pre-tag-" + strconv.Itoa(len(preElements)) + "></pre>"
+			preElements = append(preElements, part[start:]+"</pre>")
+		}
+
+		text += parts[len(parts)-1]
+	}
+
+	text = autopDoubleBreak.ReplaceAllString(text, "\n\n")
+	text = autopBlockOpen.ReplaceAllString(text, "\n\n$1")
+	text = autopBlockClose.ReplaceAllString(text, "$1\n\n")
+	text = autopHr.ReplaceAllString(text, "$1\n\n")
+	text = strings.NewReplacer("\r\n", "\n", "\r", "\n").Replace(text)
+
+	// newlines inside of tags are kept out of the way until the end
+	text = autopHtmlElement.ReplaceAllStringFunc(text, func(element string) string {
+		return strings.Replace(element, "\n", " <!-- wpnl --> ", -1)
+	})
+
+	if strings.Contains(text, "<option") {
+		text = autopOptionOpen.ReplaceAllString(text, "<option")
+		text = autopOptionClose.ReplaceAllString(text, "</option>")
+	}
+
+	if strings.Contains(text, "</object>") {
+		text = autopObjectOpen.ReplaceAllString(text, "$1")
+		text = autopObjectClose.ReplaceAllString(text, "</object>")
+		text = autopObjectParam.ReplaceAllString(text, "$1")
+	}
+
+	if strings.Contains(text, "<source") || strings.Contains(text, "<track") {
+		text = autopMediaOpen.ReplaceAllString(text, "$1")
+		text = autopMediaClose.ReplaceAllString(text, "$1")
+		text = autopMediaSource.ReplaceAllString(text, "$1")
+	}
+
+	if strings.Contains(text, "<figcaption") {
+		text = autopFigcaptionOpen.ReplaceAllString(text, "$1")
+		text = autopFigcaptionClose.ReplaceAllString(text, "</figcaption>")
+	}
+
+	text = autopBreaks.ReplaceAllString(text, "\n\n")
+
+	var paragraphs strings.Builder
+	for _, paragraph := range autopParagraphs.Split(text, -1) {
+		if paragraph != "" {
+			paragraphs.WriteString("<p>" + strings.Trim(paragraph, "\n") + "</p>\n")
+		}
+	}
+
+	text = paragraphs.String()
+	text = autopEmptyParagraph.ReplaceAllString(text, "")
+	text = autopUnclosed.ReplaceAllString(text, "<p>$1</p></$2>")
+	text = autopWrappedBlock.ReplaceAllString(text, "$1")
+	text = autopWrappedLi.ReplaceAllString(text, "$1")
+	text = autopBlockquote.ReplaceAllString(text, "<blockquote$1><p>")
+	text = strings.Replace(text, "</blockquote></p>", "</p></blockquote>", -1)
+	text = autopOpenBeforeBlock.ReplaceAllString(text, "$1")
+	text = autopCloseAfterBlock.ReplaceAllString(text, "$1")
+
+	// the newlines in elements that are not html are kept
+	for _, re := range autopPreservedElement {
+		text = re.ReplaceAllStringFunc(text, func(element string) string {
+			return strings.Replace(element, "\n", "<WPPreserveNewline />", -1)
+		})
+	}
+
+	text = strings.NewReplacer("<br>", "<br />", "<br/>", "<br />").Replace(text)
+	text = autopLineBreaks(text)
+	text = strings.Replace(text, "<WPPreserveNewline />", "\n", -1)
+
+	text = autopBrAfterBlock.ReplaceAllString(text, "$1")
+	text = autopBrBeforeBlock.ReplaceAllString(text, "$1")
+	text = autopTrailingNewline.ReplaceAllString(text, "</p>$1")
+
+	for i, element := range preElements {
+		text = strings.Replace(text, "<pre wp-pre-tag-"+strconv.Itoa(i)+"></pre>", element, 1)
+	}
+
+	return strings.NewReplacer(" <!-- wpnl --> ", "\n", "<!-- wpnl -->", "\n").Replace(text)
+}
+
+// autopLineBreaks replaces the newlines that do not follow a `<br />` with one,
+// since regexp does not support the lookbehind that WordPress uses
+func autopLineBreaks(text string) string {
+	var b strings.Builder
+	for i := 0; i < len(text); {
+		loc := autopNewline.FindStringIndex(text[i:])
+		if loc == nil {
+			b.WriteString(text[i:])
+			break
+		}
+
+		start, end := i+loc[0], i+loc[1]
+		if strings.HasSuffix(text[:start], "<br />") {
+			// the newline may still match from the next character
+			b.WriteString(text[i : start+1])
+			i = start + 1
+			continue
+		}
+
+		b.WriteString(text[i:start])
+		b.WriteString("<br />\n")
+		i = end
+	}
+
+	return b.String()
+}
@@ -0,0 +1,54 @@
+package wordpress
+
+import (
+	"testing"
+)
+
+func TestAutop(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want string
+	}{
+		{"empty", "   \n", ""},
+		{"paragraphs", "a\n\nb", "<p>a</p>\n<p>b</p>\n"},
+		{"extra newlines", "a\n\n\n\nb", "<p>a</p>\n<p>b</p>\n"},
+		{"line break", "a\nb", "<p>a<br />\nb</p>\n"},
+
+		// the newlines after existing breaks are not given another break,
+		// which WordPress matches with a lookbehind
+		{"existing break", "a<br />\nb", "<p>a<br />\nb</p>\n"},
+		{"existing unclosed break", "a<br>\nb", "<p>a<br />\nb</p>\n"},
+		{"double break", "a<br />\n<br />\nb", "<p>a</p>\n<p>b</p>\n"},
+
+		// pre elements are replaced by placeholders and put back untouched
+		{"pre", "<pre>a\n\nb</pre>", "<pre>a\n\nb</pre>\n"},
+		{"pre between paragraphs", "a\n\n<pre>x\ny</pre>\n\nb", "<p>a</p>\n<pre>x\ny</pre>\n<p>b</p>\n"},
+		{"multiple pre", "<pre>x\n\ny</pre>\n<pre>z\n\nw</pre>", "<pre>x\n\ny</pre>\n<pre>z\n\nw</pre>\n"},
+
+		{"block", "<div>a</div>", "<div>a</div>\n"},
+		{"blockquote", "<blockquote>a</blockquote>", "<blockquote><p>a</p></blockquote>\n"},
+		{"blockquote paragraphs", "<blockquote>a\n\nb</blockquote>", "<blockquote><p>a</p>\n<p>b</p></blockquote>\n"},
+		{"list", "<ul>\n<li>a</li>\n<li>b</li>\n</ul>", "<ul>\n<li>a</li>\n<li>b</li>\n</ul>\n"},
+		{"list items", "<li>a</li>\n\n<li>b</li>", "<li>a</li>\n<li>b</li>\n"},
+
+		// the newlines of elements that are not html are kept as they are
+		{"script", "<script>a\nb</script>", "<p><script>a\nb</script></p>\n"},
+		{"svg", "x\n\n<svg>\n<g/>\n</svg>", "<p>x</p>\n<p><svg>\n<g/>\n</svg></p>\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := autop(tt.text); got != tt.want {
+				t.Errorf("autop(%q) = %q, expected %q", tt.text, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRenderContentBlocks(t *testing.T) {
+	p := &Post{Object: Object{Content: "<!-- wp:paragraph -->\n<p>a\nb</p>\n<!-- /wp:paragraph -->"}}
+	if got := string(p.RenderContent()); got != p.Content {
+		t.Errorf("expected block content to be left alone, got %q", got)
+	}
+}
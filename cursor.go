@@ -0,0 +1,64 @@
+package wordpress
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// cursorVersion is incremented whenever the cursor format changes,
+// so that cursors from an older version are rejected instead of misread
+const cursorVersion = 1
+
+// ErrInvalidCursor is returned when a cursor could not be decoded
+var ErrInvalidCursor = errors.New("wordpress: invalid cursor")
+
+// cursor is the position of a row in the results of a query
+//
+// Cursors are handed to clients as the base64 encoding of the json encoded cursor,
+// but they should be treated as opaque strings.
+type cursor struct {
+	// The version of the cursor format
+	Version int `json:"v"`
+
+	// The column the query was ordered by
+	Order string `json:"o"`
+
+	// The value of the order column of the row
+	Value string `json:"k"`
+
+	// The id of the row
+	Id int64 `json:"i"`
+}
+
+// encodeCursor returns the cursor of the row with the given order column value and id
+func encodeCursor(order, value string, id int64) string {
+	b, _ := json.Marshal(&cursor{Version: cursorVersion, Order: order, Value: value, Id: id})
+	return base64.URLEncoding.EncodeToString(b)
+}
+
+// decodeCursor decodes the cursor and checks that it belongs to a query ordered by the given column
+func decodeCursor(s, order string) (*cursor, error) {
+	b, err := base64.URLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	var cur cursor
+	if err := json.Unmarshal(b, &cur); err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	if cur.Version != cursorVersion {
+		return nil, fmt.Errorf("wordpress: unsupported cursor version %d", cur.Version)
+	}
+
+	// cursors of object queries used to quote the column
+	if strings.Trim(cur.Order, "`") != order {
+		return nil, fmt.Errorf("wordpress: cursor is for a query ordered by %s, not %s", cur.Order, order)
+	}
+
+	return &cur, nil
+}
@@ -0,0 +1,87 @@
+package wordpress
+
+import (
+	"golang.org/x/net/context"
+)
+
+// CustomObject represents an object of a custom post type, i.e. a WooCommerce product
+type CustomObject struct {
+	Object
+
+	// All of the object's metadata, including the keys starting with an underscore
+	Meta map[string]string `json:"meta"`
+}
+
+// QueryObjectsOfType returns the ids of the objects of the post type that match the query
+//
+// The post type is only used if the options do not set one.
+func QueryObjectsOfType(c context.Context, postType PostType, opts *ObjectQueryOptions) (Iterator, error) {
+	c, span := startSpan(c, "/wordpress.QueryObjectsOfType")
+	defer span.End()
+
+	opts = opts.clone()
+	if opts.PostType == "" {
+		opts.PostType = postType
+	}
+
+	return queryObjects(c, opts)
+}
+
+// GetObjectsOfType gets the objects of the post type along with all of their metadata
+//
+// The metadata of all the objects is loaded in a single query.
+// Returns a `MissingResourcesError` if any of the objects do not exist or are of another type
+func GetObjectsOfType(c context.Context, postType PostType, objectIds ...int64) ([]*CustomObject, error) {
+	c, span := startSpan(c, "/wordpress.GetObjectsOfType")
+	defer span.End()
+
+	if len(objectIds) == 0 {
+		return []*CustomObject{}, nil
+	}
+
+	ids, idMap := dedupe(objectIds)
+
+	objects, err := getObjects(c, ids...)
+	if err != nil {
+		return nil, err
+	}
+
+	byId := make(map[int64]*CustomObject)
+	var typedIds []int64
+	for _, obj := range objects {
+		if obj.Type == postType {
+			byId[obj.Id] = &CustomObject{Object: *obj}
+			typedIds = append(typedIds, obj.Id)
+		}
+	}
+
+	meta, err := GetObjectsMeta(c, typedIds)
+	if err != nil {
+		return nil, err
+	}
+
+	for id, obj := range byId {
+		obj.Meta = meta[id]
+	}
+
+	ret := make([]*CustomObject, len(objectIds))
+	for id, obj := range byId {
+		// insert into return set
+		for _, index := range idMap[id] {
+			ret[index] = obj
+		}
+	}
+
+	var mre MissingResourcesError
+	for i, obj := range ret {
+		if obj == nil {
+			mre = append(mre, objectIds[i])
+		}
+	}
+
+	if len(mre) > 0 {
+		return nil, mre
+	}
+
+	return ret, nil
+}
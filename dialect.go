@@ -0,0 +1,134 @@
+package wordpress
+
+import (
+	"database/sql"
+	"strings"
+
+	"github.com/elgris/sqrl"
+	"golang.org/x/net/context"
+)
+
+// Dialect represents the flavour of SQL spoken by the database
+//
+// The value is also the name of the `database/sql` driver used to open the database.
+type Dialect string
+
+const (
+	// DialectMySQL is for the MySQL or MariaDB database that WordPress runs on
+	DialectMySQL Dialect = "mysql"
+
+	// DialectPostgres is for a PostgreSQL mirror of a WordPress database
+	//
+	// Only reading and options are supported, since the other writes rely on
+	// transactions, whose statements are not rewritten, and on insert ids.
+	DialectPostgres Dialect = "postgres"
+)
+
+// NewPostgres creates and returns a new connection to a PostgreSQL mirror of a WordPress database
+//
+// A driver must be registered as "postgres", i.e. by importing github.com/lib/pq.
+func NewPostgres(dsn string) (*WordPress, error) {
+	db, err := sql.Open(string(DialectPostgres), dsn)
+	if err != nil {
+		return nil, err
+	}
+
+	return &WordPress{db: db, Dialect: DialectPostgres}, nil
+}
+
+func dialect(c context.Context) Dialect {
+	if d, ok := c.Value(dialectKey).(Dialect); ok && d != "" {
+		return d
+	}
+
+	return DialectMySQL
+}
+
+// datePart returns the expression extracting the part (YEAR, MONTH, or DAY) of the date column
+func datePart(c context.Context, part, column string) string {
+	if dialect(c) == DialectPostgres {
+		return "EXTRACT(" + part + " FROM " + column + ")"
+	}
+
+	if part == "DAY" {
+		return "DAYOFMONTH(" + column + ")"
+	}
+
+	return part + "(" + column + ")"
+}
+
+// fullTextMatch returns the predicate matching the query against the titles and contents of objects
+//
+// MySQL's boolean mode operators are closest to Postgres's web search syntax.
+func fullTextMatch(c context.Context) string {
+	if dialect(c) == DialectPostgres {
+		return "to_tsvector(post_title || ' ' || post_content) @@ websearch_to_tsquery(?)"
+	}
+
+	return "MATCH(post_title, post_content) AGAINST (? IN BOOLEAN MODE)"
+}
+
+// fullTextRelevance returns the expression ranking the objects' relevance to the query
+func fullTextRelevance(c context.Context) string {
+	if dialect(c) == DialectPostgres {
+		return "ts_rank(to_tsvector(post_title || ' ' || post_content), websearch_to_tsquery(?))"
+	}
+
+	return "MATCH(post_title, post_content) AGAINST (? IN BOOLEAN MODE)"
+}
+
+// upsert returns the suffix of an insert statement that updates the columns
+// of the existing row instead when the unique key conflicts
+func upsert(c context.Context, key string, columns ...string) string {
+	sets := make([]string, len(columns))
+	if dialect(c) == DialectPostgres {
+		for i, column := range columns {
+			sets[i] = column + " = EXCLUDED." + column
+		}
+
+		return "ON CONFLICT (" + key + ") DO UPDATE SET " + strings.Join(sets, ", ")
+	}
+
+	for i, column := range columns {
+		sets[i] = column + " = VALUES(" + column + ")"
+	}
+
+	return "ON DUPLICATE KEY UPDATE " + strings.Join(sets, ", ")
+}
+
+// dialectDB rewrites the `?` placeholders built by sqrl to the database's format
+//
+// Statements executed in transactions are not rewritten.
+type dialectDB struct {
+	*sql.DB
+
+	placeholders sqrl.PlaceholderFormat
+}
+
+func (db *dialectDB) rebind(stmt string) string {
+	if db.placeholders == nil {
+		return stmt
+	}
+
+	// only fails for unterminated escapes, which sqrl never builds
+	if rebound, err := db.placeholders.ReplacePlaceholders(stmt); err == nil {
+		return rebound
+	}
+
+	return stmt
+}
+
+// QueryContext executes a query that returns rows
+func (db *dialectDB) QueryContext(c context.Context, stmt string, args ...interface{}) (*sql.Rows, error) {
+	return db.DB.QueryContext(c, db.rebind(stmt), args...)
+}
+
+// QueryRowContext executes a query that is expected to return at most one row
+func (db *dialectDB) QueryRowContext(c context.Context, stmt string, args ...interface{}) *sql.Row {
+	return db.DB.QueryRowContext(c, db.rebind(stmt), args...)
+}
+
+// ExecContext executes a query without returning any rows
+func (db *dialectDB) ExecContext(c context.Context, stmt string, args ...interface{}) (sql.Result, error) {
+	return db.DB.ExecContext(c, db.rebind(stmt), args...)
+}
@@ -0,0 +1,99 @@
+package wordpress
+
+import (
+	"database/sql"
+	"strconv"
+	"time"
+
+	"golang.org/x/net/context"
+)
+
+// DiscussionSettings represents the site's settings that gate commenting
+type DiscussionSettings struct {
+	// Whether comments are allowed on new posts
+	DefaultCommentStatus bool `json:"default_comment_status"`
+
+	// Whether comment authors must fill out their name and email
+	RequireNameEmail bool `json:"require_name_email"`
+
+	// Whether users must be registered and logged in to comment
+	CommentRegistration bool `json:"comment_registration"`
+
+	// Whether comments are automatically closed on old posts
+	CloseCommentsForOldPosts bool `json:"close_comments_for_old_posts"`
+
+	// The age in days after which comments are closed
+	CloseCommentsDaysOld int `json:"close_comments_days_old"`
+}
+
+// GetDiscussionSettings gets the discussion settings from the options table
+//
+// Options that are not set take WordPress's default values.
+func GetDiscussionSettings(c context.Context) (*DiscussionSettings, error) {
+	c, span := startSpan(c, "/wordpress.GetDiscussionSettings")
+	defer span.End()
+
+	options := map[string]string{
+		"default_comment_status":       "open",
+		"require_name_email":           "1",
+		"comment_registration":         "0",
+		"close_comments_for_old_posts": "0",
+		"close_comments_days_old":      "14",
+	}
+
+	for name := range options {
+		value, err := GetOption(c, name)
+		if err == sql.ErrNoRows {
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+
+		options[name] = value
+	}
+
+	settings := &DiscussionSettings{
+		DefaultCommentStatus:     options["default_comment_status"] == "open",
+		RequireNameEmail:         options["require_name_email"] == "1",
+		CommentRegistration:      options["comment_registration"] == "1",
+		CloseCommentsForOldPosts: options["close_comments_for_old_posts"] == "1",
+	}
+
+	settings.CloseCommentsDaysOld, _ = strconv.Atoi(options["close_comments_days_old"])
+
+	return settings, nil
+}
+
+// CommentsOpen returns whether new comments are allowed on the object
+//
+// Comments must be open on the object itself, and posts older than
+// the `close_comments_days_old` option are closed if auto-closing is enabled.
+func (obj *Object) CommentsOpen(c context.Context) (bool, error) {
+	c, span := startSpan(c, "/wordpress.Object.CommentsOpen")
+	defer span.End()
+
+	if !obj.CommentStatus {
+		return false, nil
+	}
+
+	// like WordPress, only posts are automatically closed
+	if obj.Type != PostTypePost {
+		return true, nil
+	}
+
+	settings, err := GetDiscussionSettings(c)
+	if err != nil {
+		return false, err
+	}
+
+	if !settings.CloseCommentsForOldPosts || settings.CloseCommentsDaysOld <= 0 {
+		return true, nil
+	}
+
+	date := obj.DateGmt
+	if date.IsZero() {
+		date = obj.Date
+	}
+
+	return time.Since(date) < time.Duration(settings.CloseCommentsDaysOld)*24*time.Hour, nil
+}
@@ -34,16 +34,23 @@ const (
 
 	// PostStatusInherit inherits its status from its parent
 	PostStatusInherit PostStatus = "inherit"
+
+	// PostStatusAny matches posts of every status when querying
+	PostStatusAny PostStatus = "any"
 )
 
 // Scan formats incoming data from a sql database
-func (s PostStatus) Scan(src interface{}) error {
-	if arr, ok := src.([]uint8); ok {
-		s = PostStatus(arr)
-		return nil
+func (s *PostStatus) Scan(src interface{}) error {
+	switch src := src.(type) {
+	case []uint8:
+		*s = PostStatus(src)
+	case string:
+		*s = PostStatus(src)
+	default:
+		return errors.New("the source is not a string")
 	}
 
-	return errors.New("the source is not a []uint8")
+	return nil
 }
 
 // PostType represents a WordPress post type
@@ -74,13 +81,17 @@ const (
 )
 
 // Scan formats incoming data from a sql database
-func (t PostType) Scan(src interface{}) error {
-	if arr, ok := src.([]uint8); ok {
-		t = PostType(arr)
-		return nil
+func (t *PostType) Scan(src interface{}) error {
+	switch src := src.(type) {
+	case []uint8:
+		*t = PostType(src)
+	case string:
+		*t = PostType(src)
+	default:
+		return errors.New("the source is not a string")
 	}
 
-	return errors.New("the source is not a []uint8")
+	return nil
 }
 
 // MenuItemType represents menu item link types
@@ -114,3 +125,55 @@ const (
 	// TaxonomyPostTag is for post tags
 	TaxonomyPostTag Taxonomy = "post_tag"
 )
+
+// StickyMode represents how sticky posts are treated when querying
+//
+// Sticky posts are treated like any other post if no mode is given.
+type StickyMode string
+
+const (
+	// StickyInclude floats sticky posts to the top of the results
+	StickyInclude StickyMode = "include"
+
+	// StickyExclude leaves sticky posts out of the results
+	StickyExclude StickyMode = "exclude"
+
+	// StickyOnly leaves everything but sticky posts out of the results
+	StickyOnly StickyMode = "only"
+)
+
+// CommentStatus represents the moderation status of a comment
+//
+// It is stored in the `comment_approved` column.
+type CommentStatus string
+
+const (
+	// CommentStatusApproved is a comment that is publicly visible
+	CommentStatusApproved CommentStatus = "1"
+
+	// CommentStatusPending is a comment that is awaiting moderation
+	CommentStatusPending CommentStatus = "0"
+
+	// CommentStatusSpam is a comment that was marked as spam
+	CommentStatusSpam CommentStatus = "spam"
+
+	// CommentStatusTrash is a comment that was trashed
+	CommentStatusTrash CommentStatus = "trash"
+
+	// CommentStatusAny matches comments of every status when querying
+	CommentStatusAny CommentStatus = "any"
+)
+
+// Scan formats incoming data from a sql database
+func (s *CommentStatus) Scan(src interface{}) error {
+	switch src := src.(type) {
+	case []uint8:
+		*s = CommentStatus(src)
+	case string:
+		*s = CommentStatus(src)
+	default:
+		return errors.New("the source is not a string")
+	}
+
+	return nil
+}
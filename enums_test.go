@@ -0,0 +1,59 @@
+package wordpress
+
+import "testing"
+
+func TestPostStatusScan(t *testing.T) {
+	for _, src := range []interface{}{"draft", []byte("draft")} {
+		var s PostStatus
+		if err := s.Scan(src); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if s != PostStatusDraft {
+			t.Errorf("expected %T %v to scan as %q, got %q", src, src, PostStatusDraft, s)
+		}
+	}
+
+	var s PostStatus
+	if err := s.Scan(1); err == nil {
+		t.Errorf("expected an error scanning a non-string, got %q", s)
+	}
+}
+
+func TestPostTypeScan(t *testing.T) {
+	for _, src := range []interface{}{"page", []byte("page")} {
+		var typ PostType
+		if err := typ.Scan(src); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if typ != PostTypePage {
+			t.Errorf("expected %T %v to scan as %q, got %q", src, src, PostTypePage, typ)
+		}
+	}
+
+	var typ PostType
+	if err := typ.Scan(nil); err == nil {
+		t.Errorf("expected an error scanning a non-string, got %q", typ)
+	}
+}
+
+func TestGetObjectsTypeAndStatus(t *testing.T) {
+	c := newTestContext(t)
+
+	post := testPost(t, c, "Post", testDate)
+	page := testObject(t, c, map[string]interface{}{"post_type": string(PostTypePage), "post_status": string(PostStatusDraft)})
+
+	objs, err := getObjects(c, post, page)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if objs[0].Type != PostTypePost || objs[0].Status != PostStatusPublish {
+		t.Errorf("expected a published post, got %s %s", objs[0].Status, objs[0].Type)
+	}
+
+	if objs[1].Type != PostTypePage || objs[1].Status != PostStatusDraft {
+		t.Errorf("expected a draft page, got %s %s", objs[1].Status, objs[1].Type)
+	}
+}
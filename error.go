@@ -2,29 +2,75 @@ package wordpress
 
 import (
 	"bytes"
+	"errors"
 	"strconv"
 )
 
+// ErrNotFound is returned when a resource looked up by something other than its id does not exist
+var ErrNotFound = errors.New("wordpress: not found")
+
+// SlugConflictError is returned when creating a term whose slug is already used in its taxonomy
+type SlugConflictError struct {
+	Taxonomy Taxonomy
+	Slug     string
+}
+
+func (err *SlugConflictError) Error() string {
+	return "wordpress: " + string(err.Taxonomy) + " slug already exists: " + err.Slug
+}
+
+// InvalidOrderError is returned when querying with an order column that is not allowed
+type InvalidOrderError struct {
+	Column string
+}
+
+func (err *InvalidOrderError) Error() string {
+	return "wordpress: cannot order by " + strconv.Quote(err.Column)
+}
+
+// AuthenticationError is returned when a login and password do not match any user
+//
+// The same error is returned whether the login or the password is wrong.
+type AuthenticationError struct {
+	Login string
+}
+
+func (err *AuthenticationError) Error() string {
+	return "wordpress: incorrect login or password for " + strconv.Quote(err.Login)
+}
+
+// MissingResourcesError is returned when any of the resources requested by id do not exist
+//
+// It lists the missing ids, and can be found in wrapped errors with `errors.As`.
 type MissingResourcesError []int64
 
+// IDs returns the ids of the missing resources, without repeating any
+func (ids MissingResourcesError) IDs() []int64 {
+	deduped, _ := dedupe(ids)
+	return deduped
+}
+
 func (ids MissingResourcesError) Error() string {
-	if len(ids) == 0 {
-		return ""
+	deduped := ids.IDs()
+	switch len(deduped) {
+	case 0:
+		return "wordpress: could not find resources"
+	case 1:
+		return "wordpress: could not find id " + strconv.FormatInt(deduped[0], 10)
 	}
 
 	var msg bytes.Buffer
 	msg.WriteString("wordpress: could not find ids ")
-	for _, id := range ids[:len(ids)-1] {
+	for i, id := range deduped[:len(deduped)-1] {
+		if i > 0 {
+			msg.WriteString(", ")
+		}
+
 		msg.WriteString(strconv.FormatInt(id, 10))
-		msg.WriteRune(',')
-		msg.WriteRune(' ')
 	}
-	msg.Truncate(msg.Len() - 2)
-	if len(ids) > 1 {
-		msg.WriteString(" and")
-	}
-	msg.WriteRune(' ')
-	msg.WriteString(strconv.FormatInt(ids[len(ids)-1], 10))
+
+	msg.WriteString(" and ")
+	msg.WriteString(strconv.FormatInt(deduped[len(deduped)-1], 10))
 
 	return msg.String()
 }
@@ -0,0 +1,110 @@
+package wordpress
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func TestMissingResourcesErrorMessage(t *testing.T) {
+	tests := []struct {
+		err  MissingResourcesError
+		want string
+	}{
+		{nil, "wordpress: could not find resources"},
+		{MissingResourcesError{}, "wordpress: could not find resources"},
+		{MissingResourcesError{4}, "wordpress: could not find id 4"},
+		{MissingResourcesError{4, 4}, "wordpress: could not find id 4"},
+		{MissingResourcesError{4, 2}, "wordpress: could not find ids 4 and 2"},
+		{MissingResourcesError{4, 2, 4, 9}, "wordpress: could not find ids 4, 2 and 9"},
+	}
+
+	for _, tt := range tests {
+		if got := tt.err.Error(); got != tt.want {
+			t.Errorf("%v.Error() = %q, expected %q", []int64(tt.err), got, tt.want)
+		}
+	}
+}
+
+func TestMissingResourcesErrorIDs(t *testing.T) {
+	err := MissingResourcesError{3, 1, 3, 2, 1}
+	if got, want := err.IDs(), []int64{3, 1, 2}; !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+
+	// the error can be found when wrapped
+	var mre MissingResourcesError
+	if !errors.As(fmt.Errorf("loading posts: %w", err), &mre) || len(mre.IDs()) != 3 {
+		t.Errorf("expected to find the wrapped error, got %v", mre)
+	}
+}
+
+func TestMissingResources(t *testing.T) {
+	c := newTestContext(t)
+
+	post := testPost(t, c, "Hello World", testDate)
+	term := testTerm(t, c, TaxonomyCategory, "News", 0)
+	user := testExec(t, c, "INSERT INTO wp_users (user_login, user_nicename) VALUES ('admin', 'admin')")
+	userId, err := user.LastInsertId()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// users are found with and without a description
+	described := testExec(t, c, "INSERT INTO wp_users (user_login, user_nicename) VALUES ('editor', 'editor')")
+	describedId, err := described.LastInsertId()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testExec(t, c, "INSERT INTO wp_usermeta (user_id, meta_key, meta_value) VALUES (?, 'description', 'Edits things')", describedId)
+
+	tests := []struct {
+		name  string
+		get   func(ids ...int64) (interface{}, error)
+		found int64
+	}{
+		{
+			name:  "getObjects",
+			get:   func(ids ...int64) (interface{}, error) { return getObjects(c, ids...) },
+			found: post,
+		},
+		{
+			name:  "getTerms",
+			get:   func(ids ...int64) (interface{}, error) { return getTerms(c, ids...) },
+			found: term,
+		},
+		{
+			name:  "GetUsers",
+			get:   func(ids ...int64) (interface{}, error) { return GetUsers(c, ids...) },
+			found: userId,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := tt.get(tt.found); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			absent := tt.found + 100
+
+			_, err := tt.get(tt.found, absent)
+
+			var mre MissingResourcesError
+			if !errors.As(err, &mre) || !reflect.DeepEqual(mre.IDs(), []int64{absent}) {
+				t.Errorf("expected id %d to be missing, got %v", absent, err)
+			}
+		})
+	}
+
+	users, err := GetUsers(c, userId, describedId)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if users[0].Description != "" || users[1].Description != "Edits things" {
+		t.Errorf("expected the descriptions %q and %q, got %q and %q", "", "Edits things", users[0].Description, users[1].Description)
+	}
+}
@@ -0,0 +1,379 @@
+package wordpress
+
+import (
+	"encoding/xml"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/net/context"
+)
+
+// FeedFormat represents a syndication feed format
+type FeedFormat string
+
+const (
+	// FeedFormatRSS2 is an RSS 2.0 feed
+	FeedFormatRSS2 FeedFormat = "rss2"
+
+	// FeedFormatAtom is an Atom 1.0 feed
+	FeedFormatAtom FeedFormat = "atom"
+)
+
+// FeedOptions represents the available parameters for generating feeds
+type FeedOptions struct {
+	// The feed format, defaults to RSS 2.0
+	Format FeedFormat
+
+	// The url of the feed itself
+	SelfLink string
+
+	// Only include posts in this category or its children
+	Category int64
+
+	// Only include posts by this author
+	Author int64
+
+	// The number of posts to include, defaults to the `posts_per_rss` option
+	Limit int
+
+	// Only include the excerpt instead of the full content,
+	// defaults to the `rss_use_excerpt` option
+	ExcerptOnly *bool
+}
+
+type rssFeed struct {
+	XMLName   xml.Name   `xml:"rss"`
+	Version   string     `xml:"version,attr"`
+	ContentNS string     `xml:"xmlns:content,attr"`
+	DcNS      string     `xml:"xmlns:dc,attr"`
+	AtomNS    string     `xml:"xmlns:atom,attr"`
+	Channel   rssChannel `xml:"channel"`
+}
+
+type rssChannel struct {
+	Title         string    `xml:"title"`
+	AtomLink      *atomLink `xml:"atom:link,omitempty"`
+	Link          string    `xml:"link"`
+	Description   string    `xml:"description"`
+	LastBuildDate string    `xml:"lastBuildDate,omitempty"`
+	Items         []rssItem `xml:"item"`
+}
+
+type rssItem struct {
+	Title       string   `xml:"title"`
+	Link        string   `xml:"link"`
+	PubDate     string   `xml:"pubDate"`
+	Creator     string   `xml:"dc:creator,omitempty"`
+	Categories  []string `xml:"category"`
+	Guid        rssGuid  `xml:"guid"`
+	Description string   `xml:"description"`
+	Content     string   `xml:"content:encoded,omitempty"`
+}
+
+type rssGuid struct {
+	IsPermaLink bool   `xml:"isPermaLink,attr"`
+	Value       string `xml:",chardata"`
+}
+
+type atomFeed struct {
+	XMLName  xml.Name    `xml:"http://www.w3.org/2005/Atom feed"`
+	Title    string      `xml:"title"`
+	Subtitle string      `xml:"subtitle,omitempty"`
+	Id       string      `xml:"id"`
+	Updated  string      `xml:"updated"`
+	Links    []atomLink  `xml:"link"`
+	Entries  []atomEntry `xml:"entry"`
+}
+
+type atomLink struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr,omitempty"`
+	Type string `xml:"type,attr,omitempty"`
+}
+
+type atomEntry struct {
+	Title      string         `xml:"title"`
+	Id         string         `xml:"id"`
+	Links      []atomLink     `xml:"link"`
+	Published  string         `xml:"published"`
+	Updated    string         `xml:"updated"`
+	Author     *atomPerson    `xml:"author,omitempty"`
+	Categories []atomCategory `xml:"category"`
+	Summary    *atomText      `xml:"summary,omitempty"`
+	Content    *atomText      `xml:"content,omitempty"`
+}
+
+type atomPerson struct {
+	Name string `xml:"name"`
+}
+
+type atomCategory struct {
+	Term string `xml:"term,attr"`
+}
+
+type atomText struct {
+	Type  string `xml:"type,attr"`
+	Value string `xml:",chardata"`
+}
+
+// feedItem is the format-agnostic representation of a post in a feed
+type feedItem struct {
+	post       *Post
+	link       string
+	author     string
+	categories []string
+}
+
+// GenerateFeed writes a feed of the most recent published posts
+//
+// The feed's title, description, and link are taken from the `blogname`,
+// `blogdescription`, and `home` options.
+func GenerateFeed(c context.Context, w io.Writer, opts FeedOptions) error {
+	c, span := startSpan(c, "/wordpress.GenerateFeed")
+	defer span.End()
+
+	title, err := GetOption(c, "blogname")
+	if err != nil {
+		return err
+	}
+
+	description, err := GetOption(c, "blogdescription")
+	if err != nil {
+		return err
+	}
+
+	home, err := GetOption(c, "home")
+	if err != nil {
+		return err
+	}
+
+	home = strings.TrimRight(home, "/")
+
+	if opts.Limit <= 0 {
+		opts.Limit = 10
+		if value, err := GetOption(c, "posts_per_rss"); err == nil {
+			if n, err := strconv.Atoi(value); err == nil && n > 0 {
+				opts.Limit = n
+			}
+		}
+	}
+
+	if opts.ExcerptOnly == nil {
+		value, _ := GetOption(c, "rss_use_excerpt")
+		excerptOnly := value == "1"
+		opts.ExcerptOnly = &excerptOnly
+	}
+
+	if opts.Category > 0 {
+		categories, err := GetCategories(c, opts.Category)
+		if err != nil {
+			return err
+		}
+
+		if len(categories) > 0 && categories[0] != nil {
+			title += " » " + categories[0].Name
+		}
+	}
+
+	it, err := QueryPosts(c, &ObjectQueryOptions{
+		Category: opts.Category,
+		Author:   opts.Author,
+		Limit:    opts.Limit})
+	if err != nil {
+		return err
+	}
+
+	ids, err := it.Slice()
+	if err != nil {
+		return err
+	}
+
+	items, err := getFeedItems(c, home, ids)
+	if err != nil {
+		return err
+	}
+
+	spanInt64(span, "wp/feed/count", int64(len(items)))
+
+	var feed interface{}
+	if opts.Format == FeedFormatAtom {
+		feed = newAtomFeed(title, description, home, opts, items)
+	} else {
+		feed = newRSSFeed(title, description, home, opts, items)
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "\t")
+	if err := enc.Encode(feed); err != nil {
+		return err
+	}
+
+	_, err = io.WriteString(w, "\n")
+	return err
+}
+
+// getFeedItems loads the posts along with their authors and term names
+func getFeedItems(c context.Context, home string, ids []int64) ([]*feedItem, error) {
+	posts, err := GetPosts(c, ids...)
+	if err != nil {
+		return nil, err
+	}
+
+	var authorIds, categoryIds, tagIds []int64
+	for _, p := range posts {
+		authorIds = append(authorIds, p.AuthorId)
+		categoryIds = append(categoryIds, p.CategoryIds...)
+		tagIds = append(tagIds, p.TagIds...)
+	}
+
+	users, err := GetUsers(c, authorIds...)
+	if err != nil {
+		return nil, err
+	}
+
+	categories, err := GetCategories(c, categoryIds...)
+	if err != nil {
+		return nil, err
+	}
+
+	tags, err := GetTags(c, tagIds...)
+	if err != nil {
+		return nil, err
+	}
+
+	names := make(map[int64]string)
+	for _, u := range users {
+		names[u.Id] = u.Name
+	}
+
+	termNames := make(map[int64]string)
+	for _, cat := range categories {
+		termNames[cat.Id] = cat.Name
+	}
+
+	for _, tag := range tags {
+		termNames[tag.Id] = tag.Name
+	}
+
+	links, err := newPermalinkResolver(c)
+	if err != nil {
+		return nil, err
+	}
+
+	ret := make([]*feedItem, len(posts))
+	for i, p := range posts {
+		link, err := links.link(c, &p.Object, p.CategoryIds)
+		if err != nil {
+			return nil, err
+		}
+
+		item := &feedItem{post: p, link: home + link, author: names[p.AuthorId]}
+		for _, id := range append(append([]int64{}, p.CategoryIds...), p.TagIds...) {
+			if name, ok := termNames[id]; ok {
+				item.categories = append(item.categories, name)
+			}
+		}
+
+		ret[i] = item
+	}
+
+	return ret, nil
+}
+
+// excerpt returns the post's excerpt, or its content if there is none
+func (item *feedItem) excerpt() string {
+	if item.post.Excerpt != "" {
+		return item.post.Excerpt
+	}
+
+	return item.post.Content
+}
+
+func newRSSFeed(title, description, home string, opts FeedOptions, items []*feedItem) *rssFeed {
+	feed := &rssFeed{
+		Version:   "2.0",
+		ContentNS: "http://purl.org/rss/1.0/modules/content/",
+		DcNS:      "http://purl.org/dc/elements/1.1/",
+		AtomNS:    "http://www.w3.org/2005/Atom",
+		Channel: rssChannel{
+			Title:       title,
+			Link:        home,
+			Description: description}}
+
+	if opts.SelfLink != "" {
+		feed.Channel.AtomLink = &atomLink{Href: opts.SelfLink, Rel: "self", Type: "application/rss+xml"}
+	}
+
+	for _, item := range items {
+		if feed.Channel.LastBuildDate == "" {
+			feed.Channel.LastBuildDate = item.post.ModifiedGmt.UTC().Format(time.RFC1123Z)
+		}
+
+		rss := rssItem{
+			Title:       item.post.Title,
+			Link:        item.link,
+			PubDate:     item.post.DateGmt.UTC().Format(time.RFC1123Z),
+			Creator:     item.author,
+			Categories:  item.categories,
+			Guid:        rssGuid{Value: item.post.Guid},
+			Description: item.excerpt()}
+
+		if !*opts.ExcerptOnly {
+			rss.Content = item.post.Content
+		}
+
+		feed.Channel.Items = append(feed.Channel.Items, rss)
+	}
+
+	return feed
+}
+
+func newAtomFeed(title, description, home string, opts FeedOptions, items []*feedItem) *atomFeed {
+	feed := &atomFeed{
+		Title:    title,
+		Subtitle: description,
+		Id:       home,
+		Updated:  time.Now().UTC().Format(time.RFC3339),
+		Links:    []atomLink{{Href: home, Rel: "alternate", Type: "text/html"}}}
+
+	if opts.SelfLink != "" {
+		feed.Id = opts.SelfLink
+		feed.Links = append(feed.Links, atomLink{Href: opts.SelfLink, Rel: "self", Type: "application/atom+xml"})
+	}
+
+	for i, item := range items {
+		if i == 0 {
+			feed.Updated = item.post.ModifiedGmt.UTC().Format(time.RFC3339)
+		}
+
+		entry := atomEntry{
+			Title:     item.post.Title,
+			Id:        item.post.Guid,
+			Links:     []atomLink{{Href: item.link, Rel: "alternate", Type: "text/html"}},
+			Published: item.post.DateGmt.UTC().Format(time.RFC3339),
+			Updated:   item.post.ModifiedGmt.UTC().Format(time.RFC3339),
+			Summary:   &atomText{Type: "html", Value: item.excerpt()}}
+
+		if item.author != "" {
+			entry.Author = &atomPerson{Name: item.author}
+		}
+
+		for _, name := range item.categories {
+			entry.Categories = append(entry.Categories, atomCategory{Term: name})
+		}
+
+		if !*opts.ExcerptOnly {
+			entry.Content = &atomText{Type: "html", Value: item.post.Content}
+		}
+
+		feed.Entries = append(feed.Entries, entry)
+	}
+
+	return feed
+}
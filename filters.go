@@ -0,0 +1,125 @@
+package wordpress
+
+import (
+	"fmt"
+	"sync"
+
+	"golang.org/x/net/context"
+)
+
+// AfterGetPostsFilterFunc is run on the results of `GetPosts`
+type AfterGetPostsFilterFunc func(c context.Context, posts []*Post) ([]*Post, error)
+
+// AfterGetAttachmentsFilterFunc is run on the results of `GetAttachments`
+type AfterGetAttachmentsFilterFunc func(c context.Context, attachments []*Attachment) ([]*Attachment, error)
+
+// AfterGetTermsFilterFunc is run on every set of terms loaded from the database,
+// including the terms that categories and tags are loaded from
+//
+// Term filters must return every term they are given, in the same order,
+// otherwise the loading function returns an error.
+type AfterGetTermsFilterFunc func(c context.Context, terms []*Term) ([]*Term, error)
+
+// AfterGetCategoriesFilterFunc is run on the results of `GetCategories` and `GetCategoriesWithLinks`
+type AfterGetCategoriesFilterFunc func(c context.Context, categories []*Category) ([]*Category, error)
+
+// AfterGetTagsFilterFunc is run on the results of `GetTags`
+type AfterGetTagsFilterFunc func(c context.Context, tags []*Tag) ([]*Tag, error)
+
+// AfterGetUsersFilterFunc is run on the results of `GetUsers`
+type AfterGetUsersFilterFunc func(c context.Context, users []*User) ([]*User, error)
+
+// AfterGetMenuItemsFilterFunc is run on the results of `GetMenuItems`
+type AfterGetMenuItemsFilterFunc func(c context.Context, menuItems []*MenuItem) ([]*MenuItem, error)
+
+// The after-get filters post-process the resources loaded from the database,
+// returning the resources to use in their place.
+//
+// Filters are usually added during initialization, and are run on the results of every call
+// in the order they were added, each receiving the results of the one before.
+var (
+	afterGetPostsFilters       filters[*Post]
+	afterGetAttachmentsFilters filters[*Attachment]
+	afterGetTermsFilters       = filters[*Term]{name: "term", sameLength: true}
+	afterGetCategoriesFilters  filters[*Category]
+	afterGetTagsFilters        filters[*Tag]
+	afterGetUsersFilters       filters[*User]
+	afterGetMenuItemsFilters   filters[*MenuItem]
+)
+
+// AddAfterGetPostsFilter registers a filter that is run on the results of every `GetPosts` call
+func AddAfterGetPostsFilter(f AfterGetPostsFilterFunc) {
+	afterGetPostsFilters.add(f)
+}
+
+// AddAfterGetAttachmentsFilter registers a filter that is run on the results of every `GetAttachments` call
+func AddAfterGetAttachmentsFilter(f AfterGetAttachmentsFilterFunc) {
+	afterGetAttachmentsFilters.add(f)
+}
+
+// AddAfterGetTermsFilter registers a filter that is run on every set of terms loaded from the database
+func AddAfterGetTermsFilter(f AfterGetTermsFilterFunc) {
+	afterGetTermsFilters.add(f)
+}
+
+// AddAfterGetCategoriesFilter registers a filter that is run on the results of
+// every `GetCategories` and `GetCategoriesWithLinks` call
+func AddAfterGetCategoriesFilter(f AfterGetCategoriesFilterFunc) {
+	afterGetCategoriesFilters.add(f)
+}
+
+// AddAfterGetTagsFilter registers a filter that is run on the results of every `GetTags` call
+func AddAfterGetTagsFilter(f AfterGetTagsFilterFunc) {
+	afterGetTagsFilters.add(f)
+}
+
+// AddAfterGetUsersFilter registers a filter that is run on the results of every `GetUsers` call
+func AddAfterGetUsersFilter(f AfterGetUsersFilterFunc) {
+	afterGetUsersFilters.add(f)
+}
+
+// AddAfterGetMenuItemsFilter registers a filter that is run on the results of every `GetMenuItems` call
+func AddAfterGetMenuItemsFilter(f AfterGetMenuItemsFilterFunc) {
+	afterGetMenuItemsFilters.add(f)
+}
+
+// filters are the registered after-get filters of a type of resource
+type filters[T any] struct {
+	mu  sync.RWMutex
+	fns []func(c context.Context, items []T) ([]T, error)
+
+	// name is the resource named in errors
+	name string
+
+	// sameLength requires the filters to return as many items as they are given
+	sameLength bool
+}
+
+func (f *filters[T]) add(fn func(c context.Context, items []T) ([]T, error)) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.fns = append(f.fns, fn)
+}
+
+// apply runs the filters on the items, returning the filtered items
+func (f *filters[T]) apply(c context.Context, items []T) ([]T, error) {
+	f.mu.RLock()
+	fns := f.fns
+	f.mu.RUnlock()
+
+	for _, fn := range fns {
+		filtered, err := fn(c, items)
+		if err != nil {
+			return nil, err
+		}
+
+		if f.sameLength && len(filtered) != len(items) {
+			return nil, fmt.Errorf("wordpress: %s filter returned %d items instead of %d", f.name, len(filtered), len(items))
+		}
+
+		items = filtered
+	}
+
+	return items, nil
+}
@@ -0,0 +1,37 @@
+package wordpress
+
+import (
+	"testing"
+
+	"golang.org/x/net/context"
+)
+
+func TestFiltersApplyInOrder(t *testing.T) {
+	var f filters[*Term]
+	f.add(func(c context.Context, terms []*Term) ([]*Term, error) {
+		return append(terms, &Term{Name: "first"}), nil
+	})
+	f.add(func(c context.Context, terms []*Term) ([]*Term, error) {
+		return append(terms, &Term{Name: "second"}), nil
+	})
+
+	terms, err := f.apply(context.Background(), nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(terms) != 2 || terms[0].Name != "first" || terms[1].Name != "second" {
+		t.Errorf("expected the filters to run in order, got %v", terms)
+	}
+}
+
+func TestFiltersSameLength(t *testing.T) {
+	f := filters[*Term]{name: "term", sameLength: true}
+	f.add(func(c context.Context, terms []*Term) ([]*Term, error) {
+		return terms[1:], nil
+	})
+
+	if _, err := f.apply(context.Background(), []*Term{{Id: 1}, {Id: 2}}); err == nil {
+		t.Error("expected an error when a term filter drops terms")
+	}
+}
@@ -1,9 +1,21 @@
 module github.com/ssttevee/go-wordpress
 
+go 1.18
+
 require (
-	cloud.google.com/go v0.45.0
 	github.com/elgris/sqrl v0.0.0-20190909141434-5a439265eeec
 	github.com/go-sql-driver/mysql v1.5.0
+	github.com/graph-gophers/graphql-go v1.3.0
+	github.com/mattn/go-sqlite3 v1.14.16
 	github.com/wulijun/go-php-serialize v0.0.0-20131104125240-bfe692b0100e
-	golang.org/x/net v0.0.0-20201002202402-0a1ea396d57c
+	go.opentelemetry.io/otel v1.7.0
+	go.opentelemetry.io/otel/trace v1.7.0
+	golang.org/x/crypto v0.21.0
+	golang.org/x/net v0.21.0
+)
+
+require (
+	github.com/go-logr/logr v1.2.3 // indirect
+	github.com/go-logr/stdr v1.2.2 // indirect
+	github.com/opentracing/opentracing-go v1.1.0 // indirect
 )
@@ -0,0 +1,243 @@
+package graphql
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/ssttevee/go-wordpress"
+	"golang.org/x/net/context"
+)
+
+// batchWait is how long a loader waits for more ids before querying the database
+const batchWait = time.Millisecond
+
+type ctxKey int
+
+var loadersKey interface{} = ctxKey(0)
+
+// fetchFunc loads the values for the given ids, returning them in the same order
+//
+// It may return a `wordpress.MissingResourcesError` if any of the ids do not exist.
+type fetchFunc func(c context.Context, ids []int64) ([]interface{}, error)
+
+// loader coalesces concurrent lookups into a single batched fetch
+//
+// Results are memoized for the lifetime of the loader, which is a single request.
+type loader struct {
+	fetch fetchFunc
+
+	mu    sync.Mutex
+	batch *batch
+	cache map[int64]*batch
+}
+
+type batch struct {
+	ids  []int64
+	done chan struct{}
+
+	results map[int64]interface{}
+	err     error
+}
+
+func newLoader(fetch fetchFunc) *loader {
+	return &loader{fetch: fetch, cache: make(map[int64]*batch)}
+}
+
+// load returns the value for the id, or nil if it does not exist
+//
+// Missing ids do not fail the lookups of the other ids in the same batch.
+func (l *loader) load(c context.Context, id int64) (interface{}, error) {
+	values, err := l.loadMany(c, id)
+	if err != nil {
+		return nil, err
+	}
+
+	return values[0], nil
+}
+
+// loadMany returns the values for the ids in the same order
+func (l *loader) loadMany(c context.Context, ids ...int64) ([]interface{}, error) {
+	batches := make([]*batch, len(ids))
+
+	l.mu.Lock()
+	for i, id := range ids {
+		if b, ok := l.cache[id]; ok {
+			batches[i] = b
+			continue
+		}
+
+		if l.batch == nil {
+			l.batch = &batch{done: make(chan struct{})}
+			go l.dispatch(c, l.batch)
+		}
+
+		l.batch.ids = append(l.batch.ids, id)
+		l.cache[id] = l.batch
+		batches[i] = l.batch
+	}
+	l.mu.Unlock()
+
+	ret := make([]interface{}, len(ids))
+	for i, b := range batches {
+		<-b.done
+		if b.err != nil {
+			return nil, b.err
+		}
+
+		ret[i] = b.results[ids[i]]
+	}
+
+	return ret, nil
+}
+
+func (l *loader) dispatch(c context.Context, b *batch) {
+	time.Sleep(batchWait)
+
+	l.mu.Lock()
+	l.batch = nil
+	l.mu.Unlock()
+
+	defer close(b.done)
+
+	b.results = make(map[int64]interface{}, len(b.ids))
+
+	// the missing ids are left out of the results, so they load as nil
+	for ids := b.ids; len(ids) > 0; {
+		values, err := l.fetch(c, ids)
+
+		var mre wordpress.MissingResourcesError
+		if errors.As(err, &mre) {
+			ids = without(ids, mre.IDs())
+			continue
+		} else if err != nil {
+			b.err = err
+			return
+		}
+
+		for i, id := range ids {
+			b.results[id] = values[i]
+		}
+
+		return
+	}
+}
+
+// without returns the ids that are not in the excluded ids
+func without(ids []int64, excluded []int64) []int64 {
+	skip := make(map[int64]bool)
+	for _, id := range excluded {
+		skip[id] = true
+	}
+
+	var ret []int64
+	for _, id := range ids {
+		if !skip[id] {
+			ret = append(ret, id)
+		}
+	}
+
+	return ret
+}
+
+// loaders holds the per-request loaders for each resource type
+type loaders struct {
+	posts       *loader
+	categories  *loader
+	tags        *loader
+	users       *loader
+	attachments *loader
+}
+
+// NewContext returns a derived context containing the database connection
+// and the batched loaders used by the resolvers
+//
+// A new context should be created for every request so that loaded
+// resources are not shared between requests.
+func NewContext(parent context.Context, wp *wordpress.WordPress) context.Context {
+	return context.WithValue(wordpress.NewContext(parent, wp), loadersKey, &loaders{
+		posts: newLoader(func(c context.Context, ids []int64) ([]interface{}, error) {
+			posts, err := wordpress.GetPosts(c, ids...)
+			if err != nil {
+				return nil, err
+			}
+
+			ret := make([]interface{}, len(ids))
+			for i, p := range posts {
+				if p != nil {
+					ret[i] = p
+				}
+			}
+
+			return ret, nil
+		}),
+		categories: newLoader(func(c context.Context, ids []int64) ([]interface{}, error) {
+			categories, err := wordpress.GetCategories(c, ids...)
+			if err != nil {
+				return nil, err
+			}
+
+			ret := make([]interface{}, len(ids))
+			for i, cat := range categories {
+				if cat != nil {
+					ret[i] = cat
+				}
+			}
+
+			return ret, nil
+		}),
+		tags: newLoader(func(c context.Context, ids []int64) ([]interface{}, error) {
+			tags, err := wordpress.GetTags(c, ids...)
+			if err != nil {
+				return nil, err
+			}
+
+			ret := make([]interface{}, len(ids))
+			for i, tag := range tags {
+				if tag != nil {
+					ret[i] = tag
+				}
+			}
+
+			return ret, nil
+		}),
+		users: newLoader(func(c context.Context, ids []int64) ([]interface{}, error) {
+			users, err := wordpress.GetUsers(c, ids...)
+			if err != nil {
+				return nil, err
+			}
+
+			ret := make([]interface{}, len(ids))
+			for i, u := range users {
+				if u != nil {
+					ret[i] = u
+				}
+			}
+
+			return ret, nil
+		}),
+		attachments: newLoader(func(c context.Context, ids []int64) ([]interface{}, error) {
+			attachments, err := wordpress.GetAttachments(c, ids...)
+			if err != nil {
+				return nil, err
+			}
+
+			ret := make([]interface{}, len(ids))
+			for i, att := range attachments {
+				if att != nil {
+					ret[i] = att
+				}
+			}
+
+			return ret, nil
+		})})
+}
+
+func loadersFrom(c context.Context) *loaders {
+	l, ok := c.Value(loadersKey).(*loaders)
+	if !ok {
+		panic("non-graphql context")
+	}
+
+	return l
+}
@@ -0,0 +1,53 @@
+package graphql
+
+import (
+	"testing"
+
+	"github.com/ssttevee/go-wordpress"
+	"golang.org/x/net/context"
+)
+
+func TestLoaderMissingId(t *testing.T) {
+	var fetched [][]int64
+	l := newLoader(func(c context.Context, ids []int64) ([]interface{}, error) {
+		fetched = append(fetched, ids)
+
+		ret := make([]interface{}, len(ids))
+		for i, id := range ids {
+			if id == 2 {
+				return nil, wordpress.MissingResourcesError{id}
+			}
+
+			ret[i] = id * 10
+		}
+
+		return ret, nil
+	})
+
+	values, err := l.loadMany(context.Background(), 1, 2)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if values[0] != int64(10) {
+		t.Errorf("expected the present id to load 10, got %v", values[0])
+	}
+
+	if values[1] != nil {
+		t.Errorf("expected the absent id to load nil, got %v", values[1])
+	}
+
+	if len(fetched) != 2 || len(fetched[1]) != 1 || fetched[1][0] != 1 {
+		t.Errorf("expected a retry with only the present id, got %v", fetched)
+	}
+
+	// the results are memoized
+	v, err := l.load(context.Background(), 2)
+	if err != nil || v != nil {
+		t.Errorf("expected nil for the absent id, got %v, %v", v, err)
+	}
+
+	if len(fetched) != 2 {
+		t.Errorf("expected no more fetches, got %v", fetched)
+	}
+}
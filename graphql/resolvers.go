@@ -0,0 +1,660 @@
+package graphql
+
+import (
+	"strconv"
+	"time"
+
+	gql "github.com/graph-gophers/graphql-go"
+	"github.com/ssttevee/go-wordpress"
+	"golang.org/x/net/context"
+)
+
+// maxFirst is the largest number of nodes that a connection may return at once
+const maxFirst = 100
+
+// postOrderColumns are the columns of the values of the `PostOrder` enum
+var postOrderColumns = map[string]string{
+	"DATE":     "post_date",
+	"MODIFIED": "post_modified",
+	"TITLE":    "post_title",
+	"SLUG":     "post_name",
+	"ID":       "ID",
+}
+
+// Resolver is the root resolver of the schema
+type Resolver struct{}
+
+type postsArgs struct {
+	First        *int32
+	After        *string
+	Category     *gql.ID
+	CategoryName *string
+	Tag          *gql.ID
+	TagName      *string
+	Author       *gql.ID
+	AuthorName   *string
+	Search       *string
+	OrderBy      *string
+	Ascending    *bool
+}
+
+// Posts resolves the published posts that match the arguments
+func (r *Resolver) Posts(c context.Context, args postsArgs) (*postConnectionResolver, error) {
+	opts := &wordpress.ObjectQueryOptions{
+		Limit:          first(args.First),
+		After:          derefString(args.After),
+		CategoryName:   derefString(args.CategoryName),
+		TagName:        derefString(args.TagName),
+		AuthorName:     derefString(args.AuthorName),
+		Query:          derefString(args.Search),
+		Order:          postOrderColumns[derefString(args.OrderBy)],
+		OrderAscending: args.Ascending != nil && *args.Ascending}
+
+	var err error
+	if opts.Category, err = parseOptionalID(args.Category); err != nil {
+		return nil, err
+	}
+
+	if opts.TagId, err = parseOptionalID(args.Tag); err != nil {
+		return nil, err
+	}
+
+	if opts.Author, err = parseOptionalID(args.Author); err != nil {
+		return nil, err
+	}
+
+	it, err := wordpress.QueryPosts(c, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	ids, err := it.Slice()
+	if err != nil {
+		return nil, err
+	}
+
+	values, err := loadersFrom(c).posts.loadMany(c, ids...)
+	if err != nil {
+		return nil, err
+	}
+
+	ret := &postConnectionResolver{cursor: it.Cursor()}
+	for _, v := range values {
+		if p, ok := v.(*wordpress.Post); ok {
+			ret.nodes = append(ret.nodes, &postResolver{p})
+		}
+	}
+
+	return ret, nil
+}
+
+// Post resolves a single post
+func (r *Resolver) Post(c context.Context, args struct{ ID gql.ID }) (*postResolver, error) {
+	id, err := parseID(args.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	// only published posts may be resolved
+	it, err := wordpress.QueryPosts(c, &wordpress.ObjectQueryOptions{Post: id, Limit: 1})
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := it.Next(); err == wordpress.Done {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	return loadPost(c, id)
+}
+
+type termsArgs struct {
+	First  *int32
+	After  *string
+	Parent *gql.ID
+	Slug   *string
+}
+
+func (args *termsArgs) options() (*wordpress.TermQueryOptions, error) {
+	opts := &wordpress.TermQueryOptions{
+		Limit: first(args.First),
+		After: derefString(args.After),
+		Slug:  derefString(args.Slug)}
+
+	if args.Parent != nil {
+		parent, err := parseID(*args.Parent)
+		if err != nil {
+			return nil, err
+		}
+
+		opts.ParentId = &parent
+	}
+
+	return opts, nil
+}
+
+// Categories resolves the categories that match the arguments
+func (r *Resolver) Categories(c context.Context, args termsArgs) (*categoryConnectionResolver, error) {
+	opts, err := args.options()
+	if err != nil {
+		return nil, err
+	}
+
+	it, err := wordpress.QueryCategories(c, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	ids, err := it.Slice()
+	if err != nil {
+		return nil, err
+	}
+
+	nodes, err := loadCategories(c, ids...)
+	if err != nil {
+		return nil, err
+	}
+
+	return &categoryConnectionResolver{nodes: nodes, cursor: it.Cursor()}, nil
+}
+
+// Category resolves a single category
+func (r *Resolver) Category(c context.Context, args struct{ ID gql.ID }) (*categoryResolver, error) {
+	id, err := parseID(args.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	return loadCategory(c, id)
+}
+
+// Tags resolves the tags that match the arguments
+func (r *Resolver) Tags(c context.Context, args struct {
+	First *int32
+	After *string
+	Slug  *string
+}) (*tagConnectionResolver, error) {
+	opts, err := (&termsArgs{First: args.First, After: args.After, Slug: args.Slug}).options()
+	if err != nil {
+		return nil, err
+	}
+
+	it, err := wordpress.QueryTags(c, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	ids, err := it.Slice()
+	if err != nil {
+		return nil, err
+	}
+
+	nodes, err := loadTags(c, ids...)
+	if err != nil {
+		return nil, err
+	}
+
+	return &tagConnectionResolver{nodes: nodes, cursor: it.Cursor()}, nil
+}
+
+// Tag resolves a single tag
+func (r *Resolver) Tag(c context.Context, args struct{ ID gql.ID }) (*tagResolver, error) {
+	id, err := parseID(args.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	tags, err := loadTags(c, id)
+	if err != nil || len(tags) == 0 {
+		return nil, err
+	}
+
+	return tags[0], nil
+}
+
+// User resolves a single user
+func (r *Resolver) User(c context.Context, args struct{ ID gql.ID }) (*userResolver, error) {
+	id, err := parseID(args.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	return loadUser(c, id)
+}
+
+// Attachment resolves a single attachment
+func (r *Resolver) Attachment(c context.Context, args struct{ ID gql.ID }) (*attachmentResolver, error) {
+	id, err := parseID(args.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	return loadAttachment(c, id)
+}
+
+// Menu resolves the menu hierarchy of the menu with the given slug
+func (r *Resolver) Menu(c context.Context, args struct{ Name string }) ([]*menuItemResolver, error) {
+	items, err := wordpress.GetMenuItems(c, &wordpress.ObjectQueryOptions{MenuName: args.Name})
+	if err != nil {
+		return nil, err
+	}
+
+	return newMenuItemResolvers(items), nil
+}
+
+type postConnectionResolver struct {
+	nodes  []*postResolver
+	cursor string
+}
+
+func (r *postConnectionResolver) Nodes() []*postResolver {
+	return r.nodes
+}
+
+func (r *postConnectionResolver) Cursor() string {
+	return r.cursor
+}
+
+type categoryConnectionResolver struct {
+	nodes  []*categoryResolver
+	cursor string
+}
+
+func (r *categoryConnectionResolver) Nodes() []*categoryResolver {
+	return r.nodes
+}
+
+func (r *categoryConnectionResolver) Cursor() string {
+	return r.cursor
+}
+
+type tagConnectionResolver struct {
+	nodes  []*tagResolver
+	cursor string
+}
+
+func (r *tagConnectionResolver) Nodes() []*tagResolver {
+	return r.nodes
+}
+
+func (r *tagConnectionResolver) Cursor() string {
+	return r.cursor
+}
+
+type postResolver struct {
+	p *wordpress.Post
+}
+
+func (r *postResolver) ID() gql.ID {
+	return formatID(r.p.Id)
+}
+
+func (r *postResolver) Slug() string {
+	return r.p.Name
+}
+
+func (r *postResolver) Title() string {
+	return r.p.Title
+}
+
+func (r *postResolver) Content() string {
+	if r.p.Password != "" {
+		return ""
+	}
+
+	return r.p.Content
+}
+
+func (r *postResolver) Excerpt() string {
+	if r.p.Password != "" {
+		return ""
+	}
+
+	return r.p.Excerpt
+}
+
+func (r *postResolver) Status() string {
+	return string(r.p.Status)
+}
+
+func (r *postResolver) Type() string {
+	return string(r.p.Type)
+}
+
+func (r *postResolver) Date() string {
+	return r.p.Date.Format(time.RFC3339)
+}
+
+func (r *postResolver) Modified() string {
+	return r.p.Modified.Format(time.RFC3339)
+}
+
+func (r *postResolver) CommentStatus() bool {
+	return r.p.CommentStatus
+}
+
+func (r *postResolver) CommentCount() int32 {
+	return int32(r.p.CommentCount)
+}
+
+func (r *postResolver) Author(c context.Context) (*userResolver, error) {
+	return loadUser(c, r.p.AuthorId)
+}
+
+func (r *postResolver) FeaturedMedia(c context.Context) (*attachmentResolver, error) {
+	if r.p.FeaturedMediaId == 0 {
+		return nil, nil
+	}
+
+	return loadAttachment(c, r.p.FeaturedMediaId)
+}
+
+func (r *postResolver) Categories(c context.Context) ([]*categoryResolver, error) {
+	return loadCategories(c, r.p.CategoryIds...)
+}
+
+func (r *postResolver) Tags(c context.Context) ([]*tagResolver, error) {
+	return loadTags(c, r.p.TagIds...)
+}
+
+func (r *postResolver) Meta(args struct{ Key string }) *string {
+	if value, ok := r.p.Meta[args.Key]; ok {
+		return &value
+	}
+
+	return nil
+}
+
+type categoryResolver struct {
+	cat *wordpress.Category
+}
+
+func (r *categoryResolver) ID() gql.ID {
+	return formatID(r.cat.Id)
+}
+
+func (r *categoryResolver) Name() string {
+	return r.cat.Name
+}
+
+func (r *categoryResolver) Slug() string {
+	return r.cat.Slug
+}
+
+func (r *categoryResolver) Description() string {
+	return r.cat.Description
+}
+
+func (r *categoryResolver) Link() string {
+	return r.cat.Link
+}
+
+func (r *categoryResolver) Count() int32 {
+	return int32(r.cat.Count)
+}
+
+func (r *categoryResolver) Parent(c context.Context) (*categoryResolver, error) {
+	if r.cat.Parent == 0 {
+		return nil, nil
+	}
+
+	return loadCategory(c, r.cat.Parent)
+}
+
+type tagResolver struct {
+	tag *wordpress.Tag
+}
+
+func (r *tagResolver) ID() gql.ID {
+	return formatID(r.tag.Id)
+}
+
+func (r *tagResolver) Name() string {
+	return r.tag.Name
+}
+
+func (r *tagResolver) Slug() string {
+	return r.tag.Slug
+}
+
+func (r *tagResolver) Description() string {
+	return r.tag.Description
+}
+
+func (r *tagResolver) Link() string {
+	return r.tag.Link
+}
+
+func (r *tagResolver) Count() int32 {
+	return int32(r.tag.Count)
+}
+
+type userResolver struct {
+	u *wordpress.User
+}
+
+func (r *userResolver) ID() gql.ID {
+	return formatID(r.u.Id)
+}
+
+func (r *userResolver) Slug() string {
+	return r.u.Slug
+}
+
+func (r *userResolver) Name() string {
+	return r.u.Name
+}
+
+func (r *userResolver) Description() string {
+	return r.u.Description
+}
+
+func (r *userResolver) URL() string {
+	return r.u.Website
+}
+
+func (r *userResolver) Gravatar() string {
+	return r.u.Gravatar
+}
+
+type attachmentResolver struct {
+	att *wordpress.Attachment
+}
+
+func (r *attachmentResolver) ID() gql.ID {
+	return formatID(r.att.Id)
+}
+
+func (r *attachmentResolver) Title() string {
+	return r.att.Title
+}
+
+func (r *attachmentResolver) URL() string {
+	return r.att.Url
+}
+
+func (r *attachmentResolver) FileName() string {
+	return r.att.FileName
+}
+
+func (r *attachmentResolver) MimeType() string {
+	return r.att.MimeType
+}
+
+func (r *attachmentResolver) Width() int32 {
+	return int32(r.att.Width)
+}
+
+func (r *attachmentResolver) Height() int32 {
+	return int32(r.att.Height)
+}
+
+func (r *attachmentResolver) Caption() string {
+	return r.att.Caption
+}
+
+func (r *attachmentResolver) AltText() string {
+	return r.att.AltText
+}
+
+type menuItemResolver struct {
+	mi *wordpress.MenuItem
+}
+
+func newMenuItemResolvers(items []*wordpress.MenuItem) []*menuItemResolver {
+	ret := make([]*menuItemResolver, len(items))
+	for i, mi := range items {
+		ret[i] = &menuItemResolver{mi}
+	}
+
+	return ret
+}
+
+func (r *menuItemResolver) ID() gql.ID {
+	return formatID(r.mi.Id)
+}
+
+func (r *menuItemResolver) Title() string {
+	return r.mi.Title
+}
+
+func (r *menuItemResolver) URL() string {
+	return r.mi.Link
+}
+
+func (r *menuItemResolver) Target() string {
+	return r.mi.Target
+}
+
+func (r *menuItemResolver) Classes() string {
+	return r.mi.Classes
+}
+
+func (r *menuItemResolver) Xfn() string {
+	return r.mi.Xfn
+}
+
+func (r *menuItemResolver) Type() string {
+	return string(r.mi.Type)
+}
+
+func (r *menuItemResolver) Object() string {
+	return r.mi.Object
+}
+
+func (r *menuItemResolver) ObjectID() gql.ID {
+	return formatID(r.mi.ObjectId)
+}
+
+func (r *menuItemResolver) Children() []*menuItemResolver {
+	return newMenuItemResolvers(r.mi.Children)
+}
+
+func loadPost(c context.Context, id int64) (*postResolver, error) {
+	v, err := loadersFrom(c).posts.load(c, id)
+	if p, ok := v.(*wordpress.Post); ok && err == nil {
+		return &postResolver{p}, nil
+	}
+
+	return nil, err
+}
+
+func loadCategory(c context.Context, id int64) (*categoryResolver, error) {
+	categories, err := loadCategories(c, id)
+	if err != nil || len(categories) == 0 {
+		return nil, err
+	}
+
+	return categories[0], nil
+}
+
+func loadCategories(c context.Context, ids ...int64) ([]*categoryResolver, error) {
+	values, err := loadersFrom(c).categories.loadMany(c, ids...)
+	if err != nil {
+		return nil, err
+	}
+
+	ret := make([]*categoryResolver, 0, len(values))
+	for _, v := range values {
+		if cat, ok := v.(*wordpress.Category); ok {
+			ret = append(ret, &categoryResolver{cat})
+		}
+	}
+
+	return ret, nil
+}
+
+func loadTags(c context.Context, ids ...int64) ([]*tagResolver, error) {
+	values, err := loadersFrom(c).tags.loadMany(c, ids...)
+	if err != nil {
+		return nil, err
+	}
+
+	ret := make([]*tagResolver, 0, len(values))
+	for _, v := range values {
+		if tag, ok := v.(*wordpress.Tag); ok {
+			ret = append(ret, &tagResolver{tag})
+		}
+	}
+
+	return ret, nil
+}
+
+func loadUser(c context.Context, id int64) (*userResolver, error) {
+	v, err := loadersFrom(c).users.load(c, id)
+	if u, ok := v.(*wordpress.User); ok && err == nil {
+		return &userResolver{u}, nil
+	}
+
+	return nil, err
+}
+
+func loadAttachment(c context.Context, id int64) (*attachmentResolver, error) {
+	v, err := loadersFrom(c).attachments.load(c, id)
+	if att, ok := v.(*wordpress.Attachment); ok && err == nil {
+		return &attachmentResolver{att}, nil
+	}
+
+	return nil, err
+}
+
+func formatID(id int64) gql.ID {
+	return gql.ID(strconv.FormatInt(id, 10))
+}
+
+func parseID(id gql.ID) (int64, error) {
+	return strconv.ParseInt(string(id), 10, 64)
+}
+
+func parseOptionalID(id *gql.ID) (int64, error) {
+	if id == nil {
+		return 0, nil
+	}
+
+	return parseID(*id)
+}
+
+// first returns the limit of a connection's `first` argument, clamped to [1, maxFirst]
+//
+// Zero is returned if the argument is omitted, which is the default limit.
+func first(i *int32) int {
+	if i == nil {
+		return 0
+	} else if *i < 1 {
+		return 1
+	} else if *i > maxFirst {
+		return maxFirst
+	}
+
+	return int(*i)
+}
+
+func derefString(s *string) string {
+	if s == nil {
+		return ""
+	}
+
+	return *s
+}
@@ -0,0 +1,55 @@
+package graphql
+
+import (
+	"testing"
+
+	"golang.org/x/net/context"
+)
+
+func TestFirst(t *testing.T) {
+	tests := []struct {
+		first *int32
+		want  int
+	}{
+		{nil, 0},
+		{int32Ptr(-1), 1},
+		{int32Ptr(0), 1},
+		{int32Ptr(25), 25},
+		{int32Ptr(100), 100},
+		{int32Ptr(1000000), 100},
+	}
+
+	for _, tt := range tests {
+		if got := first(tt.first); got != tt.want {
+			t.Errorf("first(%v) = %d, expected %d", tt.first, got, tt.want)
+		}
+	}
+}
+
+func TestPostsOrderByEnum(t *testing.T) {
+	schema, err := NewSchema()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the query is rejected by validation, before anything is resolved
+	res := schema.Exec(context.Background(), `{ posts(orderBy: "post_date; DROP TABLE wp_posts") { cursor } }`, "", nil)
+	if len(res.Errors) == 0 {
+		t.Fatal("expected an invalid orderBy to be rejected")
+	}
+
+	res = schema.Exec(context.Background(), `{ posts(orderBy: post_title) { cursor } }`, "", nil)
+	if len(res.Errors) == 0 {
+		t.Fatal("expected a column name to be rejected")
+	}
+
+	for value := range postOrderColumns {
+		if err := schema.Validate(`{ posts(orderBy: ` + value + `) { cursor } }`); len(err) > 0 {
+			t.Errorf("expected %s to be valid, got %v", value, err)
+		}
+	}
+}
+
+func int32Ptr(i int32) *int32 {
+	return &i
+}
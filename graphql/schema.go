@@ -0,0 +1,148 @@
+// Package graphql provides a read-only GraphQL schema over the WordPress types
+//
+// Lookups of related resources (i.e. a post's author, featured media, and terms)
+// are batched per request to avoid issuing a query for every post.
+package graphql
+
+import (
+	"net/http"
+
+	gql "github.com/graph-gophers/graphql-go"
+	"github.com/graph-gophers/graphql-go/relay"
+	"github.com/ssttevee/go-wordpress"
+)
+
+// Schema is the GraphQL schema served by the resolvers
+const Schema = `
+schema {
+	query: Query
+}
+
+type Query {
+	posts(first: Int, after: String, category: ID, categoryName: String, tag: ID, tagName: String, author: ID, authorName: String, search: String, orderBy: PostOrder, ascending: Boolean): PostConnection!
+	post(id: ID!): Post
+	categories(first: Int, after: String, parent: ID, slug: String): CategoryConnection!
+	category(id: ID!): Category
+	tags(first: Int, after: String, slug: String): TagConnection!
+	tag(id: ID!): Tag
+	user(id: ID!): User
+	attachment(id: ID!): Attachment
+	menu(name: String!): [MenuItem!]!
+}
+
+enum PostOrder {
+	DATE
+	MODIFIED
+	TITLE
+	SLUG
+	ID
+}
+
+type PostConnection {
+	nodes: [Post!]!
+	cursor: String!
+}
+
+type CategoryConnection {
+	nodes: [Category!]!
+	cursor: String!
+}
+
+type TagConnection {
+	nodes: [Tag!]!
+	cursor: String!
+}
+
+type Post {
+	id: ID!
+	slug: String!
+	title: String!
+	content: String!
+	excerpt: String!
+	status: String!
+	type: String!
+	date: String!
+	modified: String!
+	commentStatus: Boolean!
+	commentCount: Int!
+	author: User
+	featuredMedia: Attachment
+	categories: [Category!]!
+	tags: [Tag!]!
+	meta(key: String!): String
+}
+
+type Category {
+	id: ID!
+	name: String!
+	slug: String!
+	description: String!
+	link: String!
+	count: Int!
+	parent: Category
+}
+
+type Tag {
+	id: ID!
+	name: String!
+	slug: String!
+	description: String!
+	link: String!
+	count: Int!
+}
+
+type User {
+	id: ID!
+	slug: String!
+	name: String!
+	description: String!
+	url: String!
+	gravatar: String!
+}
+
+type Attachment {
+	id: ID!
+	title: String!
+	url: String!
+	fileName: String!
+	mimeType: String!
+	width: Int!
+	height: Int!
+	caption: String!
+	altText: String!
+}
+
+type MenuItem {
+	id: ID!
+	title: String!
+	url: String!
+	target: String!
+	classes: String!
+	xfn: String!
+	type: String!
+	object: String!
+	objectId: ID!
+	children: [MenuItem!]!
+}
+`
+
+// NewSchema parses the schema and attaches the root resolver
+//
+// Queries must be executed with a context created by `NewContext`.
+func NewSchema(opts ...gql.SchemaOpt) (*gql.Schema, error) {
+	return gql.ParseSchema(Schema, &Resolver{}, opts...)
+}
+
+// NewHandler returns a handler that executes GraphQL queries against the WordPress database
+func NewHandler(wp *wordpress.WordPress, opts ...gql.SchemaOpt) (http.Handler, error) {
+	schema, err := NewSchema(opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	h := &relay.Handler{Schema: schema}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		h.ServeHTTP(w, r.WithContext(NewContext(r.Context(), wp)))
+	}), nil
+}
@@ -0,0 +1,75 @@
+package inmemory
+
+import "reflect"
+
+// deepCopy returns a copy of the value that shares no pointers, slices, or maps with it
+//
+// Unexported struct fields are copied shallowly since they cannot be set through reflection.
+func deepCopy(v reflect.Value) reflect.Value {
+	if !v.IsValid() {
+		return v
+	}
+
+	switch v.Kind() {
+	case reflect.Ptr:
+		if v.IsNil() {
+			return v
+		}
+
+		ret := reflect.New(v.Type().Elem())
+		ret.Elem().Set(deepCopy(v.Elem()))
+
+		return ret
+	case reflect.Interface:
+		if v.IsNil() {
+			return v
+		}
+
+		ret := reflect.New(v.Type()).Elem()
+		ret.Set(deepCopy(v.Elem()))
+
+		return ret
+	case reflect.Slice:
+		if v.IsNil() {
+			return v
+		}
+
+		ret := reflect.MakeSlice(v.Type(), v.Len(), v.Len())
+		for i := 0; i < v.Len(); i++ {
+			ret.Index(i).Set(deepCopy(v.Index(i)))
+		}
+
+		return ret
+	case reflect.Array:
+		ret := reflect.New(v.Type()).Elem()
+		for i := 0; i < v.Len(); i++ {
+			ret.Index(i).Set(deepCopy(v.Index(i)))
+		}
+
+		return ret
+	case reflect.Map:
+		if v.IsNil() {
+			return v
+		}
+
+		ret := reflect.MakeMapWithSize(v.Type(), v.Len())
+		for _, key := range v.MapKeys() {
+			ret.SetMapIndex(deepCopy(key), deepCopy(v.MapIndex(key)))
+		}
+
+		return ret
+	case reflect.Struct:
+		ret := reflect.New(v.Type()).Elem()
+		ret.Set(v)
+
+		for i := 0; i < v.NumField(); i++ {
+			if field := ret.Field(i); field.CanSet() {
+				field.Set(deepCopy(v.Field(i)))
+			}
+		}
+
+		return ret
+	default:
+		return v
+	}
+}
@@ -0,0 +1,204 @@
+// Package inmemory provides an in-process cache for WordPress data
+package inmemory
+
+import (
+	"container/list"
+	"errors"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ssttevee/go-wordpress"
+	"golang.org/x/net/context"
+)
+
+// CacheManager is a least recently used cache that keeps values in memory
+//
+// Values are deep copied when they are set and gotten,
+// so callers cannot mutate the cached values.
+type CacheManager struct {
+	// Expirations overrides the time to live of the keys starting with each prefix
+	// (i.e. `wp_term_` for terms), the longest matching prefix wins
+	//
+	// It must not be modified once the cache is in use.
+	Expirations map[string]time.Duration
+
+	maxEntries int
+	ttl        time.Duration
+
+	mu      sync.Mutex
+	entries map[string]*list.Element
+	order   *list.List
+}
+
+type entry struct {
+	key     string
+	value   reflect.Value
+	expires time.Time
+}
+
+// New creates a cache that holds at most maxEntries values for at most ttl
+//
+// Entries do not expire if ttl is zero, and there is no size limit if maxEntries is zero.
+func New(maxEntries int, ttl time.Duration) *CacheManager {
+	return &CacheManager{
+		maxEntries: maxEntries,
+		ttl:        ttl,
+		entries:    make(map[string]*list.Element),
+		order:      list.New(),
+	}
+}
+
+var _ wordpress.CacheManager = (*CacheManager)(nil)
+
+// Get stores the cached value of the key in dst, which must be a pointer
+func (m *CacheManager) Get(c context.Context, key string, dst interface{}) error {
+	ptr := reflect.ValueOf(dst)
+	if ptr.Kind() != reflect.Ptr || ptr.IsNil() {
+		return errors.New("inmemory: dst must be a non-nil pointer")
+	}
+
+	value, ok := m.get(key)
+	if !ok {
+		return wordpress.ErrCacheMiss
+	}
+
+	return assign(ptr.Elem(), value)
+}
+
+// GetMulti appends the cached values of the keys to dst, which must be a pointer to a slice
+//
+// Returns the keys that were cached, in the same order as they were given
+func (m *CacheManager) GetMulti(c context.Context, keys []string, dst interface{}) ([]string, error) {
+	ptr := reflect.ValueOf(dst)
+	if ptr.Kind() != reflect.Ptr || ptr.IsNil() || ptr.Elem().Kind() != reflect.Slice {
+		return nil, errors.New("inmemory: dst must be a non-nil pointer to a slice")
+	}
+
+	slice := ptr.Elem()
+
+	var hits []string
+	for _, key := range keys {
+		value, ok := m.get(key)
+		if !ok {
+			continue
+		}
+
+		elem := reflect.New(slice.Type().Elem()).Elem()
+		if err := assign(elem, value); err != nil {
+			return nil, err
+		}
+
+		slice = reflect.Append(slice, elem)
+		hits = append(hits, key)
+	}
+
+	ptr.Elem().Set(slice)
+
+	return hits, nil
+}
+
+// Set caches the value for the key
+func (m *CacheManager) Set(c context.Context, key string, value interface{}) error {
+	e := &entry{key: key, value: deepCopy(reflect.ValueOf(value))}
+	if ttl := m.expiration(key); ttl > 0 {
+		e.expires = time.Now().Add(ttl)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if el, ok := m.entries[key]; ok {
+		el.Value = e
+		m.order.MoveToFront(el)
+		return nil
+	}
+
+	m.entries[key] = m.order.PushFront(e)
+
+	for m.maxEntries > 0 && m.order.Len() > m.maxEntries {
+		m.remove(m.order.Back())
+	}
+
+	return nil
+}
+
+// Delete removes the keys from the cache
+func (m *CacheManager) Delete(c context.Context, keys ...string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, key := range keys {
+		if el, ok := m.entries[key]; ok {
+			m.remove(el)
+		}
+	}
+
+	return nil
+}
+
+// expiration returns the time to live of the key
+func (m *CacheManager) expiration(key string) time.Duration {
+	ttl := m.ttl
+
+	var matched string
+	for prefix, expiration := range m.Expirations {
+		if strings.HasPrefix(key, prefix) && len(prefix) > len(matched) {
+			matched = prefix
+			ttl = expiration
+		}
+	}
+
+	return ttl
+}
+
+// get returns the value of the key, marking it as the most recently used
+func (m *CacheManager) get(key string) (reflect.Value, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	el, ok := m.entries[key]
+	if !ok {
+		return reflect.Value{}, false
+	}
+
+	e := el.Value.(*entry)
+	if !e.expires.IsZero() && time.Now().After(e.expires) {
+		m.remove(el)
+		return reflect.Value{}, false
+	}
+
+	m.order.MoveToFront(el)
+
+	return e.value, true
+}
+
+func (m *CacheManager) remove(el *list.Element) {
+	m.order.Remove(el)
+	delete(m.entries, el.Value.(*entry).key)
+}
+
+// assign stores a copy of the value in dst, dereferencing the value if it is a pointer to dst's type
+func assign(dst, value reflect.Value) error {
+	if !value.IsValid() {
+		dst.Set(reflect.Zero(dst.Type()))
+		return nil
+	}
+
+	if !value.Type().AssignableTo(dst.Type()) && value.Kind() == reflect.Ptr && value.Type().Elem().AssignableTo(dst.Type()) {
+		if value.IsNil() {
+			return errors.New("inmemory: cannot assign a nil pointer to a " + dst.Type().String())
+		}
+
+		value = value.Elem()
+	}
+
+	if !value.Type().AssignableTo(dst.Type()) {
+		return errors.New("inmemory: cannot assign a " + value.Type().String() + " to a " + dst.Type().String())
+	}
+
+	dst.Set(deepCopy(value))
+
+	return nil
+}
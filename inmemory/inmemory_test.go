@@ -0,0 +1,186 @@
+package inmemory
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ssttevee/go-wordpress"
+	"golang.org/x/net/context"
+)
+
+func TestCacheManagerConcurrent(t *testing.T) {
+	c := context.Background()
+
+	// small enough for the goroutines to evict each other's entries
+	m := New(16, 0)
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 8)
+	for i := 0; i < cap(errs); i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+
+			for j := 0; j < 200; j++ {
+				key := "wp_object_" + strconv.Itoa(j%32)
+				p := &wordpress.Post{Object: wordpress.Object{Id: int64(j % 32)}, CategoryIds: []int64{int64(i)}}
+
+				if err := m.Set(c, key, p); err != nil {
+					errs <- err
+					return
+				}
+
+				// the cached copy is not shared with the caller
+				p.CategoryIds[0] = -1
+
+				var got wordpress.Post
+				if err := m.Get(c, key, &got); err != nil && err != wordpress.ErrCacheMiss {
+					errs <- err
+					return
+				} else if err == nil && (got.Id != int64(j%32) || got.CategoryIds[0] < 0) {
+					errs <- fmt.Errorf("expected an unmodified copy of post %d, got %d %v", j%32, got.Id, got.CategoryIds)
+					return
+				}
+
+				var multi []*wordpress.Post
+				if _, err := m.GetMulti(c, []string{key, "wp_object_0"}, &multi); err != nil {
+					errs <- err
+					return
+				}
+
+				if j%10 == 0 {
+					if err := m.Delete(c, key); err != nil {
+						errs <- err
+						return
+					}
+				}
+			}
+		}(i)
+	}
+
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		t.Error(err)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if len(m.entries) > 16 || m.order.Len() != len(m.entries) {
+		t.Errorf("expected at most 16 consistent entries, got %d in the map and %d in the list", len(m.entries), m.order.Len())
+	}
+}
+
+func TestCacheManagerGetMultiOrder(t *testing.T) {
+	c := context.Background()
+	m := New(0, 0)
+
+	for _, id := range []int64{3, 1, 2} {
+		if err := m.Set(c, "wp_term_"+strconv.FormatInt(id, 10), &wordpress.Term{Id: id}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	keys := []string{"wp_term_2", "wp_term_4", "wp_term_3", "wp_term_1"}
+
+	var terms []*wordpress.Term
+	hits, err := m.GetMulti(c, keys, &terms)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if want := []string{"wp_term_2", "wp_term_3", "wp_term_1"}; !reflect.DeepEqual(hits, want) {
+		t.Errorf("expected the hits %v, got %v", want, hits)
+	}
+
+	var ids []int64
+	for _, term := range terms {
+		ids = append(ids, term.Id)
+	}
+
+	if want := []int64{2, 3, 1}; !reflect.DeepEqual(ids, want) {
+		t.Errorf("expected the values in the order of the keys %v, got %v", want, ids)
+	}
+}
+
+func TestCacheManagerEviction(t *testing.T) {
+	c := context.Background()
+	m := New(2, 0)
+
+	for _, key := range []string{"a", "b"} {
+		if err := m.Set(c, key, key); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	// getting a makes b the least recently used
+	var value string
+	if err := m.Get(c, "a", &value); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if err := m.Set(c, "c", "c"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if err := m.Get(c, "b", &value); err != wordpress.ErrCacheMiss {
+		t.Errorf("expected b to be evicted, got %q, %v", value, err)
+	}
+
+	for _, key := range []string{"a", "c"} {
+		if err := m.Get(c, key, &value); err != nil || value != key {
+			t.Errorf("expected %q to be cached, got %q, %v", key, value, err)
+		}
+	}
+}
+
+func TestCacheManagerExpirations(t *testing.T) {
+	c := context.Background()
+
+	m := New(0, time.Hour)
+	m.Expirations = map[string]time.Duration{
+		"wp_object_":  time.Millisecond,
+		"wp_object_1": time.Hour,
+		"wp_user_":    0,
+	}
+
+	tests := []struct {
+		key  string
+		want time.Duration
+	}{
+		{key: "wp_term_1", want: time.Hour},
+		{key: "wp_object_2", want: time.Millisecond},
+		{key: "wp_object_1", want: time.Hour},
+		{key: "wp_user_1", want: 0},
+	}
+
+	for _, tt := range tests {
+		if ttl := m.expiration(tt.key); ttl != tt.want {
+			t.Errorf("expected %s to live for %v, got %v", tt.key, tt.want, ttl)
+		}
+
+		if err := m.Set(c, tt.key, tt.key); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	time.Sleep(10 * time.Millisecond)
+
+	for _, tt := range tests {
+		var value string
+		err := m.Get(c, tt.key, &value)
+		if tt.want == time.Millisecond {
+			if err != wordpress.ErrCacheMiss {
+				t.Errorf("expected %s to expire, got %q, %v", tt.key, value, err)
+			}
+		} else if err != nil || value != tt.key {
+			t.Errorf("expected %s to be cached, got %q, %v", tt.key, value, err)
+		}
+	}
+}
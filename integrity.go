@@ -0,0 +1,89 @@
+package wordpress
+
+import (
+	"github.com/elgris/sqrl"
+	"golang.org/x/net/context"
+)
+
+// IntegrityReport represents the number of inconsistent rows found in the database
+type IntegrityReport struct {
+	// Postmeta rows whose post does not exist
+	OrphanedPostMeta int `json:"orphaned_postmeta"`
+
+	// Term relationships whose object does not exist
+	OrphanedRelationships int `json:"orphaned_relationships"`
+
+	// Term relationships whose term taxonomy does not exist
+	DanglingRelationships int `json:"dangling_relationships"`
+
+	// Posts whose author does not exist
+	InvalidAuthors int `json:"invalid_authors"`
+}
+
+// OK returns whether no inconsistencies were found
+func (r *IntegrityReport) OK() bool {
+	return *r == IntegrityReport{}
+}
+
+// CheckIntegrity counts the rows left inconsistent by deleted posts, terms, and users
+//
+// This only reads from the database, nothing is repaired.
+func CheckIntegrity(c context.Context) (*IntegrityReport, error) {
+	c, span := startSpan(c, "/wordpress.CheckIntegrity")
+	defer span.End()
+
+	var report IntegrityReport
+
+	checks := []struct {
+		count *int
+		query *sqrl.SelectBuilder
+	}{
+		{
+			count: &report.OrphanedPostMeta,
+			query: sqrl.Select("COUNT(*)").
+				From(table(c, "postmeta") + " AS pm").
+				LeftJoin(table(c, "posts") + " AS p ON p.ID = pm.post_id").
+				Where("p.ID IS NULL"),
+		},
+		{
+			// link categories relate to the links table instead of posts
+			count: &report.OrphanedRelationships,
+			query: sqrl.Select("COUNT(*)").
+				From(table(c, "term_relationships") + " AS tr").
+				Join(table(c, "term_taxonomy") + " AS tt ON tt.term_taxonomy_id = tr.term_taxonomy_id").
+				LeftJoin(table(c, "posts") + " AS p ON p.ID = tr.object_id").
+				Where("p.ID IS NULL").
+				Where(sqrl.NotEq{"tt.taxonomy": "link_category"}),
+		},
+		{
+			count: &report.DanglingRelationships,
+			query: sqrl.Select("COUNT(*)").
+				From(table(c, "term_relationships") + " AS tr").
+				LeftJoin(table(c, "term_taxonomy") + " AS tt ON tt.term_taxonomy_id = tr.term_taxonomy_id").
+				Where("tt.term_taxonomy_id IS NULL"),
+		},
+		{
+			count: &report.InvalidAuthors,
+			query: sqrl.Select("COUNT(*)").
+				From(table(c, "posts") + " AS p").
+				LeftJoin(table(c, "users") + " AS u ON u.ID = p.post_author").
+				Where("u.ID IS NULL").
+				Where(sqrl.NotEq{"p.post_author": 0}),
+		},
+	}
+
+	for _, check := range checks {
+		stmt, args, err := check.query.ToSql()
+		if err != nil {
+			return nil, err
+		}
+
+		spanString(span, "wp/integrity/query", stmt)
+
+		if err := database(c).QueryRowContext(c, stmt, args...).Scan(check.count); err != nil {
+			return nil, err
+		}
+	}
+
+	return &report, nil
+}
@@ -7,7 +7,17 @@ import (
 type Iterator interface {
 	Next() (int64, error)
 	Cursor() string
+
+	// PrevCursor returns the cursor of the first row in the result page,
+	// to be passed as `Before` to get the page before it
+	//
+	// It is empty if the page is empty or the query cannot be paginated backward.
+	PrevCursor() string
+
 	Slice() ([]int64, error)
+
+	// Len returns the number of rows in the result page, including those already read
+	Len() int
 }
 
 var (
@@ -20,6 +30,9 @@ var (
 type iteratorImpl struct {
 	next   func() (int64, error)
 	cursor string
+	length int
+
+	prevCursor string
 }
 
 func (it *iteratorImpl) Next() (int64, error) {
@@ -30,6 +43,14 @@ func (it *iteratorImpl) Cursor() string {
 	return it.cursor
 }
 
+func (it *iteratorImpl) PrevCursor() string {
+	return it.prevCursor
+}
+
+func (it *iteratorImpl) Len() int {
+	return it.length
+}
+
 func (it *iteratorImpl) Slice() (ret []int64, err error) {
 	for {
 		var id int64
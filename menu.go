@@ -1,13 +1,13 @@
 package wordpress
 
 import (
-	"go.opencensus.io/trace"
-	"fmt"
+	"errors"
 	"github.com/elgris/sqrl"
 	"github.com/wulijun/go-php-serialize/phpserialize"
 	"golang.org/x/net/context"
 	"sort"
 	"strconv"
+	"strings"
 )
 
 // MenuItem represents a WordPress menu item
@@ -43,7 +43,7 @@ type MenuLocation struct {
 
 // GetMenus gets the available menus from the database
 func GetMenus(c context.Context) ([]*MenuLocation, error) {
-	c, span := trace.StartSpan(c, "/wordpress.GetMenus")
+	c, span := startSpan(c, "/wordpress.GetMenus")
 	defer span.End()
 
 	stmt, args, err := sqrl.Select("t.term_id", "t.name", "t.slug").
@@ -54,13 +54,15 @@ func GetMenus(c context.Context) ([]*MenuLocation, error) {
 		return nil, err
 	}
 
-	span.AddAttributes(trace.StringAttribute("wp/menu/query", stmt))
+	spanString(span, "wp/menu/query", stmt)
 
-	rows, err := database(c).Query(stmt, args...)
+	rows, err := database(c).QueryContext(c, stmt, args...)
 	if err != nil {
 		return nil, err
 	}
 
+	defer rows.Close()
+
 	var ret []*MenuLocation
 	for rows.Next() {
 		var ml MenuLocation
@@ -72,19 +74,82 @@ func GetMenus(c context.Context) ([]*MenuLocation, error) {
 		ret = append(ret, &ml)
 	}
 
-	span.AddAttributes(trace.Int64Attribute("wp/menu/count", int64(len(ret))))
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	spanInt64(span, "wp/menu/count", int64(len(ret)))
 
 	return ret, nil
 }
 
+// MaxMenuItems is the maximum number of items loaded by `GetMenuItems`
+// when the query does not set its own limit
+var MaxMenuItems = 1000
+
+// ErrTooManyMenuItems is returned when a menu has more items than allowed
+var ErrTooManyMenuItems = errors.New("wordpress: too many menu items")
+
+// QueryMenus returns the ids of the menus that match the query
+func QueryMenus(c context.Context, opts *TermQueryOptions) (Iterator, error) {
+	c, span := startSpan(c, "/wordpress.QueryMenus")
+	defer span.End()
+
+	opts.Taxonomy = TaxonomyNavMenu
+
+	return queryTerms(c, opts)
+}
+
+// GetMenuByLocation gets the entire hierarchy of the menu assigned to the theme location
+//
+// The locations are read from the modifications of the active theme.
+// Returns `ErrNotFound` if no menu is assigned to the location
+func GetMenuByLocation(c context.Context, location string) ([]*MenuItem, error) {
+	c, span := startSpan(c, "/wordpress.GetMenuByLocation")
+	defer span.End()
+
+	spanString(span, "wp/menu/location", location)
+
+	mods, err := getThemeMods(c)
+	if err != nil {
+		return nil, err
+	}
+
+	locations, _ := mods["nav_menu_locations"].(map[interface{}]interface{})
+
+	var menuId int64
+	switch v := locations[location].(type) {
+	case int64:
+		menuId = v
+	case string:
+		menuId, _ = strconv.ParseInt(v, 10, 64)
+	}
+
+	if menuId <= 0 {
+		return nil, ErrNotFound
+	}
+
+	return GetMenuItems(c, &ObjectQueryOptions{MenuId: &menuId})
+}
+
 // GetMenuItems gets the entire menu hierarchy
 //
-// It is also the most expensive operation in this package... use sparingly...
+// The linked posts, pages, and categories are resolved in batches,
+// so only a handful of queries are made regardless of the size of the menu.
+// Menus larger than `opts.Limit`, or `MaxMenuItems` if unset, return `ErrTooManyMenuItems`.
 func GetMenuItems(c context.Context, opts *ObjectQueryOptions) ([]*MenuItem, error) {
-	c, span := trace.StartSpan(c, "/wordpress.GetMenu")
+	c, span := startSpan(c, "/wordpress.GetMenu")
 	defer span.End()
 
-	opts.Limit = -1
+	opts = opts.clone()
+
+	limit := opts.Limit
+	if limit <= 0 {
+		limit = MaxMenuItems
+	}
+
+	// fetch one more than the limit to detect oversized menus
+	opts.Limit = limit + 1
 	opts.PostType = PostTypeNavMenuItem
 
 	it, err := queryObjects(c, opts)
@@ -99,45 +164,16 @@ func GetMenuItems(c context.Context, opts *ObjectQueryOptions) ([]*MenuItem, err
 
 	if len(objectIds) == 0 {
 		return nil, nil
+	} else if len(objectIds) > limit {
+		return nil, ErrTooManyMenuItems
 	}
 
-	stmt, args, err := sqrl.Select("post_id", "meta_key", "meta_value").
-		From(table(c, "postmeta")).
-		Where(sqrl.Eq{"post_id": objectIds}).ToSql()
-	if err != nil {
-		return nil, err
-	}
-
-	rows, err := database(c).Query(stmt, args...)
+	metaMap, err := GetObjectsMeta(c, objectIds)
 	if err != nil {
 		return nil, err
 	}
 
-	var n int
-
-	metaMap := make(map[int64]map[string]string)
-	for rows.Next() {
-		var id int64
-		var key string
-		var value string
-
-		if err := rows.Scan(&id, &key, &value); err != nil {
-			return nil, err
-		}
-
-		if _, ok := metaMap[id]; !ok {
-			metaMap[id] = make(map[string]string)
-		}
-
-		metaMap[id][key] = value
-
-		n++
-	}
-
-	span.AddAttributes(trace.Int64Attribute("wp/menu/items", int64(n)))
-
-	count := 0
-	done := make(chan error)
+	spanInt64(span, "wp/menu/items", int64(len(objectIds)))
 
 	objects, err := getObjects(c, objectIds...)
 	if err != nil {
@@ -177,7 +213,7 @@ func GetMenuItems(c context.Context, opts *ObjectQueryOptions) ([]*MenuItem, err
 						}
 					}
 
-					mi.Classes = mi.Classes[:len(mi.Classes)-1]
+					mi.Classes = strings.TrimSuffix(mi.Classes, " ")
 				}
 			}
 		}
@@ -200,99 +236,137 @@ func GetMenuItems(c context.Context, opts *ObjectQueryOptions) ([]*MenuItem, err
 			}
 		}
 
-		if mi.Type != MenuItemTypeCustom {
-			count++
+		menuItems[mi.Id] = mi
+	}
+
+	if err := resolveMenuItems(c, menuItems); err != nil {
+		return nil, err
+	}
 
-			go func() {
-				if mi.Type == MenuItemTypeTaxonomy {
-					if mi.Object == "category" {
-						cats, err := GetCategories(c, mi.ObjectId)
-						if err != nil {
-							done <- err
-							return
-						}
+	var ret []*MenuItem
+	for _, mi := range menuItems {
+		parent, ok := menuItems[mi.ParentId]
+		if mi.ParentId == 0 || !ok {
+			// items whose parent was deleted are shown at the top level
+			ret = append(ret, mi)
+			continue
+		}
 
-						mi.Title = cats[0].Name
-						mi.Link = cats[0].Link
-					}
+		parent.Children = append(parent.Children, mi)
+	}
 
-					done <- nil
-				} else if mi.Type == MenuItemTypePost {
-					if mi.Object == "page" {
-						var url string
-						pageId := mi.ObjectId
-						for pageId != 0 {
-							row := sqrl.Select("post_title", "post_name", "post_parent").
-								From(table(c, "posts")).
-								Where(sqrl.Eq{"ID": pageId}).
-								RunWith(database(c)).QueryRow()
-
-							var title, slug string
-							var parent int64
-							if err := row.Scan(&title, &slug, &parent); err != nil {
-								done <- fmt.Errorf("wordpress: %v", err)
-							} else {
-								if mi.Title == "" {
-									mi.Title = title
-								}
-
-								url = "/" + slug + url
-							}
-
-							pageId = parent
-						}
+	sortMenuItems(ret)
 
-						mi.Link = url
+	return afterGetMenuItemsFilters.apply(c, ret)
+}
 
-						done <- nil
-					} else {
-						row := sqrl.Select("SELECT YEAR(post_date)", "MONTH(post_date)", "post_title", "post_name").
-							From(table(c, "posts")).
-							Where(sqrl.Eq{"ID": mi.ObjectId}).
-							RunWith(database(c)).QueryRow()
+// resolveMenuItems fills in the titles and links of the menu items
+// that point to posts, pages, and categories
+//
+// It runs once all of the menu items are built and batches the lookups
+// by type, so no menu item is ever written to concurrently.
+func resolveMenuItems(c context.Context, menuItems map[int64]*MenuItem) error {
+	var categoryIds, postIds []int64
+	for _, mi := range menuItems {
+		switch {
+		case mi.Type == MenuItemTypeTaxonomy && mi.Object == string(TaxonomyCategory):
+			categoryIds = append(categoryIds, mi.ObjectId)
+		case mi.Type == MenuItemTypePost:
+			postIds = append(postIds, mi.ObjectId)
+		}
+	}
 
-						var year, month int
-						var title, slug string
-						if err := row.Scan(&year, &month, &title, &slug); err != nil {
-							done <- fmt.Errorf("Unable to get post url - %v; %v", err, mi)
-						} else {
-							mi.Title = title
-							mi.Link = fmt.Sprintf("/%d/%d/%s", year, month, slug)
+	if len(categoryIds) > 0 {
+		// menu items may point to deleted categories
+		categories, err := GetCategories(c, categoryIds...)
+		if mre, ok := err.(MissingResourcesError); ok {
+			categories, err = GetCategories(c, without(categoryIds, mre)...)
+		}
+		if err != nil {
+			return err
+		}
 
-							done <- nil
-						}
-					}
-				} else {
-					done <- nil
-				}
-			}()
+		byId := make(map[int64]*Category)
+		for _, cat := range categories {
+			if cat != nil {
+				byId[cat.Id] = cat
+			}
 		}
 
-		menuItems[mi.Id] = mi
+		for _, mi := range menuItems {
+			if cat, ok := byId[mi.ObjectId]; ok && mi.Type == MenuItemTypeTaxonomy && mi.Object == string(TaxonomyCategory) {
+				mi.Title = cat.Name
+				mi.Link = cat.Link
+			}
+		}
 	}
 
-	var ret []*MenuItem
-	for _, mi := range menuItems {
-		if mi.ParentId == 0 {
-			ret = append(ret, mi)
-			continue
+	if len(postIds) > 0 {
+		posts, err := getObjectsWithAncestors(c, postIds)
+		if err != nil {
+			return err
 		}
 
-		if menuItems[mi.ParentId].Children == nil {
-			menuItems[mi.ParentId].Children = make([]*MenuItem, 0, 1)
+		links, err := newPermalinkResolver(c)
+		if err != nil {
+			return err
 		}
 
-		menuItems[mi.ParentId].Children = append(menuItems[mi.ParentId].Children, mi)
+		for _, mi := range menuItems {
+			obj, ok := posts[mi.ObjectId]
+			if !ok || mi.Type != MenuItemTypePost {
+				continue
+			}
+
+			if mi.Title == "" {
+				mi.Title = obj.Title
+			}
+
+			if obj.Type == PostTypePage {
+				// pages are linked by the slugs of all their ancestors
+				url := ""
+				seen := make(map[int64]bool)
+				for page := obj; page != nil && !seen[page.Id]; page = posts[int64(page.ParentId)] {
+					seen[page.Id] = true
+					url = "/" + page.Name + url
+				}
+
+				mi.Link = url
+			} else if mi.Link, err = links.link(c, obj, nil); err != nil {
+				return err
+			}
+		}
 	}
 
-	sortMenuItems(ret)
+	return nil
+}
 
-	for count > 0 {
-		if err := <-done; err != nil {
+// getObjectsWithAncestors gets the objects along with all of their ancestors,
+// making one query per level of the hierarchy
+func getObjectsWithAncestors(c context.Context, ids []int64) (map[int64]*Object, error) {
+	ret := make(map[int64]*Object)
+	for len(ids) > 0 {
+		// menu items and pages may point to deleted objects
+		objects, err := getExistingObjects(c, ids...)
+		if err != nil {
 			return nil, err
 		}
 
-		count--
+		ids = nil
+		for _, obj := range objects {
+			if obj == nil {
+				continue
+			}
+
+			ret[obj.Id] = obj
+
+			parentId := int64(obj.ParentId)
+			if _, ok := ret[parentId]; parentId != 0 && !ok {
+				ids = append(ids, parentId)
+			}
+		}
+
+		ids, _ = dedupe(ids)
 	}
 
 	return ret, nil
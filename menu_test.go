@@ -0,0 +1,41 @@
+package wordpress
+
+import (
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestGetMenuItemsDatedPost(t *testing.T) {
+	c := newTestContext(t)
+	testOption(t, c, "permalink_structure", "/%year%/%monthnum%/%postname%/")
+
+	postId := testPost(t, c, "Hello World", time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC))
+
+	menuId := testTerm(t, c, TaxonomyNavMenu, "Main", 0)
+	itemId := testObject(t, c, map[string]interface{}{"post_type": "nav_menu_item", "menu_order": 1})
+	testMeta(t, c, itemId, map[string]string{
+		"_menu_item_type":             "post_type",
+		"_menu_item_object":           "post",
+		"_menu_item_object_id":        strconv.FormatInt(postId, 10),
+		"_menu_item_menu_item_parent": "0",
+	})
+	testRelate(t, c, itemId, menuId)
+
+	items, err := GetMenuItems(c, &ObjectQueryOptions{MenuId: &menuId})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(items) != 1 {
+		t.Fatalf("expected 1 menu item, got %d", len(items))
+	}
+
+	if items[0].Title != "Hello World" {
+		t.Errorf("expected the post's title, got %q", items[0].Title)
+	}
+
+	if want := "/2020/01/hello-world/"; items[0].Link != want {
+		t.Errorf("expected the link %s, got %s", want, items[0].Link)
+	}
+}
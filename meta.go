@@ -0,0 +1,180 @@
+package wordpress
+
+import (
+	"database/sql"
+	"sort"
+
+	"github.com/elgris/sqrl"
+	"golang.org/x/net/context"
+)
+
+// SetMetaOptions represents the available parameters for setting metadata
+type SetMetaOptions struct {
+	// Delete the keys given an empty value instead of storing the empty value
+	DeleteEmpty bool
+}
+
+// SetMeta inserts or updates the given metadata
+//
+// WordPress does not put a unique key on the post id and meta key pair,
+// so this cannot be a plain `INSERT ... ON DUPLICATE KEY UPDATE`;
+// existing keys are looked up and updated in the same transaction instead.
+//
+// Multi-valued metadata (the same key repeated on an object) is not supported,
+// every row with a given key is set to the same value.
+func (obj *Object) SetMeta(c context.Context, kv map[string]string) error {
+	return obj.SetMetaMulti(c, kv)
+}
+
+// DeleteMeta deletes the object's metadata with the given keys
+//
+// Deletes all metadata if no metadata keys are given
+func (obj *Object) DeleteMeta(c context.Context, keys ...string) error {
+	c, span := startSpan(c, "/wordpress.Object.DeleteMeta")
+	defer span.End()
+
+	q := sqrl.Delete(table(c, "postmeta")).
+		Where(sqrl.Eq{"post_id": obj.Id})
+
+	if len(keys) > 0 {
+		q = q.Where(sqrl.Eq{"meta_key": keys})
+	}
+
+	stmt, args, err := q.ToSql()
+	if err != nil {
+		return err
+	}
+
+	spanString(span, "wp/meta/query", stmt)
+
+	_, err = database(c).ExecContext(c, stmt, args...)
+
+	return err
+}
+
+// SetMetaMulti inserts or updates all of the given metadata in a single transaction
+func (obj *Object) SetMetaMulti(c context.Context, meta map[string]string) error {
+	return obj.SetMetaMultiOpts(c, meta, nil)
+}
+
+// SetMetaMultiOpts inserts or updates all of the given metadata in a single transaction
+//
+// At most one statement is executed for each of the inserts, updates, and deletes.
+func (obj *Object) SetMetaMultiOpts(c context.Context, meta map[string]string, opts *SetMetaOptions) error {
+	c, span := startSpan(c, "/wordpress.Object.SetMetaMulti")
+	defer span.End()
+
+	if len(meta) == 0 {
+		return nil
+	}
+
+	if opts == nil {
+		opts = &SetMetaOptions{}
+	}
+
+	keys := make([]string, 0, len(meta))
+	for key := range meta {
+		keys = append(keys, key)
+	}
+
+	// sorted for stable statements and lock ordering
+	sort.Strings(keys)
+
+	tx, err := database(c).BeginTx(c, nil)
+	if err != nil {
+		return err
+	}
+
+	if err := setMetaMulti(c, tx, obj.Id, keys, meta, opts); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func setMetaMulti(c context.Context, tx *sql.Tx, objectId int64, keys []string, meta map[string]string, opts *SetMetaOptions) error {
+	span := spanFromContext(c)
+
+	stmt, args, err := sqrl.Select("DISTINCT meta_key").
+		From(table(c, "postmeta")).
+		Where(sqrl.Eq{"post_id": objectId, "meta_key": keys}).ToSql()
+	if err != nil {
+		return err
+	}
+
+	spanString(span, "wp/meta/query", stmt)
+
+	rows, err := tx.QueryContext(c, stmt, args...)
+	if err != nil {
+		return err
+	}
+
+	existing := make(map[string]bool)
+	for rows.Next() {
+		var key string
+		if err := rows.Scan(&key); err != nil {
+			rows.Close()
+			return err
+		}
+
+		existing[key] = true
+	}
+
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	var deletes []string
+	updates := sqrl.Case("meta_key")
+	var updateKeys []string
+	inserts := sqrl.Insert(table(c, "postmeta")).Columns("post_id", "meta_key", "meta_value")
+	var insertCount int
+
+	for _, key := range keys {
+		value := meta[key]
+		if value == "" && opts.DeleteEmpty {
+			if existing[key] {
+				deletes = append(deletes, key)
+			}
+		} else if existing[key] {
+			updates = updates.When(sqrl.Expr("?", key), sqrl.Expr("?", value))
+			updateKeys = append(updateKeys, key)
+		} else {
+			inserts = inserts.Values(objectId, key, value)
+			insertCount++
+		}
+	}
+
+	var queries []sqrl.Sqlizer
+	if len(deletes) > 0 {
+		queries = append(queries, sqrl.Delete(table(c, "postmeta")).
+			Where(sqrl.Eq{"post_id": objectId, "meta_key": deletes}))
+	}
+
+	if len(updateKeys) > 0 {
+		queries = append(queries, sqrl.Update(table(c, "postmeta")).
+			Set("meta_value", updates).
+			Where(sqrl.Eq{"post_id": objectId, "meta_key": updateKeys}))
+	}
+
+	if insertCount > 0 {
+		queries = append(queries, inserts)
+	}
+
+	for _, q := range queries {
+		stmt, args, err := q.ToSql()
+		if err != nil {
+			return err
+		}
+
+		spanString(span, "wp/meta/query", stmt)
+
+		if _, err := tx.ExecContext(c, stmt, args...); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
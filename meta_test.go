@@ -0,0 +1,75 @@
+package wordpress
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSetAndDeleteMeta(t *testing.T) {
+	c := newTestContext(t)
+
+	obj := &Object{Id: testPost(t, c, "Hello World", testDate)}
+	other := &Object{Id: testPost(t, c, "Other", testDate)}
+	testMeta(t, c, other.Id, map[string]string{"color": "blue"})
+
+	getMeta := func(obj *Object) map[string]string {
+		t.Helper()
+
+		meta, err := obj.GetMeta(c)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		return meta
+	}
+
+	if err := obj.SetMeta(c, map[string]string{"color": "red", "size": "large"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if want := map[string]string{"color": "red", "size": "large"}; !reflect.DeepEqual(getMeta(obj), want) {
+		t.Errorf("expected %v, got %v", want, getMeta(obj))
+	}
+
+	// existing keys are updated rather than duplicated
+	if err := obj.SetMeta(c, map[string]string{"color": "green", "shape": "round"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if want := map[string]string{"color": "green", "size": "large", "shape": "round"}; !reflect.DeepEqual(getMeta(obj), want) {
+		t.Errorf("expected %v, got %v", want, getMeta(obj))
+	}
+
+	var rows int
+	if err := database(c).QueryRowContext(c, "SELECT COUNT(*) FROM wp_postmeta WHERE post_id = ?", obj.Id).Scan(&rows); err != nil || rows != 3 {
+		t.Errorf("expected 3 rows, got %d, %v", rows, err)
+	}
+
+	if err := obj.SetMetaMultiOpts(c, map[string]string{"shape": "", "size": "small"}, &SetMetaOptions{DeleteEmpty: true}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if want := map[string]string{"color": "green", "size": "small"}; !reflect.DeepEqual(getMeta(obj), want) {
+		t.Errorf("expected the empty value to be deleted, got %v", getMeta(obj))
+	}
+
+	if err := obj.DeleteMeta(c, "size"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if want := map[string]string{"color": "green"}; !reflect.DeepEqual(getMeta(obj), want) {
+		t.Errorf("expected %v, got %v", want, getMeta(obj))
+	}
+
+	if err := obj.DeleteMeta(c); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if meta := getMeta(obj); len(meta) != 0 {
+		t.Errorf("expected all metadata to be deleted, got %v", meta)
+	}
+
+	if want := map[string]string{"color": "blue"}; !reflect.DeepEqual(getMeta(other), want) {
+		t.Errorf("expected the other object's metadata to be untouched, got %v", getMeta(other))
+	}
+}
@@ -1,12 +1,13 @@
 package wordpress
 
 import (
-	"go.opencensus.io/trace"
-	"encoding/base64"
+	"errors"
 	"fmt"
 	"github.com/elgris/sqrl"
 	"golang.org/x/net/context"
 	"regexp"
+	"sort"
+	"strconv"
 	"strings"
 	"time"
 )
@@ -80,7 +81,7 @@ type Object struct {
 	MenuOrder int `json:"-"`
 
 	// The post's type. (i.e. post or page)
-	Type string `json:"type"`
+	Type PostType `json:"type"`
 
 	// An attachment's mime type.
 	MimeType string `json:"mime_type,omitempty"`
@@ -89,6 +90,40 @@ type Object struct {
 	CommentCount int `json:"-"`
 }
 
+// OrderClause represents a column to order query results by
+type OrderClause struct {
+	Column    string
+	Ascending bool
+}
+
+func (clause OrderClause) sql() string {
+	if clause.Ascending {
+		return clause.Column + " ASC"
+	}
+
+	return clause.Column + " DESC"
+}
+
+// sortableObjectColumns are the `posts` columns that objects may be ordered by
+//
+// Order columns are embedded in the statements, so nothing else is allowed.
+var sortableObjectColumns = map[string]bool{
+	"ID":                true,
+	"post_author":       true,
+	"post_date":         true,
+	"post_date_gmt":     true,
+	"post_title":        true,
+	"post_status":       true,
+	"post_name":         true,
+	"post_modified":     true,
+	"post_modified_gmt": true,
+	"post_parent":       true,
+	"menu_order":        true,
+	"post_type":         true,
+	"post_mime_type":    true,
+	"comment_count":     true,
+}
+
 // ObjectQueryOptions represents the available parameters for querying
 //
 // Somewhat similar to WP's json plugin
@@ -96,11 +131,42 @@ type ObjectQueryOptions struct {
 	After string `param:"after"`
 	Limit int    `param:"limit"`
 
+	// Page is the 1-based page of `Limit` objects to return,
+	// it cannot be combined with `After`
+	Page int `param:"page"`
+
+	// Before is the `PrevCursor` of a page, to return the page before it,
+	// it cannot be combined with `After` or `Page`
+	Before string `param:"before"`
+
+	// Order is the column to order by, which must be one of the `posts` columns
+	// that can be sorted on or an `*InvalidOrderError` is returned
 	Order          string `param:"order_by"`
 	OrderAscending bool   `param:"order_asc"`
 
-	PostType   PostType   `param:"post_type"`
-	PostStatus PostStatus `param:"post_status"`
+	// OrderBy orders by multiple columns, taking precedence over `Order` and `OrderAscending`
+	//
+	// Results ordered by more than one column are paginated by offset,
+	// so rows may be skipped or repeated if the results change between pages.
+	OrderBy []OrderClause `param:"order_by__multi"`
+
+	// OrderByMeta orders by the value of the metadata key in the direction of `OrderAscending`,
+	// before any `OrderBy` clauses, with objects that lack the key last
+	//
+	// Like `OrderBy`, results ordered by metadata are paginated by offset.
+	OrderByMeta string `param:"meta_key"`
+
+	// Sticky changes how the posts in the `sticky_posts` option are treated
+	//
+	// Like `OrderBy`, results with sticky posts floated to the top are paginated by offset.
+	Sticky StickyMode `param:"sticky"`
+
+	// OrderByMetaNumeric compares the `OrderByMeta` values as numbers instead of strings
+	OrderByMetaNumeric bool `param:"meta_value_num"`
+
+	PostType     PostType     `param:"post_type"`
+	PostStatus   PostStatus   `param:"post_status"`
+	PostStatusIn []PostStatus `param:"post_status__in"`
 
 	Author      int64   `param:"author_id"`
 	AuthorIn    []int64 `param:"author_id__in"`
@@ -120,7 +186,8 @@ type ObjectQueryOptions struct {
 	CategoryNameIn    []string `param:"category_name__in"`
 	CategoryNameNotIn []string `param:"category_name__not_in"`
 
-	MenuId      int64   `param:"menu_id"`
+	// MenuId is a pointer so that a zero id can be distinguished from unset
+	MenuId      *int64  `param:"menu_id"`
 	MenuIdAnd   []int64 `param:"menu_id__and_in"`
 	MenuIdIn    []int64 `param:"menu_id__in"`
 	MenuIdNotIn []int64 `param:"menu_id__not_in"`
@@ -139,7 +206,8 @@ type ObjectQueryOptions struct {
 	NameIn    []string `param:"post_name__in"`
 	NameNotIn []string `param:"post_name__not_in"`
 
-	Parent      int64   `param:"post_parent"`
+	// Parent is a pointer so that top-level objects can be matched with a zero id
+	Parent      *int64  `param:"post_parent"`
 	ParentIn    []int64 `param:"post_parent__in"`
 	ParentNotIn []int64 `param:"post_parent__not_in"`
 
@@ -157,55 +225,141 @@ type ObjectQueryOptions struct {
 	TagNameIn    []string `param:"tag_name__in"`
 	TagNameNotIn []string `param:"tag_name__not_in"`
 
+	// TaxonomyTermIn matches objects related to any of the terms of each of the taxonomies,
+	// which allows filtering by custom taxonomies
+	TaxonomyTermIn map[Taxonomy][]int64 `param:"taxonomy_term__in"`
+
+	// TaxonomyTermAnd matches objects related to all of the terms of each of the taxonomies
+	TaxonomyTermAnd map[Taxonomy][]int64 `param:"taxonomy_term__and"`
+
+	// TaxonomyTermNotIn matches objects related to none of the terms of each of the taxonomies
+	TaxonomyTermNotIn map[Taxonomy][]int64 `param:"taxonomy_term__not_in"`
+
+	// GuidIn matches objects by their guid, set `Limit` to -1 to match more than a page of guids
+	GuidIn []string `param:"guid__in"`
+
 	Query string `param:"q"`
 
+	// FullText matches `Query` against a FULLTEXT index on `post_title` and `post_content`
+	// in boolean mode, instead of matching each word with `LIKE`
+	FullText bool `param:"fulltext"`
+
+	// OrderByRelevance orders `FullText` results by relevance before any other order,
+	// which paginates the results by offset like `OrderBy`
+	OrderByRelevance bool `param:"orderby_relevance"`
+
 	Day   int `param:"day_of_month"`
 	Month int `param:"month_num"`
 	Year  int `param:"year"`
 
+	// AfterDate matches objects published strictly after the date
 	AfterDate time.Time
+
+	// DateBefore matches objects published strictly before the date
+	DateBefore time.Time
+
+	// DateQuery matches objects published within the range,
+	// in addition to `AfterDate` and `DateBefore`
+	DateQuery *DateQuery
+}
+
+// DateQuery represents a range of publish dates, either of which may be left open
+//
+// Like `post_date`, the bounds are compared by their wall clock in the site's time zone.
+type DateQuery struct {
+	After          time.Time
+	AfterInclusive bool
+
+	Before          time.Time
+	BeforeInclusive bool
+}
+
+// DateIn returns the object's date in the given location, usually the site's `GetTimezone`
+//
+// `Date` holds the wall clock of the site's timezone but is scanned as UTC,
+// so the GMT date is used when available and the wall clock is reinterpreted otherwise.
+func (obj *Object) DateIn(loc *time.Location) time.Time {
+	if !obj.DateGmt.IsZero() {
+		return obj.DateGmt.In(loc)
+	}
+
+	d := obj.Date
+	return time.Date(d.Year(), d.Month(), d.Day(), d.Hour(), d.Minute(), d.Second(), d.Nanosecond(), loc)
 }
 
 // GetMeta gets the object's metadata from the database
 //
 // Returns all metadata if no metadata keys are given
 func (obj *Object) GetMeta(c context.Context, keys ...string) (map[string]string, error) {
-	c, span := trace.StartSpan(c, "/wordpress.Object.GetMeta")
+	c, span := startSpan(c, "/wordpress.Object.GetMeta")
+	defer span.End()
+
+	meta, err := GetObjectsMeta(c, []int64{obj.Id}, keys...)
+	if err != nil {
+		return nil, err
+	}
+
+	return meta[obj.Id], nil
+}
+
+// GetObjectsMeta gets the metadata of all of the objects in a single query,
+// optionally limited to the given keys
+//
+// Every object is in the returned map, even those without any metadata.
+func GetObjectsMeta(c context.Context, objectIds []int64, keys ...string) (map[int64]map[string]string, error) {
+	c, span := startSpan(c, "/wordpress.GetObjectsMeta")
 	defer span.End()
 
-	q := sqrl.Select("meta_key", "meta_value").
+	ret := make(map[int64]map[string]string, len(objectIds))
+	for _, id := range objectIds {
+		ret[id] = make(map[string]string)
+	}
+
+	if len(objectIds) == 0 {
+		return ret, nil
+	}
+
+	q := sqrl.Select("post_id", "meta_key", "meta_value").
 		From(table(c, "postmeta")).
-		Where(sqrl.Eq{"post_id": obj.Id})
+		Where(sqrl.Eq{"post_id": objectIds})
 
 	if len(keys) > 0 {
 		q = q.Where(sqrl.Eq{"meta_key": keys})
 	}
 
-	sql, args, err := q.ToSql()
+	stmt, args, err := q.ToSql()
 	if err != nil {
 		return nil, err
 	}
 
-	span.AddAttributes(trace.StringAttribute("wp/meta/query", sql))
+	spanString(span, "wp/meta/query", stmt)
 
-	rows, err := database(c).Query(sql, args...)
+	rows, err := database(c).QueryContext(c, stmt, args...)
 	if err != nil {
 		return nil, err
 	}
 
-	meta := make(map[string]string)
+	defer rows.Close()
+
+	var count int64
 	for rows.Next() {
+		var id int64
 		var key, val string
-		if err := rows.Scan(&key, &val); err != nil {
-			return nil, fmt.Errorf("Object GetMeta - Scan: %v", err)
+		if err := rows.Scan(&id, &key, &val); err != nil {
+			return nil, err
 		}
 
-		meta[key] = val
+		ret[id][key] = val
+		count++
 	}
 
-	span.AddAttributes(trace.Int64Attribute("wp/meta/count", int64(len(meta))))
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
 
-	return meta, nil
+	spanInt64(span, "wp/meta/count", count)
+
+	return ret, nil
 }
 
 // GetTaxonomy gets all term ids related to the object
@@ -220,7 +374,45 @@ func (obj *Object) GetTaxonomy(c context.Context, taxonomy ...Taxonomy) (Iterato
 	}
 }
 
-// GetObjects gets all object data from the database
+// objectColumnNames are the `posts` columns in the order they are scanned by getObjects
+var objectColumnNames = []string{
+	"ID",
+	"post_author",
+	"post_date",
+	"post_date_gmt",
+	"post_content",
+	"post_title",
+	"post_excerpt",
+	"post_status",
+	"comment_status",
+	"ping_status",
+	"post_password",
+	"post_name",
+	"to_ping",
+	"pinged",
+	"post_modified",
+	"post_modified_gmt",
+	"post_content_filtered",
+	"post_parent",
+	"guid",
+	"menu_order",
+	"post_type",
+	"post_mime_type",
+	"comment_count",
+}
+
+// GetObjects gets the bare object data from the database
+// without any of the metadata or terms loaded for posts
+//
+// Returns a `MissingResourcesError` if any of the objects do not exist
+func GetObjects(c context.Context, objectIds ...int64) ([]*Object, error) {
+	c, span := startSpan(c, "/wordpress.GetObjects")
+	defer span.End()
+
+	return getObjects(c, objectIds...)
+}
+
+// getObjects gets all object data from the database
 // (not including metadata)
 func getObjects(c context.Context, objectIds ...int64) ([]*Object, error) {
 	if len(objectIds) == 0 {
@@ -230,62 +422,57 @@ func getObjects(c context.Context, objectIds ...int64) ([]*Object, error) {
 	// dedupe the given object ids
 	ids, idMap := dedupe(objectIds)
 
-	// select objects from the database
-	stmt, args, err := sqrl.Select("*").
+	ret := make([]*Object, len(objectIds))
+
+	var cached []*Object
+	hitIds := getCached(c, objectCacheKey, ids, &cached)
+	for i, id := range hitIds {
+		for _, index := range idMap[id] {
+			ret[index] = cached[i]
+		}
+	}
+
+	if ids = without(ids, hitIds); len(ids) == 0 {
+		return ret, nil
+	}
+
+	// select objects from the database, listing the columns
+	// in the order they are scanned rather than relying on the table's order
+	stmt, args, err := sqrl.Select(objectColumnNames...).
 		From(table(c, "posts")).
 		Where(sqrl.Eq{"ID": ids}).ToSql()
 	if err != nil {
 		return nil, err
 	}
 
-	trace.FromContext(c).AddAttributes(trace.StringAttribute("wp/object/query", stmt))
+	spanString(spanFromContext(c), "wp/object/query", stmt)
 
-	rows, err := database(c).Query(stmt, args...)
+	rows, err := database(c).QueryContext(c, stmt, args...)
 	if err != nil {
-		return nil, fmt.Errorf("GetObjects - Query: %v", err)
+		return nil, fmt.Errorf("GetObjects - Query: %w", err)
 	}
 
-	ret := make([]*Object, len(objectIds))
+	defer rows.Close()
+
 	for rows.Next() {
-		var obj Object
-		var commentStatus, pingStatus string
-		if err := rows.Scan(
-			&obj.Id,
-			&obj.AuthorId,
-			&obj.Date,
-			&obj.DateGmt,
-			&obj.Content,
-			&obj.Title,
-			&obj.Excerpt,
-			&obj.Status,
-			&commentStatus,
-			&pingStatus,
-			&obj.Password,
-			&obj.Name,
-			&obj.ToPing,
-			&obj.Pinged,
-			&obj.Modified,
-			&obj.ModifiedGmt,
-			&obj.ContentFiltered,
-			&obj.ParentId,
-			&obj.Guid,
-			&obj.MenuOrder,
-			&obj.Type,
-			&obj.MimeType,
-			&obj.CommentCount); err != nil {
-			return nil, fmt.Errorf("unable to read object data: %v", err)
-		}
-
-		obj.CommentStatus = commentStatus == "open"
-		obj.PingStatus = pingStatus == "open"
+		obj, err := scanObject(rows)
+		if err != nil {
+			return nil, err
+		}
+
+		setCached(c, objectCacheKey, obj.Id, obj)
 
 		// redupe and insert into return set
 		for _, index := range idMap[obj.Id] {
-			ret[index] = &obj
+			ret[index] = obj
 		}
 	}
 
-	trace.FromContext(c).AddAttributes(trace.Int64Attribute("wp/object/count", int64(len(ret))))
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	spanInt64(spanFromContext(c), "wp/object/count", int64(len(ret)))
 
 	var mre MissingResourcesError
 	for i, obj := range ret {
@@ -295,12 +482,66 @@ func getObjects(c context.Context, objectIds ...int64) ([]*Object, error) {
 	}
 
 	if len(mre) > 0 {
-		return nil, err
+		return nil, mre
 	}
 
 	return ret, nil
 }
 
+// rowScanner is a row of `*sql.Row` or `*sql.Rows`
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// scanObject reads an object from a row of the columns in `objectColumnNames`
+func scanObject(row rowScanner) (*Object, error) {
+	var obj Object
+	var commentStatus, pingStatus string
+	if err := row.Scan(
+		&obj.Id,
+		&obj.AuthorId,
+		(*nullTime)(&obj.Date),
+		(*nullTime)(&obj.DateGmt),
+		&obj.Content,
+		&obj.Title,
+		&obj.Excerpt,
+		&obj.Status,
+		&commentStatus,
+		&pingStatus,
+		&obj.Password,
+		&obj.Name,
+		&obj.ToPing,
+		&obj.Pinged,
+		(*nullTime)(&obj.Modified),
+		(*nullTime)(&obj.ModifiedGmt),
+		&obj.ContentFiltered,
+		&obj.ParentId,
+		&obj.Guid,
+		&obj.MenuOrder,
+		&obj.Type,
+		&obj.MimeType,
+		&obj.CommentCount); err != nil {
+		return nil, fmt.Errorf("unable to read object data: %w", err)
+	}
+
+	obj.CommentStatus = commentStatus == "open"
+	obj.PingStatus = pingStatus == "open"
+
+	return &obj, nil
+}
+
+// getExistingObjects is like getObjects, except that objects
+// that do not exist are left out instead of failing the whole set
+func getExistingObjects(c context.Context, objectIds ...int64) ([]*Object, error) {
+	objects, err := getObjects(c, objectIds...)
+	mre, ok := err.(MissingResourcesError)
+	if !ok {
+		return objects, err
+	}
+
+	return getObjects(c, without(objectIds, mre)...)
+}
+
 type inSubquery struct {
 	column string
 	query  sqrl.Sqlizer
@@ -321,30 +562,406 @@ func (in inSubquery) ToSql() (string, []interface{}, error) {
 	return in.column + stmt, args, nil
 }
 
+// clone returns a copy of the options that can be changed without changing the original,
+// or empty options if opts is nil
+//
+// The slices are shared, so they must only be appended to with a full slice expression.
+func (opts *ObjectQueryOptions) clone() *ObjectQueryOptions {
+	if opts == nil {
+		return &ObjectQueryOptions{}
+	}
+
+	clone := *opts
+	return &clone
+}
+
 // queryObjects returns the ids of the objects that match the query
 func queryObjects(c context.Context, opts *ObjectQueryOptions) (Iterator, error) {
+	// the options are normalized on a copy, so that the caller's options can be reused
+	opts = opts.clone()
+
+	if opts.Page < 0 {
+		return nil, errors.New("wordpress: page must not be negative")
+	} else if opts.Page > 0 && opts.After != "" {
+		return nil, errors.New("wordpress: page and after cannot be combined")
+	} else if opts.Before != "" && (opts.Page > 0 || opts.After != "") {
+		return nil, errors.New("wordpress: before cannot be combined with page or after")
+	}
+
+	// a single clause is the same as the shortcut
+	if len(opts.OrderBy) == 1 {
+		opts.Order = opts.OrderBy[0].Column
+		opts.OrderAscending = opts.OrderBy[0].Ascending
+		opts.OrderBy = nil
+	}
+
 	if opts.Order == "" {
 		opts.Order = "post_date"
+	} else if !sortableObjectColumns[opts.Order] {
+		return nil, &InvalidOrderError{Column: opts.Order}
+	}
+
+	for _, clause := range opts.OrderBy {
+		if !sortableObjectColumns[clause.Column] {
+			return nil, &InvalidOrderError{Column: clause.Column}
+		}
+	}
+
+	// orders with multiple columns are paginated by offset instead of by keyset
+	var orderBy []string
+	for _, clause := range opts.OrderBy {
+		orderBy = append(orderBy, clause.sql())
+	}
+
+	if opts.OrderByMeta != "" {
+		value := "om.meta_value"
+		if opts.OrderByMetaNumeric {
+			value = "CAST(om.meta_value AS DECIMAL(65,10))"
+		}
+
+		if opts.OrderAscending {
+			value += " ASC"
+		} else {
+			value += " DESC"
+		}
+
+		// objects without the key sort last in either direction
+		orderBy = append([]string{"om.meta_value IS NULL ASC", value}, orderBy...)
+	}
+
+	// the order column is only selected for keyset cursors, so it is replaced by the relevance
+	selection := sqrl.Select("ID", opts.Order)
+
+	byRelevance := opts.Query != "" && opts.FullText && opts.OrderByRelevance
+	if byRelevance {
+		selection = sqrl.Select("ID").
+			Column(fullTextRelevance(c)+" AS relevance", opts.Query)
+
+		orderBy = append([]string{"relevance DESC"}, orderBy...)
+	}
+
+	var stickyIds []int64
+	if opts.Sticky == StickyInclude {
+		var err error
+		if stickyIds, err = GetStickyPostIds(c); err != nil {
+			return nil, err
+		}
+
+		if len(stickyIds) > 0 {
+			orderBy = append([]string{"sticky.sticky_id IS NULL ASC"}, orderBy...)
+		}
+	}
+
+	offsetOrder := strings.Join(orderBy, ", ")
+	if len(stickyIds) > 0 {
+		offsetOrder = "sticky " + fmt.Sprint(stickyIds) + ", " + offsetOrder
+	}
+
+	if opts.OrderByMeta != "" {
+		offsetOrder = "meta_key " + strconv.Quote(opts.OrderByMeta) + ", " + offsetOrder
+	}
+
+	if byRelevance {
+		offsetOrder = "q " + strconv.Quote(opts.Query) + ", " + offsetOrder
+	}
+
+	q, err := filterObjects(c, selection.From(table(c, "posts")), opts)
+	if err != nil {
+		return nil, err
+	}
+
+	if opts.OrderByMeta != "" {
+		// aggregated so that repeated keys don't repeat objects
+		q = q.LeftJoin("(SELECT post_id, MIN(meta_value) AS meta_value FROM "+table(c, "postmeta")+
+			" WHERE meta_key = ? GROUP BY post_id) AS om ON om.post_id = "+table(c, "posts")+".ID", opts.OrderByMeta)
+	}
+
+	if len(stickyIds) > 0 {
+		// joined since the order clause cannot have parameters
+		in, args := expandIn("ID", stickyIds)
+		q = q.LeftJoin("(SELECT ID AS sticky_id FROM "+table(c, "posts")+" WHERE "+in+") AS sticky "+
+			"ON sticky.sticky_id = "+table(c, "posts")+".ID", args...)
+	}
+
+	if opts.Limit == 0 {
+		opts.Limit = 10
+	}
+
+	limit := opts.Limit
+
+	var offset int
+	if opts.Page > 1 && opts.Limit > 0 {
+		offset = (opts.Page - 1) * opts.Limit
+	}
+
+	// pages before a keyset cursor are queried in reverse, then flipped back
+	var reverse bool
+
+	if offsetOrder != "" {
+		if opts.After != "" {
+			cur, err := decodeCursor(opts.After, offsetOrder)
+			if err != nil {
+				return nil, err
+			}
+
+			if offset, err = strconv.Atoi(cur.Value); err != nil || offset < 0 {
+				return nil, ErrInvalidCursor
+			}
+		} else if opts.Before != "" {
+			cur, err := decodeCursor(opts.Before, offsetOrder)
+			if err != nil {
+				return nil, err
+			}
+
+			// the cursor's value is the offset after its row, and its row is excluded
+			end, err := strconv.Atoi(cur.Value)
+			if err != nil || end < 1 {
+				return nil, ErrInvalidCursor
+			}
+
+			if end--; end == 0 {
+				return newObjectIterator(opts, offsetOrder, 0, nil, nil), nil
+			}
+
+			if limit < 0 || limit > end {
+				limit = end
+			}
+
+			offset = end - limit
+		}
+
+		// the shortcut order still applies after the meta, relevance, and sticky orders
+		if len(opts.OrderBy) == 0 {
+			if opts.OrderAscending {
+				orderBy = append(orderBy, opts.Order+" ASC")
+			} else {
+				orderBy = append(orderBy, opts.Order+" DESC")
+			}
+		}
+
+		q = q.OrderBy(append(orderBy, "ID ASC")...)
 	} else {
-		// gotta prevent dat sql injection :)
-		opts.Order = strings.Replace(opts.Order, "`", "", -1)
+		from := opts.After
+		if opts.Before != "" {
+			from, reverse = opts.Before, true
+		}
+
+		ascending := opts.OrderAscending != reverse
+
+		// rows are compared by the order column and then by id, so that rows
+		// with the same order value are neither skipped nor repeated between pages
+		if from != "" {
+			cur, err := decodeCursor(from, opts.Order)
+			if err != nil {
+				return nil, err
+			}
+
+			op := "<"
+			if ascending {
+				op = ">"
+			}
+
+			q = q.Where("("+opts.Order+", ID) "+op+" (?, ?)", cur.Value, cur.Id)
+		}
+
+		direction := " DESC"
+		if ascending {
+			direction = " ASC"
+		}
+
+		q = q.OrderBy(opts.Order+direction, "ID"+direction)
 	}
 
-	opts.Order = "`" + opts.Order + "`"
+	if limit > 0 {
+		q = q.Limit(uint64(limit))
+	}
 
-	q := sqrl.Select("ID", opts.Order).From(table(c, "posts"))
+	if offset > 0 {
+		q = q.Offset(uint64(offset))
+	}
 
-	termsSubQuery := sqrl.Select("object_id").
-		From(table(c, "term_relationships") + " AS tr").
-		Join(table(c, "term_taxonomy") + " AS tt ON tr.term_taxonomy_id = tt.term_taxonomy_id").
-		Join(table(c, "terms") + " AS t ON tt.term_id = t.term_id")
+	resultKey := queryResultKey(c, "posts", opts)
+
+	var cached queryResult
+	if getCachedQuery(c, resultKey, &cached) {
+		return newObjectIterator(opts, offsetOrder, offset, cached.Ids, cached.Cursors), nil
+	}
+
+	stmt, args, err := q.ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	spanString(spanFromContext(c), "wp/object/query", stmt)
+
+	rows, err := database(c).QueryContext(c, stmt, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	var ids []int64
+	var cursors []string
+	for rows.Next() {
+		var id int64
+		var cursor string
+		if err = rows.Scan(&id, &cursor); err != nil {
+			return nil, err
+		}
+
+		ids = append(ids, id)
+		cursors = append(cursors, cursor)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	spanInt64(spanFromContext(c), "wp/object/count", int64(len(ids)))
+
+	if reverse {
+		for i, j := 0, len(ids)-1; i < j; i, j = i+1, j-1 {
+			ids[i], ids[j] = ids[j], ids[i]
+			cursors[i], cursors[j] = cursors[j], cursors[i]
+		}
+	}
+
+	setCachedQuery(c, resultKey, &queryResult{Ids: ids, Cursors: cursors})
+
+	return newObjectIterator(opts, offsetOrder, offset, ids, cursors), nil
+}
+
+// newObjectIterator returns an iterator over the ids, whose cursors are built from the order column values
+//
+// If offsetOrder is not empty, the cursors are built from the offset of each row instead.
+func newObjectIterator(opts *ObjectQueryOptions, offsetOrder string, offset int, ids []int64, cursors []string) Iterator {
+	it := iteratorImpl{cursor: opts.After, length: len(ids)}
+
+	if len(ids) > 0 {
+		if offsetOrder != "" {
+			it.prevCursor = encodeCursor(offsetOrder, strconv.Itoa(offset+1), ids[0])
+		} else {
+			it.prevCursor = encodeCursor(opts.Order, cursors[0], ids[0])
+		}
+	}
+
+	var counter int
+	it.next = func() (id int64, err error) {
+		if counter < len(ids) {
+			id = ids[counter]
+			if offsetOrder != "" {
+				it.cursor = encodeCursor(offsetOrder, strconv.Itoa(offset+counter+1), id)
+			} else {
+				it.cursor = encodeCursor(opts.Order, cursors[counter], id)
+			}
+			counter++
+		} else {
+			return it.exit(Done)
+		}
+
+		return id, err
+	}
+
+	return &it
+}
+
+// countObjects returns the number of objects that match the query
+//
+// Pagination options are ignored
+func countObjects(c context.Context, opts *ObjectQueryOptions) (int, error) {
+	q, err := filterObjects(c, sqrl.Select("COUNT(*)").From(table(c, "posts")), opts)
+	if err != nil {
+		return 0, err
+	}
+
+	stmt, args, err := q.ToSql()
+	if err != nil {
+		return 0, err
+	}
+
+	spanString(spanFromContext(c), "wp/object/query", stmt)
+
+	var count int
+	if err := database(c).QueryRowContext(c, stmt, args...).Scan(&count); err != nil {
+		return 0, err
+	}
+
+	spanInt64(spanFromContext(c), "wp/object/count", int64(count))
+
+	return count, nil
+}
+
+// latestModified returns the most recent GMT modified time of the objects that match the query
+func latestModified(c context.Context, opts *ObjectQueryOptions) (time.Time, error) {
+	q, err := filterObjects(c, sqrl.Select("MAX(post_modified_gmt)").From(table(c, "posts")), opts)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	stmt, args, err := q.ToSql()
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	spanString(spanFromContext(c), "wp/object/query", stmt)
+
+	// MAX returns null if nothing matches, which is scanned as the zero time
+	var modified time.Time
+	if err := database(c).QueryRowContext(c, stmt, args...).Scan((*nullTime)(&modified)); err != nil {
+		return time.Time{}, err
+	}
+
+	return modified, nil
+}
+
+// sortedTaxonomies returns the taxonomies of the map in order, so that the built query is stable
+func sortedTaxonomies(terms map[Taxonomy][]int64) []Taxonomy {
+	taxonomies := make([]Taxonomy, 0, len(terms))
+	for taxonomy := range terms {
+		taxonomies = append(taxonomies, taxonomy)
+	}
+
+	sort.Slice(taxonomies, func(i, j int) bool {
+		return taxonomies[i] < taxonomies[j]
+	})
+
+	return taxonomies
+}
+
+// filterObjects applies the filters in opts to the given select query
+//
+// The options are left unchanged.
+func filterObjects(c context.Context, q *sqrl.SelectBuilder, opts *ObjectQueryOptions) (*sqrl.SelectBuilder, error) {
+	// termsIn matches objects related to any of the terms of the taxonomy, or to none of them if neg is true
+	//
+	// A new subquery is built for every filter since `Where` modifies the builder.
+	termsIn := func(taxonomy Taxonomy, column string, terms interface{}, neg bool) inSubquery {
+		return inSubquery{
+			column: "ID",
+			query: sqrl.Select("object_id").
+				From(table(c, "term_relationships") + " AS tr").
+				Join(table(c, "term_taxonomy") + " AS tt ON tr.term_taxonomy_id = tt.term_taxonomy_id").
+				Join(table(c, "terms") + " AS t ON tt.term_id = t.term_id").
+				Where(sqrl.Eq{"tt.taxonomy": string(taxonomy), column: terms}),
+			neg: neg}
+	}
 
 	if opts.PostType != "" {
 		q = q.Where(sqrl.Eq{"post_type": string(opts.PostType)})
 	}
 
 	if opts.PostStatus != "" {
-		q = q.Where(sqrl.Eq{"post_status": string(opts.PostStatus)})
+		if opts.PostStatus != PostStatusAny {
+			q = q.Where(sqrl.Eq{"post_status": string(opts.PostStatus)})
+		}
+	} else if opts.PostStatusIn != nil && len(opts.PostStatusIn) > 0 {
+		var statuses []string
+		for _, status := range opts.PostStatusIn {
+			statuses = append(statuses, string(status))
+		}
+
+		q = q.Where(sqrl.Eq{"post_status": statuses})
 	}
 
 	if opts.Author > 0 {
@@ -376,19 +993,27 @@ func queryObjects(c context.Context, opts *ObjectQueryOptions) (Iterator, error)
 			neg: true})
 	}
 
+	// the category filters are expanded into copies, since the options must not be modified
+	categoryAnd := opts.CategoryAnd[:len(opts.CategoryAnd):len(opts.CategoryAnd)]
+	categoryIn := opts.CategoryIn[:len(opts.CategoryIn):len(opts.CategoryIn)]
+	categoryNotIn := opts.CategoryNotIn[:len(opts.CategoryNotIn):len(opts.CategoryNotIn)]
+	categoryNameAnd := opts.CategoryNameAnd[:len(opts.CategoryNameAnd):len(opts.CategoryNameAnd)]
+	categoryNameIn := opts.CategoryNameIn[:len(opts.CategoryNameIn):len(opts.CategoryNameIn)]
+	categoryNameNotIn := opts.CategoryNameNotIn[:len(opts.CategoryNameNotIn):len(opts.CategoryNameNotIn)]
+
 	if opts.CategoryName != "" {
 		sortCategory := func(cat string) {
 			switch cat[:1] {
 			case "+":
-				opts.CategoryNameAnd = append(opts.CategoryNameAnd, cat[1:])
+				categoryNameAnd = append(categoryNameAnd, cat[1:])
 			case "~":
-				opts.CategoryNameNotIn = append(opts.CategoryNameNotIn, cat[1:])
+				categoryNameNotIn = append(categoryNameNotIn, cat[1:])
 			default:
 				if cat[:1] == "," {
 					cat = cat[1:]
 				}
 
-				opts.CategoryNameIn = append(opts.CategoryNameIn, cat)
+				categoryNameIn = append(categoryNameIn, cat)
 			}
 		}
 		prevIndex := 0
@@ -398,147 +1023,103 @@ func queryObjects(c context.Context, opts *ObjectQueryOptions) (Iterator, error)
 		}
 
 		sortCategory(opts.CategoryName[prevIndex:])
-
-		opts.CategoryName = ""
 	}
 
-	if opts.CategoryNameAnd != nil && len(opts.CategoryNameAnd) > 0 {
-		for _, categoryName := range opts.CategoryNameAnd {
+	if len(categoryNameAnd) > 0 {
+		for _, categoryName := range categoryNameAnd {
 			catId, _ := GetCategoryIdBySlug(c, categoryName)
 			if catId == 0 {
 				continue
 			}
-			opts.CategoryAnd = append(opts.CategoryAnd, catId)
+			categoryAnd = append(categoryAnd, catId)
 		}
-	} else if opts.CategoryNameIn != nil && len(opts.CategoryNameIn) > 0 {
-		for _, categoryName := range opts.CategoryNameIn {
+	} else if len(categoryNameIn) > 0 {
+		for _, categoryName := range categoryNameIn {
 			catId, _ := GetCategoryIdBySlug(c, categoryName)
 			if catId == 0 {
 				continue
 			}
-			opts.CategoryIn = append(opts.CategoryIn, catId)
+			categoryIn = append(categoryIn, catId)
 		}
-	} else if opts.CategoryNameNotIn != nil && len(opts.CategoryNameNotIn) > 0 {
-		for _, categoryName := range opts.CategoryNameNotIn {
+	}
+
+	// exclusions are applied regardless of the inclusions above
+	if len(categoryNameNotIn) > 0 {
+		for _, categoryName := range categoryNameNotIn {
 			catId, _ := GetCategoryIdBySlug(c, categoryName)
 			if catId == 0 {
 				continue
 			}
-			opts.CategoryNotIn = append(opts.CategoryNotIn, catId)
+			categoryNotIn = append(categoryNotIn, catId)
 		}
 	}
 
+	// the hierarchy is only loaded once, no matter how many categories are expanded
+	var children categoryHierarchy
+	descendants := func(ids ...int64) ([]int64, error) {
+		if children == nil {
+			var err error
+			if children, err = categoryChildren(c); err != nil {
+				return nil, err
+			}
+		}
+
+		return children.descendants(ids...), nil
+	}
+
 	if opts.Category > 0 {
-		cat := Category{Term: Term{Id: opts.Category}}
-		ids, err := cat.GetChildrenIds(c)
+		ids, err := descendants(opts.Category)
 		if err != nil {
 			return nil, err
 		}
 
-		q = q.Where(inSubquery{
-			column: "ID",
-			query: termsSubQuery.Where(sqrl.Eq{
-				"tt.taxonomy": "category",
-				"t.term_id":   ids})})
-	} else if opts.CategoryAnd != nil && len(opts.CategoryAnd) > 0 {
-		for _, categoryId := range opts.CategoryAnd {
-			cat := Category{Term: Term{Id: categoryId}}
-			ids, err := cat.GetChildrenIds(c)
+		q = q.Where(termsIn(TaxonomyCategory, "t.term_id", ids, false))
+	} else if len(categoryAnd) > 0 {
+		for _, categoryId := range categoryAnd {
+			ids, err := descendants(categoryId)
 			if err != nil {
 				return nil, err
 			}
 
-			q = q.Where(inSubquery{
-				column: "ID",
-				query: termsSubQuery.Where(sqrl.Eq{
-					"tt.taxonomy": "category",
-					"t.term_id":   ids})})
-		}
-	} else if opts.CategoryIn != nil && len(opts.CategoryIn) > 0 {
-		var catIds []int64
-		for _, categoryId := range opts.CategoryIn[:] {
-			cat := Category{Term: Term{Id: categoryId}}
-			ids, err := cat.GetChildrenIds(c)
-			if err != nil {
-				return nil, err
-			}
-
-			catIds = append(append(catIds, categoryId), ids...)
+			q = q.Where(termsIn(TaxonomyCategory, "t.term_id", ids, false))
+		}
+	} else if len(categoryIn) > 0 {
+		ids, err := descendants(categoryIn...)
+		if err != nil {
+			return nil, err
 		}
 
-		q = q.Where(inSubquery{
-			column: "ID",
-			query: termsSubQuery.Where(sqrl.Eq{
-				"tt.taxonomy": "category",
-				"t.term_id":   catIds})})
-	} else if opts.CategoryNotIn != nil && len(opts.CategoryNotIn) > 0 {
-		var catIds []int64
-		for _, categoryId := range opts.CategoryNotIn[:] {
-			cat := Category{Term: Term{Id: categoryId}}
-			ids, err := cat.GetChildrenIds(c)
-			if err != nil {
-				return nil, err
-			}
+		q = q.Where(termsIn(TaxonomyCategory, "t.term_id", ids, false))
+	}
 
-			catIds = append(append(catIds, categoryId), ids...)
+	// excluding a category also excludes posts in any of its descendants
+	if len(categoryNotIn) > 0 {
+		ids, err := descendants(categoryNotIn...)
+		if err != nil {
+			return nil, err
 		}
 
-		q = q.Where(inSubquery{
-			column: "ID",
-			query: termsSubQuery.Where(sqrl.Eq{
-				"tt.taxonomy": "category",
-				"t.term_id":   catIds}),
-			neg: true})
+		q = q.Where(termsIn(TaxonomyCategory, "t.term_id", ids, true))
 	}
 
-	if opts.MenuId > 0 {
-		q = q.Where(inSubquery{
-			column: "ID",
-			query: termsSubQuery.Where(sqrl.Eq{
-				"tt.taxonomy": "nav_menu",
-				"t.term_id":   opts.MenuId})})
+	if opts.MenuId != nil {
+		q = q.Where(termsIn(TaxonomyNavMenu, "t.term_id", *opts.MenuId, false))
 	} else if opts.MenuIdAnd != nil && len(opts.MenuIdAnd) > 0 {
 		for _, menuId := range opts.MenuIdAnd {
-			q = q.Where(inSubquery{
-				column: "ID",
-				query: termsSubQuery.Where(sqrl.Eq{
-					"tt.taxonomy": "nav_menu",
-					"t.term_id":   menuId})})
+			q = q.Where(termsIn(TaxonomyNavMenu, "t.term_id", menuId, false))
 		}
 	} else if opts.MenuIdIn != nil && len(opts.MenuIdIn) > 0 {
-		q = q.Where(inSubquery{
-			column: "ID",
-			query: termsSubQuery.Where(sqrl.Eq{
-				"tt.taxonomy": "nav_menu",
-				"t.term_id":   opts.MenuIdIn})})
+		q = q.Where(termsIn(TaxonomyNavMenu, "t.term_id", opts.MenuIdIn, false))
 	} else if opts.MenuIdNotIn != nil && len(opts.MenuIdNotIn) > 0 {
-		q = q.Where(inSubquery{
-			column: "ID",
-			query: termsSubQuery.Where(sqrl.Eq{
-				"tt.taxonomy": "nav_menu",
-				"t.term_id":   opts.MenuIdNotIn}),
-			neg: true})
+		q = q.Where(termsIn(TaxonomyNavMenu, "t.term_id", opts.MenuIdNotIn, true))
 	}
 
 	if opts.MenuName != "" {
-		q = q.Where(inSubquery{
-			column: "ID",
-			query: termsSubQuery.Where(sqrl.Eq{
-				"tt.taxonomy": "nav_menu",
-				"t.slug":      opts.MenuName})})
+		q = q.Where(termsIn(TaxonomyNavMenu, "t.slug", opts.MenuName, false))
 	} else if opts.MenuNameIn != nil && len(opts.MenuNameIn) > 0 {
-		q = q.Where(inSubquery{
-			column: "ID",
-			query: termsSubQuery.Where(sqrl.Eq{
-				"tt.taxonomy": "nav_menu",
-				"t.slug":      opts.MenuNameIn})})
+		q = q.Where(termsIn(TaxonomyNavMenu, "t.slug", opts.MenuNameIn, false))
 	} else if opts.MenuNameNotIn != nil && len(opts.MenuNameNotIn) > 0 {
-		q = q.Where(inSubquery{
-			column: "ID",
-			query: termsSubQuery.Where(sqrl.Eq{
-				"tt.taxonomy": "nav_menu",
-				"t.slug":      opts.MenuNameNotIn}),
-			neg: true})
+		q = q.Where(termsIn(TaxonomyNavMenu, "t.slug", opts.MenuNameNotIn, true))
 	}
 
 	var searchMeta = func(metas ...string) {
@@ -569,15 +1150,20 @@ func queryObjects(c context.Context, opts *ObjectQueryOptions) (Iterator, error)
 			neg:    neg})
 	}
 
+	// every meta filter is its own subquery, so they can all be combined
 	if opts.Meta != "" {
 		searchMeta(opts.Meta)
-	} else if len(opts.MetaAnd) > 0 {
-		for _, meta := range opts.MetaAnd {
-			searchMeta(meta)
-		}
-	} else if len(opts.MetaIn) > 0 {
+	}
+
+	for _, meta := range opts.MetaAnd {
+		searchMeta(meta)
+	}
+
+	if len(opts.MetaIn) > 0 {
 		searchMeta(opts.MetaIn...)
-	} else if len(opts.MetaNotIn) > 0 {
+	}
+
+	if len(opts.MetaNotIn) > 0 {
 		searchMeta(append([]string{"is not in"}, opts.MetaNotIn...)...)
 	}
 
@@ -589,14 +1175,18 @@ func queryObjects(c context.Context, opts *ObjectQueryOptions) (Iterator, error)
 		q = q.Where(sqrl.NotEq{"post_name": opts.NameNotIn})
 	}
 
-	if opts.Parent > 0 {
-		q = q.Where(sqrl.Eq{"post_parent": opts.Parent})
+	if opts.Parent != nil {
+		q = q.Where(sqrl.Eq{"post_parent": *opts.Parent})
 	} else if opts.ParentIn != nil && len(opts.ParentIn) > 0 {
 		q = q.Where(sqrl.Eq{"post_parent": opts.ParentIn})
 	} else if opts.ParentNotIn != nil && len(opts.ParentNotIn) > 0 {
 		q = q.Where(sqrl.NotEq{"post_parent": opts.ParentNotIn})
 	}
 
+	if opts.GuidIn != nil && len(opts.GuidIn) > 0 {
+		q = q.Where(sqrl.Eq{"guid": opts.GuidIn})
+	}
+
 	if opts.Post > 0 {
 		q = q.Where(sqrl.Eq{"ID": opts.Post})
 	} else if opts.PostIn != nil && len(opts.PostIn) > 0 {
@@ -606,64 +1196,65 @@ func queryObjects(c context.Context, opts *ObjectQueryOptions) (Iterator, error)
 	}
 
 	if opts.TagId > 0 {
-		q = q.Where(inSubquery{
-			column: "ID",
-			query: termsSubQuery.Where(sqrl.Eq{
-				"tt.taxonomy": "post_tag",
-				"t.term_id":   opts.TagId})})
+		q = q.Where(termsIn(TaxonomyPostTag, "t.term_id", opts.TagId, false))
 	} else if opts.TagIdAnd != nil && len(opts.TagIdAnd) > 0 {
 		for _, tagId := range opts.TagIdAnd {
-			q = q.Where(inSubquery{
-				column: "ID",
-				query: termsSubQuery.Where(sqrl.Eq{
-					"tt.taxonomy": "post_tag",
-					"t.term_id":   tagId})})
+			q = q.Where(termsIn(TaxonomyPostTag, "t.term_id", tagId, false))
 		}
 	} else if opts.TagIdIn != nil && len(opts.TagIdIn) > 0 {
-		q = q.Where(inSubquery{
-			column: "ID",
-			query: termsSubQuery.Where(sqrl.Eq{
-				"tt.taxonomy": "post_tag",
-				"t.term_id":   opts.TagIdIn})})
+		q = q.Where(termsIn(TaxonomyPostTag, "t.term_id", opts.TagIdIn, false))
 	} else if opts.TagIdNotIn != nil && len(opts.TagIdNotIn) > 0 {
-		q = q.Where(inSubquery{
-			column: "ID",
-			query: termsSubQuery.Where(sqrl.Eq{
-				"tt.taxonomy": "post_tag",
-				"t.term_id":   opts.TagIdNotIn}),
-			neg: true})
+		q = q.Where(termsIn(TaxonomyPostTag, "t.term_id", opts.TagIdNotIn, true))
 	}
 
 	if opts.TagName != "" {
-		q = q.Where(inSubquery{
-			column: "ID",
-			query: termsSubQuery.Where(sqrl.Eq{
-				"tt.taxonomy": "post_tag",
-				"t.slug":      opts.TagName})})
+		q = q.Where(termsIn(TaxonomyPostTag, "t.slug", opts.TagName, false))
 	} else if opts.TagNameAnd != nil && len(opts.TagNameAnd) > 0 {
 		for _, tagName := range opts.TagNameAnd {
-			q = q.Where(inSubquery{
-				column: "ID",
-				query: termsSubQuery.Where(sqrl.Eq{
-					"tt.taxonomy": "post_tag",
-					"t.slug":      tagName})})
+			q = q.Where(termsIn(TaxonomyPostTag, "t.slug", tagName, false))
 		}
 	} else if opts.TagNameIn != nil && len(opts.TagNameIn) > 0 {
-		q = q.Where(inSubquery{
-			column: "ID",
-			query: termsSubQuery.Where(sqrl.Eq{
-				"tt.taxonomy": "post_tag",
-				"t.slug":      opts.TagNameIn})})
+		q = q.Where(termsIn(TaxonomyPostTag, "t.slug", opts.TagNameIn, false))
 	} else if opts.TagNameNotIn != nil && len(opts.TagNameNotIn) > 0 {
-		q = q.Where(inSubquery{
-			column: "ID",
-			query: termsSubQuery.Where(sqrl.Eq{
-				"tt.taxonomy": "post_tag",
-				"t.slug":      opts.TagNameNotIn}),
-			neg: true})
+		q = q.Where(termsIn(TaxonomyPostTag, "t.slug", opts.TagNameNotIn, true))
+	}
+
+	// each taxonomy is its own subquery, so they also combine with the built-in taxonomy filters
+	for _, taxonomy := range sortedTaxonomies(opts.TaxonomyTermIn) {
+		if termIds := opts.TaxonomyTermIn[taxonomy]; len(termIds) > 0 {
+			q = q.Where(termsIn(taxonomy, "t.term_id", termIds, false))
+		}
+	}
+
+	for _, taxonomy := range sortedTaxonomies(opts.TaxonomyTermAnd) {
+		for _, termId := range opts.TaxonomyTermAnd[taxonomy] {
+			q = q.Where(termsIn(taxonomy, "t.term_id", termId, false))
+		}
+	}
+
+	for _, taxonomy := range sortedTaxonomies(opts.TaxonomyTermNotIn) {
+		if termIds := opts.TaxonomyTermNotIn[taxonomy]; len(termIds) > 0 {
+			q = q.Where(termsIn(taxonomy, "t.term_id", termIds, true))
+		}
+	}
+
+	if opts.Sticky == StickyExclude || opts.Sticky == StickyOnly {
+		stickyIds, err := GetStickyPostIds(c)
+		if err != nil {
+			return nil, err
+		}
+
+		if opts.Sticky == StickyOnly {
+			in, args := expandIn("ID", stickyIds)
+			q = q.Where(in, args...)
+		} else if len(stickyIds) > 0 {
+			q = q.Where(sqrl.NotEq{"ID": stickyIds})
+		}
 	}
 
-	if opts.Query != "" {
+	if opts.Query != "" && opts.FullText {
+		q = q.Where(fullTextMatch(c), opts.Query)
+	} else if opts.Query != "" {
 		var pred string
 		var args []interface{}
 		for _, word := range regexpQueryDelimiter.Split(opts.Query, -1) {
@@ -681,96 +1272,42 @@ func queryObjects(c context.Context, opts *ObjectQueryOptions) (Iterator, error)
 	}
 
 	if opts.Day > 0 {
-		q = q.Where(sqrl.Eq{"DAYOFMONTH(post_date)": opts.Day})
+		q = q.Where(sqrl.Eq{datePart(c, "DAY", "post_date"): opts.Day})
 	}
 
 	if opts.Month > 0 {
-		q = q.Where(sqrl.Eq{"MONTH(post_date)": opts.Month})
+		q = q.Where(sqrl.Eq{datePart(c, "MONTH", "post_date"): opts.Month})
 	}
 
 	if opts.Year > 0 {
-		q = q.Where(sqrl.Eq{"YEAR(post_date)": opts.Year})
+		q = q.Where(sqrl.Eq{datePart(c, "YEAR", "post_date"): opts.Year})
 	}
 
 	if !opts.AfterDate.IsZero() {
-		q = q.Where("post_date > ?", opts.AfterDate)
+		q = q.Where("post_date > ?", formatDate(opts.AfterDate))
 	}
 
-	if opts.After != "" {
-		// ignore `q.After` if any errors occur
-		if b, err := base64.URLEncoding.DecodeString(opts.After); err == nil {
+	if !opts.DateBefore.IsZero() {
+		q = q.Where("post_date < ?", formatDate(opts.DateBefore))
+	}
 
-			pred := opts.Order
-			if opts.OrderAscending {
-				pred += ">"
+	if dq := opts.DateQuery; dq != nil {
+		if !dq.After.IsZero() {
+			if dq.AfterInclusive {
+				q = q.Where("post_date >= ?", formatDate(dq.After))
 			} else {
-				pred += "<"
+				q = q.Where("post_date > ?", formatDate(dq.After))
 			}
-
-			pred += " ?"
-
-			q = q.Where(pred, string(b))
 		}
-	}
-
-	order := opts.Order
-	if opts.OrderAscending {
-		order += " ASC"
-	} else {
-		order += " DESC"
-	}
-
-	q = q.OrderBy(order)
-
-	if opts.Limit == 0 {
-		opts.Limit = 10
-	}
 
-	if opts.Limit > 0 {
-		q = q.Limit(uint64(opts.Limit))
-	}
-
-	stmt, args, err := q.ToSql()
-	if err != nil {
-		return nil, err
-	}
-
-	trace.FromContext(c).AddAttributes(trace.StringAttribute("wp/object/query", stmt))
-
-	rows, err := database(c).Query(stmt, args...)
-	if err != nil {
-		return nil, err
-	}
-
-	var ids []int64
-	var cursors []string
-	for rows.Next() {
-		var id int64
-		var cursor string
-		if err = rows.Scan(&id, &cursor); err != nil {
-			return nil, err
-		}
-
-		ids = append(ids, id)
-		cursors = append(cursors, cursor)
-	}
-
-	trace.FromContext(c).AddAttributes(trace.Int64Attribute("wp/object/count", int64(len(ids))))
-
-	it := iteratorImpl{cursor: opts.After}
-
-	var counter int
-	it.next = func() (id int64, err error) {
-		if counter < len(ids) {
-			id = ids[counter]
-			it.cursor = base64.URLEncoding.EncodeToString([]byte(cursors[counter]))
-			counter++
-		} else {
-			return it.exit(Done)
+		if !dq.Before.IsZero() {
+			if dq.BeforeInclusive {
+				q = q.Where("post_date <= ?", formatDate(dq.Before))
+			} else {
+				q = q.Where("post_date < ?", formatDate(dq.Before))
+			}
 		}
-
-		return id, err
 	}
 
-	return &it, nil
+	return q, nil
 }
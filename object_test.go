@@ -0,0 +1,319 @@
+package wordpress
+
+import (
+	"errors"
+	"reflect"
+	"strconv"
+	"testing"
+	"time"
+
+	"golang.org/x/net/context"
+)
+
+func TestQueryPostsInvalidOrder(t *testing.T) {
+	c := newTestContext(t)
+	testPost(t, c, "Hello World", testDate)
+
+	for _, order := range []string{
+		"post_date DESC, (SELECT user_pass FROM wp_users)",
+		"post_date; DROP TABLE wp_posts",
+		"`post_date`",
+		"post date",
+		"post_date -- ",
+		"RAND()",
+		"wp_posts.post_date",
+		"POST_DATE",
+	} {
+		for name, opts := range map[string]*ObjectQueryOptions{
+			"Order":   {Order: order},
+			"OrderBy": {OrderBy: []OrderClause{{Column: "post_title"}, {Column: order}}},
+		} {
+			_, err := QueryPosts(c, opts)
+
+			var invalid *InvalidOrderError
+			if !errors.As(err, &invalid) {
+				t.Errorf("expected %s %q to be rejected, got %v", name, order, err)
+			} else if invalid.Column != order {
+				t.Errorf("expected the error to name %q, got %q", order, invalid.Column)
+			}
+		}
+	}
+
+	// the allowed columns still work
+	it, err := QueryPosts(c, &ObjectQueryOptions{Order: "post_title"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if ids, err := it.Slice(); err != nil || len(ids) != 1 {
+		t.Errorf("expected 1 post, got %v, %v", ids, err)
+	}
+}
+
+func TestQueryPostsIdenticalDates(t *testing.T) {
+	c := newTestContext(t)
+
+	var ids []int64
+	for i := 0; i < 7; i++ {
+		ids = append(ids, testPost(t, c, "Post "+strconv.Itoa(i), testDate))
+	}
+
+	for _, ascending := range []bool{false, true} {
+		var pages [][]int64
+		var prevCursors []string
+		var got []int64
+		after := ""
+		for len(pages) < 10 {
+			it, err := QueryPosts(c, &ObjectQueryOptions{Limit: 3, After: after, OrderAscending: ascending})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			page, err := it.Slice()
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if len(page) == 0 {
+				break
+			}
+
+			pages = append(pages, page)
+			prevCursors = append(prevCursors, it.PrevCursor())
+			got = append(got, page...)
+			after = it.Cursor()
+		}
+
+		// posts with the same date are ordered by id, in the same direction
+		want := make([]int64, len(ids))
+		for i, id := range ids {
+			if ascending {
+				want[i] = id
+			} else {
+				want[len(ids)-1-i] = id
+			}
+		}
+
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("expected every post once in the order %v, got pages %v", want, pages)
+		}
+
+		// the page before the last page is the second page
+		if len(pages) != 3 {
+			t.Fatalf("expected 3 pages, got %v", pages)
+		}
+
+		it, err := QueryPosts(c, &ObjectQueryOptions{Limit: 3, Before: prevCursors[2], OrderAscending: ascending})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if page, err := it.Slice(); err != nil || !reflect.DeepEqual(page, pages[1]) {
+			t.Errorf("expected the page before the last to be %v, got %v, %v", pages[1], page, err)
+		}
+	}
+}
+
+func TestQueryPostsOptionsUnchanged(t *testing.T) {
+	c := newTestContext(t)
+
+	news := testTerm(t, c, TaxonomyCategory, "News", 0)
+	testRelate(t, c, testPost(t, c, "Hello World", testDate), news)
+
+	opts := &ObjectQueryOptions{
+		CategoryName: "news",
+		OrderBy:      []OrderClause{{Column: "post_title", Ascending: true}},
+	}
+
+	want := *opts
+	want.OrderBy = append([]OrderClause(nil), opts.OrderBy...)
+
+	if count, err := CountPosts(c, opts); err != nil || count != 1 {
+		t.Fatalf("expected 1 post, got %d, %v", count, err)
+	}
+
+	it, err := QueryPosts(c, opts)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if ids, err := it.Slice(); err != nil || len(ids) != 1 {
+		t.Errorf("expected 1 post, got %v, %v", ids, err)
+	}
+
+	if !reflect.DeepEqual(*opts, want) {
+		t.Errorf("expected the options to be unchanged, got %+v", *opts)
+	}
+}
+
+func TestQueryPostsInvalidPage(t *testing.T) {
+	c, cancel := context.WithCancel(newTestContext(t))
+
+	// the page is validated before the category hierarchy is loaded from the database
+	cancel()
+
+	_, err := QueryPosts(c, &ObjectQueryOptions{Category: 1, Page: -1})
+	if err == nil || errors.Is(err, context.Canceled) {
+		t.Errorf("expected an invalid page error, got %v", err)
+	}
+}
+
+func TestQueryPostsCategoryNotIn(t *testing.T) {
+	c := newTestContext(t)
+
+	parent := testTerm(t, c, TaxonomyCategory, "Parent", 0)
+	child := testTerm(t, c, TaxonomyCategory, "Child", parent)
+	grandchild := testTerm(t, c, TaxonomyCategory, "Grandchild", child)
+	other := testTerm(t, c, TaxonomyCategory, "Other", 0)
+
+	inParent := testPost(t, c, "In Parent", testDate)
+	testRelate(t, c, inParent, parent)
+
+	inChild := testPost(t, c, "In Child", testDate)
+	testRelate(t, c, inChild, child)
+
+	inGrandchild := testPost(t, c, "In Grandchild", testDate)
+	testRelate(t, c, inGrandchild, grandchild)
+
+	inOther := testPost(t, c, "In Other", testDate)
+	testRelate(t, c, inOther, other)
+
+	inOtherAndChild := testPost(t, c, "In Other And Child", testDate)
+	testRelate(t, c, inOtherAndChild, other, child)
+
+	tests := []struct {
+		name string
+		opts ObjectQueryOptions
+		want []int64
+	}{
+		{
+			name: "parent",
+			opts: ObjectQueryOptions{CategoryNotIn: []int64{parent}},
+			want: []int64{inOther},
+		},
+		{
+			name: "child",
+			opts: ObjectQueryOptions{CategoryNotIn: []int64{child}},
+			want: []int64{inParent, inOther},
+		},
+		{
+			name: "with inclusions",
+			opts: ObjectQueryOptions{CategoryIn: []int64{other}, CategoryNotIn: []int64{child}},
+			want: []int64{inOther},
+		},
+		{
+			name: "several",
+			opts: ObjectQueryOptions{CategoryIn: []int64{child, other}, CategoryNotIn: []int64{grandchild, parent}},
+			want: []int64{inOther},
+		},
+		{
+			name: "several inclusions",
+			opts: ObjectQueryOptions{CategoryIn: []int64{grandchild, other}},
+			want: []int64{inGrandchild, inOther, inOtherAndChild},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.opts.OrderAscending = true
+
+			it, err := QueryPosts(c, &tt.opts)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			ids, err := it.Slice()
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if !reflect.DeepEqual(ids, tt.want) {
+				t.Errorf("expected %v, got %v", tt.want, ids)
+			}
+		})
+	}
+}
+
+func TestQueryPostsSticky(t *testing.T) {
+	c := newTestContext(t)
+
+	var ids []int64
+	for i := 0; i < 4; i++ {
+		ids = append(ids, testPost(t, c, "Post "+strconv.Itoa(i), testDate.AddDate(0, 0, i)))
+	}
+
+	// the oldest posts are stuck to the front
+	testOption(t, c, "sticky_posts", "a:2:{i:0;i:"+strconv.FormatInt(ids[0], 10)+";i:1;i:"+strconv.FormatInt(ids[1], 10)+";}")
+
+	it, err := QueryPosts(c, &ObjectQueryOptions{Sticky: StickyInclude})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := []int64{ids[1], ids[0], ids[3], ids[2]}
+	if got, err := it.Slice(); err != nil || !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %v, got %v, %v", want, got, err)
+	}
+}
+
+func TestQueryPostsDateRange(t *testing.T) {
+	c := newTestContext(t)
+
+	// the dates are stored and compared by their wall clock in the site's time zone
+	loc := time.FixedZone("UTC+09:00", 9*60*60)
+	at := func(hour int) time.Time {
+		return time.Date(2020, 1, 2, hour, 0, 0, 0, loc)
+	}
+
+	var ids []int64
+	for _, hour := range []int{10, 11, 12, 13, 14} {
+		ids = append(ids, testPost(t, c, "Post "+strconv.Itoa(hour), at(hour)))
+	}
+
+	tests := []struct {
+		name string
+		opts ObjectQueryOptions
+		want []int64
+	}{
+		{
+			name: "after",
+			opts: ObjectQueryOptions{AfterDate: at(12)},
+			want: ids[3:],
+		},
+		{
+			name: "before",
+			opts: ObjectQueryOptions{DateBefore: at(12)},
+			want: ids[:2],
+		},
+		{
+			name: "between",
+			opts: ObjectQueryOptions{AfterDate: at(10), DateBefore: at(14)},
+			want: ids[1:4],
+		},
+		{
+			name: "inclusive range",
+			opts: ObjectQueryOptions{AfterDate: at(10), DateQuery: &DateQuery{Before: at(13), BeforeInclusive: true}},
+			want: ids[1:4],
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.opts.OrderAscending = true
+
+			it, err := QueryPosts(c, &tt.opts)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			ids, err := it.Slice()
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if !reflect.DeepEqual(ids, tt.want) {
+				t.Errorf("expected %v, got %v", tt.want, ids)
+			}
+		})
+	}
+}
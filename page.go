@@ -0,0 +1,66 @@
+package wordpress
+
+import (
+	"strings"
+
+	"golang.org/x/net/context"
+)
+
+// GetPagesByTemplate returns the ids of the published pages assigned the given template
+//
+// The template is the file name stored in the page's `_wp_page_template` metadata (i.e. `template-landing.php`).
+func GetPagesByTemplate(c context.Context, template string, opts *ObjectQueryOptions) (Iterator, error) {
+	c, span := startSpan(c, "/wordpress.GetPagesByTemplate")
+	defer span.End()
+
+	spanString(span, "wp/page/template", template)
+
+	opts = opts.clone()
+	opts.PostType = PostTypePage
+	opts.MetaAnd = append(opts.MetaAnd[:len(opts.MetaAnd):len(opts.MetaAnd)], "_wp_page_template="+template)
+
+	if opts.PostStatus == "" && len(opts.PostStatusIn) == 0 {
+		opts.PostStatus = PostStatusPublish
+	}
+
+	return queryObjects(c, opts)
+}
+
+// GetPageByPath gets the published page at the path of slugs (i.e. `parent/child`)
+//
+// Returns `ErrNotFound` if there is no such page.
+func GetPageByPath(c context.Context, path string) (*Post, error) {
+	c, span := startSpan(c, "/wordpress.GetPageByPath")
+	defer span.End()
+
+	spanString(span, "wp/page/path", path)
+
+	var pageId int64
+	for _, slug := range strings.Split(strings.Trim(path, "/"), "/") {
+		parentId := pageId
+
+		it, err := queryObjects(c, &ObjectQueryOptions{
+			PostType:   PostTypePage,
+			PostStatus: PostStatusPublish,
+			Name:       slug,
+			Parent:     &parentId,
+			Limit:      1,
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		if pageId, err = it.Next(); err == Done {
+			return nil, ErrNotFound
+		} else if err != nil {
+			return nil, err
+		}
+	}
+
+	pages, err := GetPosts(c, pageId)
+	if err != nil {
+		return nil, err
+	}
+
+	return pages[0], nil
+}
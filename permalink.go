@@ -0,0 +1,248 @@
+package wordpress
+
+import (
+	"database/sql"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/elgris/sqrl"
+	"golang.org/x/net/context"
+)
+
+// Permalink returns the site-relative link to the post
+//
+// Posts are linked according to the `permalink_structure` option, and pages by their full path of slugs.
+// Sites without a permalink structure keep the `/year/month/slug` links.
+func Permalink(c context.Context, p *Post) (string, error) {
+	c, span := startSpan(c, "/wordpress.Permalink")
+	defer span.End()
+
+	r, err := newPermalinkResolver(c)
+	if err != nil {
+		return "", err
+	}
+
+	return r.link(c, &p.Object, p.CategoryIds)
+}
+
+// permalinkResolver builds object links from the permalink structure
+//
+// The structure is read once, so a single resolver should be used for many links.
+type permalinkResolver struct {
+	structure string
+
+	// the paths of the categories and slugs of the authors that were already looked up
+	categoryPaths map[int64]string
+	authorSlugs   map[int64]string
+}
+
+func newPermalinkResolver(c context.Context) (*permalinkResolver, error) {
+	structure, err := GetOption(c, "permalink_structure")
+	if err != nil && err != sql.ErrNoRows {
+		return nil, err
+	}
+
+	return &permalinkResolver{
+		structure:     structure,
+		categoryPaths: make(map[int64]string),
+		authorSlugs:   make(map[int64]string),
+	}, nil
+}
+
+// link returns the site-relative link to the object
+//
+// The categories of the object are looked up if they are needed but not given.
+func (r *permalinkResolver) link(c context.Context, obj *Object, categoryIds []int64) (string, error) {
+	if obj.Type == PostTypePage {
+		return pageLink(c, obj.Name, int64(obj.ParentId))
+	}
+
+	if r.structure == "" {
+		return fmt.Sprintf("/%d/%d/%s", obj.Date.Year(), obj.Date.Month(), obj.Name), nil
+	}
+
+	replacements := []string{
+		"%year%", strconv.Itoa(obj.Date.Year()),
+		"%monthnum%", fmt.Sprintf("%02d", obj.Date.Month()),
+		"%day%", fmt.Sprintf("%02d", obj.Date.Day()),
+		"%hour%", fmt.Sprintf("%02d", obj.Date.Hour()),
+		"%minute%", fmt.Sprintf("%02d", obj.Date.Minute()),
+		"%second%", fmt.Sprintf("%02d", obj.Date.Second()),
+		"%postname%", obj.Name,
+		"%post_id%", strconv.FormatInt(obj.Id, 10),
+	}
+
+	if strings.Contains(r.structure, "%category%") {
+		category, err := r.categoryPath(c, obj.Id, categoryIds)
+		if err != nil {
+			return "", err
+		}
+
+		replacements = append(replacements, "%category%", category)
+	}
+
+	if strings.Contains(r.structure, "%author%") {
+		author, err := r.authorSlug(c, obj.AuthorId)
+		if err != nil {
+			return "", err
+		}
+
+		replacements = append(replacements, "%author%", author)
+	}
+
+	return strings.NewReplacer(replacements...).Replace(r.structure), nil
+}
+
+// categoryPath returns the slugs of the object's category and its ancestors
+func (r *permalinkResolver) categoryPath(c context.Context, objectId int64, categoryIds []int64) (string, error) {
+	categoryId, err := primaryCategoryId(c, objectId, categoryIds)
+	if err != nil {
+		return "", err
+	}
+
+	if categoryId == 0 {
+		return "uncategorized", nil
+	}
+
+	if path, ok := r.categoryPaths[categoryId]; ok {
+		return path, nil
+	}
+
+	categories, err := GetCategories(c, categoryId)
+	if err != nil {
+		return "", err
+	}
+
+	base, err := termBase(c, TaxonomyCategory)
+	if err != nil {
+		return "", err
+	}
+
+	path := strings.TrimPrefix(categories[0].Link, base+"/")
+
+	r.categoryPaths[categoryId] = path
+
+	return path, nil
+}
+
+// primaryCategoryId returns the id of the category that the object is linked under
+//
+// Like WordPress, the category with the lowest id is used, or the default category if the object has none.
+// The categories of the object are looked up if they are not given.
+// Returns zero if there is no default category either.
+func primaryCategoryId(c context.Context, objectId int64, categoryIds []int64) (int64, error) {
+	if categoryIds == nil {
+		it, err := queryTerms(c, &TermQueryOptions{Taxonomy: TaxonomyCategory, ObjectId: objectId, Limit: -1})
+		if err != nil {
+			return 0, err
+		}
+
+		if categoryIds, err = it.Slice(); err != nil {
+			return 0, err
+		}
+	}
+
+	var categoryId int64
+	for _, id := range categoryIds {
+		if categoryId == 0 || id < categoryId {
+			categoryId = id
+		}
+	}
+
+	if categoryId == 0 {
+		id, err := GetOptionInt(c, "default_category")
+		if err == sql.ErrNoRows {
+			return 0, nil
+		} else if err != nil {
+			return 0, err
+		}
+
+		categoryId = int64(id)
+	}
+
+	return categoryId, nil
+}
+
+// authorSlug returns the slug of the object's author
+func (r *permalinkResolver) authorSlug(c context.Context, authorId int64) (string, error) {
+	if slug, ok := r.authorSlugs[authorId]; ok {
+		return slug, nil
+	}
+
+	users, err := GetUsers(c, authorId)
+	if err != nil {
+		return "", err
+	}
+
+	r.authorSlugs[authorId] = users[0].Slug
+
+	return users[0].Slug, nil
+}
+
+// termBase returns the path that prefixes the links of the taxonomy's terms
+//
+// The bases are read from the `category_base` and `tag_base` options.
+func termBase(c context.Context, taxonomy Taxonomy) (string, error) {
+	name, base := "category_base", "category"
+	if taxonomy == TaxonomyPostTag {
+		name, base = "tag_base", "tag"
+	}
+
+	value, err := GetOption(c, name)
+	if err != nil && err != sql.ErrNoRows {
+		return "", err
+	}
+
+	if value = strings.Trim(value, "/"); value != "" {
+		base = value
+	}
+
+	return "/" + base, nil
+}
+
+// pageLink prepends the slugs of the page's ancestors to its slug
+func pageLink(c context.Context, slug string, parentId int64) (string, error) {
+	url := "/" + slug
+	for parentId != 0 {
+		stmt, args, err := sqrl.Select("post_name", "post_parent").
+			From(table(c, "posts")).
+			Where(sqrl.Eq{"ID": parentId}).ToSql()
+		if err != nil {
+			return "", err
+		}
+
+		if err := database(c).QueryRowContext(c, stmt, args...).Scan(&slug, &parentId); err != nil {
+			return "", fmt.Errorf("wordpress: %v", err)
+		}
+
+		url = "/" + slug + url
+	}
+
+	return url, nil
+}
+
+// AdminEditURL returns the url of the post's editor in wp-admin
+func (p *Post) AdminEditURL(c context.Context) (string, error) {
+	siteUrl, err := GetOption(c, "siteurl")
+	if err != nil {
+		return "", err
+	}
+
+	return strings.TrimRight(siteUrl, "/") + "/wp-admin/post.php?post=" + strconv.FormatInt(p.Id, 10) + "&action=edit", nil
+}
+
+// PreviewURL returns the url of the post's preview on the front-end
+func (p *Post) PreviewURL(c context.Context) (string, error) {
+	home, err := GetOption(c, "home")
+	if err != nil {
+		return "", err
+	}
+
+	param := "p"
+	if p.Type == PostTypePage {
+		param = "page_id"
+	}
+
+	return strings.TrimRight(home, "/") + "/?" + param + "=" + strconv.FormatInt(p.Id, 10) + "&preview=true", nil
+}
@@ -0,0 +1,224 @@
+package wordpress
+
+import (
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestPermalink(t *testing.T) {
+	c := newTestContext(t)
+
+	author, err := testExec(t, c, "INSERT INTO wp_users (user_login, user_nicename) VALUES ('jane', 'jane-doe')").LastInsertId()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	news := testTerm(t, c, TaxonomyCategory, "News", 0)
+	local := testTerm(t, c, TaxonomyCategory, "Local", news)
+	sports := testTerm(t, c, TaxonomyCategory, "Sports", 0)
+
+	date := time.Date(2020, 3, 4, 5, 6, 7, 0, time.UTC)
+
+	categorized := testObject(t, c, map[string]interface{}{
+		"post_name":   "hello-world",
+		"post_date":   formatDate(date),
+		"post_author": author,
+	})
+	testRelate(t, c, categorized, sports, local)
+
+	uncategorized := testObject(t, c, map[string]interface{}{"post_name": "no-category", "post_date": formatDate(date)})
+
+	about := testObject(t, c, map[string]interface{}{"post_name": "about", "post_type": string(PostTypePage)})
+	team := testObject(t, c, map[string]interface{}{"post_name": "team", "post_type": string(PostTypePage), "post_parent": about})
+
+	tests := []struct {
+		name      string
+		structure string
+		options   map[string]string
+		id        int64
+		want      string
+	}{
+		{name: "no structure", id: categorized, want: "/2020/3/hello-world"},
+		{name: "postname", structure: "/%postname%/", id: categorized, want: "/hello-world/"},
+		{name: "date", structure: "/%year%/%monthnum%/%day%/%hour%%minute%%second%/%postname%", id: categorized, want: "/2020/03/04/050607/hello-world"},
+		{name: "post id", structure: "/archives/%post_id%", id: categorized, want: "/archives/" + strconv.FormatInt(categorized, 10)},
+		{name: "author", structure: "/%author%/%postname%/", id: categorized, want: "/jane-doe/hello-world/"},
+		{name: "nested category", structure: "/%category%/%postname%/", id: categorized, want: "/news/local/hello-world/"},
+		{
+			name:      "custom category base",
+			structure: "/%category%/%postname%/",
+			options:   map[string]string{"category_base": "/topics/"},
+			id:        categorized,
+			want:      "/news/local/hello-world/",
+		},
+		{
+			name:      "default category",
+			structure: "/%category%/%postname%/",
+			options:   map[string]string{"default_category": strconv.FormatInt(sports, 10)},
+			id:        uncategorized,
+			want:      "/sports/no-category/",
+		},
+		{name: "no default category", structure: "/%category%/%postname%/", id: uncategorized, want: "/uncategorized/no-category/"},
+		{name: "page", structure: "/%year%/%postname%/", id: about, want: "/about"},
+		{name: "nested page", structure: "/%year%/%postname%/", id: team, want: "/about/team"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			testExec(t, c, "DELETE FROM wp_options")
+			testOption(t, c, "permalink_structure", tt.structure)
+			for name, value := range tt.options {
+				testOption(t, c, name, value)
+			}
+
+			posts, err := GetPosts(c, tt.id)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			// the categories are looked up when they are not given
+			for _, categoryIds := range [][]int64{posts[0].CategoryIds, nil} {
+				p := *posts[0]
+				p.CategoryIds = categoryIds
+
+				if link, err := Permalink(c, &p); err != nil {
+					t.Errorf("unexpected error: %v", err)
+				} else if link != tt.want {
+					t.Errorf("expected %q, got %q", tt.want, link)
+				}
+			}
+		})
+	}
+}
+
+func TestPermalinkDefaultCategoryError(t *testing.T) {
+	c := newTestContext(t)
+
+	testOption(t, c, "permalink_structure", "/%category%/%postname%/")
+	testOption(t, c, "default_category", "none")
+
+	id := testPost(t, c, "Hello World", testDate)
+
+	if link, err := Permalink(c, &Post{Object: Object{Id: id, Name: "hello-world"}}); err == nil {
+		t.Errorf("expected an error for the invalid default category, got %q", link)
+	}
+}
+
+func TestTermLinks(t *testing.T) {
+	tests := []struct {
+		name     string
+		options  map[string]string
+		category string
+		tag      string
+	}{
+		{name: "default bases", category: "/category/news/local", tag: "/tag/go"},
+		{
+			name:     "custom bases",
+			options:  map[string]string{"category_base": "/topics/", "tag_base": "labels"},
+			category: "/topics/news/local",
+			tag:      "/labels/go",
+		},
+		{
+			name:     "empty bases",
+			options:  map[string]string{"category_base": "", "tag_base": "/"},
+			category: "/category/news/local",
+			tag:      "/tag/go",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newTestContext(t)
+			for name, value := range tt.options {
+				testOption(t, c, name, value)
+			}
+
+			news := testTerm(t, c, TaxonomyCategory, "News", 0)
+			local := testTerm(t, c, TaxonomyCategory, "Local", news)
+			tag := testTerm(t, c, TaxonomyPostTag, "Go", 0)
+
+			categories, err := GetCategories(c, local)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if categories[0].Link != tt.category {
+				t.Errorf("expected the category link %q, got %q", tt.category, categories[0].Link)
+			}
+
+			tags, err := GetTags(c, tag)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if tags[0].Link != tt.tag {
+				t.Errorf("expected the tag link %q, got %q", tt.tag, tags[0].Link)
+			}
+
+			// the menus link to the same urls
+			menuId := testTerm(t, c, TaxonomyNavMenu, "Main", 0)
+			itemId := testObject(t, c, map[string]interface{}{"post_type": "nav_menu_item", "menu_order": 1})
+			testMeta(t, c, itemId, map[string]string{
+				"_menu_item_type":             string(MenuItemTypeTaxonomy),
+				"_menu_item_object":           string(TaxonomyCategory),
+				"_menu_item_object_id":        strconv.FormatInt(local, 10),
+				"_menu_item_menu_item_parent": "0",
+			})
+			testRelate(t, c, itemId, menuId)
+
+			items, err := GetMenuItems(c, &ObjectQueryOptions{MenuId: &menuId})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if len(items) != 1 || items[0].Link != tt.category || items[0].Title != "Local" {
+				t.Errorf("expected a menu item linking to %q, got %+v", tt.category, items)
+			}
+		})
+	}
+}
+
+func TestMenuItemLinks(t *testing.T) {
+	c := newTestContext(t)
+	testOption(t, c, "permalink_structure", "/%category%/%postname%/")
+
+	news := testTerm(t, c, TaxonomyCategory, "News", 0)
+	local := testTerm(t, c, TaxonomyCategory, "Local", news)
+
+	post := testPost(t, c, "Hello World", testDate)
+	testRelate(t, c, post, local)
+
+	about := testObject(t, c, map[string]interface{}{"post_name": "about", "post_title": "About", "post_type": string(PostTypePage)})
+	team := testObject(t, c, map[string]interface{}{"post_name": "team", "post_title": "Team", "post_type": string(PostTypePage), "post_parent": about})
+
+	menuId := testTerm(t, c, TaxonomyNavMenu, "Main", 0)
+	for i, target := range []struct {
+		object string
+		id     int64
+	}{{"post", post}, {"page", team}} {
+		itemId := testObject(t, c, map[string]interface{}{"post_type": "nav_menu_item", "menu_order": i + 1})
+		testMeta(t, c, itemId, map[string]string{
+			"_menu_item_type":             string(MenuItemTypePost),
+			"_menu_item_object":           target.object,
+			"_menu_item_object_id":        strconv.FormatInt(target.id, 10),
+			"_menu_item_menu_item_parent": "0",
+		})
+		testRelate(t, c, itemId, menuId)
+	}
+
+	items, err := GetMenuItems(c, &ObjectQueryOptions{MenuId: &menuId})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(items) != 2 {
+		t.Fatalf("expected 2 menu items, got %d", len(items))
+	}
+
+	for i, want := range []string{"/news/local/hello-world/", "/about/team"} {
+		if items[i].Link != want {
+			t.Errorf("expected the link %q, got %q", want, items[i].Link)
+		}
+	}
+}
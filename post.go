@@ -1,9 +1,14 @@
 package wordpress
 
 import (
-	"go.opencensus.io/trace"
+	"database/sql"
+	"fmt"
+	"github.com/elgris/sqrl"
 	"golang.org/x/net/context"
+	"sort"
 	"strconv"
+	"strings"
+	"time"
 )
 
 // Post represents a WordPress post
@@ -21,11 +26,90 @@ type Post struct {
 
 	// The post's metadata
 	Meta map[string]string `json:"meta"`
+
+	// The post's page template slug, only loaded with `WithPostExtras`
+	Template string `json:"template,omitempty"`
+
+	// The user currently editing the post, only loaded with `WithPostExtras`
+	EditLock *EditLock `json:"edit_lock,omitempty"`
+}
+
+// EditLock represents a user's lock on editing a post
+type EditLock struct {
+	// The id of the user holding the lock
+	UserId int64 `json:"user"`
+
+	// The last time the lock was refreshed
+	Time time.Time `json:"time"`
+}
+
+// WithPostExtras returns a derived context in which `GetPosts`
+// also loads the `Template` and `EditLock` fields
+func WithPostExtras(parent context.Context) context.Context {
+	return context.WithValue(parent, postExtrasKey, true)
+}
+
+func postExtras(c context.Context) bool {
+	extras, _ := c.Value(postExtrasKey).(bool)
+	return extras
 }
 
+// GetStickyPostIds returns the ids of the posts that are stuck to the front page
+func GetStickyPostIds(c context.Context) ([]int64, error) {
+	c, span := startSpan(c, "/wordpress.GetStickyPostIds")
+	defer span.End()
+
+	dec, err := GetOptionSerialized(c, "sticky_posts")
+	if err == sql.ErrNoRows {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	arr, ok := dec.(map[interface{}]interface{})
+	if !ok {
+		return nil, nil
+	}
+
+	// keep the order of the serialized array
+	var indexes []int64
+	byIndex := make(map[int64]int64)
+	for key, value := range arr {
+		index, _ := key.(int64)
+
+		var id int64
+		switch v := value.(type) {
+		case int64:
+			id = v
+		case string:
+			id, _ = strconv.ParseInt(v, 10, 64)
+		}
+
+		if id > 0 {
+			indexes = append(indexes, index)
+			byIndex[index] = id
+		}
+	}
+
+	sort.Slice(indexes, func(i, j int) bool { return indexes[i] < indexes[j] })
+
+	ids := make([]int64, len(indexes))
+	for i, index := range indexes {
+		ids[i] = byIndex[index]
+	}
+
+	return ids, nil
+}
+
+// GetPostsConcurrency is the default maximum number of queries
+// run at the same time by `GetPosts` to load the posts' metadata, categories, and tags
+//
+// Use `WithConcurrency` to change the limit for a single context.
+var GetPostsConcurrency = 8
+
 // GetPosts gets all post data from the database
 func GetPosts(c context.Context, postIds ...int64) ([]*Post, error) {
-	c, span := trace.StartSpan(c, "/wordpress.GetPosts")
+	c, span := startSpan(c, "/wordpress.GetPosts")
 	defer span.End()
 
 	if len(postIds) == 0 {
@@ -35,87 +119,836 @@ func GetPosts(c context.Context, postIds ...int64) ([]*Post, error) {
 	ids, idMap := dedupe(postIds)
 
 	objects, err := getObjects(c, ids...)
+	mre, missing := err.(MissingResourcesError)
+	if missing && partialResults(c) {
+		ids = without(ids, mre)
+		objects, err = getObjects(c, ids...)
+	}
 	if err != nil {
 		return nil, err
 	}
 
-	counter := 0
-	done := make(chan error)
+	// the metadata and terms of all of the posts are loaded by one query each
+	var meta map[int64]map[string]string
+	var categoryIds, tagIds map[int64][]int64
+	if err := runLimited(concurrency(c), []func() error{
+		func() (err error) {
+			meta, err = GetObjectsMeta(c, ids)
+			return err
+		},
+		func() (err error) {
+			categoryIds, err = getObjectsTermIds(c, ids, TaxonomyCategory)
+			return err
+		},
+		func() (err error) {
+			tagIds, err = getObjectsTermIds(c, ids, TaxonomyPostTag)
+			return err
+		},
+	}); err != nil {
+		return nil, err
+	}
 
 	ret := make([]*Post, len(postIds))
 	for _, obj := range objects {
-		p := Post{Object: *obj}
-
-		counter++
-		go func() {
-			if meta, err := p.GetMeta(c); err != nil {
-				done <- err
-			} else {
-				if thumbnailId, ok := meta["_thumbnail_id"]; ok {
-					p.FeaturedMediaId, _ = strconv.ParseInt(thumbnailId, 10, 64)
-					delete(meta, "_thumbnail_id")
-				}
+		p := &Post{Object: *obj, CategoryIds: categoryIds[obj.Id], TagIds: tagIds[obj.Id]}
 
-				// clear the internal use metadata
-				for metaKey := range meta {
-					if metaKey[0] == '_' {
-						delete(meta, metaKey)
-					}
-				}
+		postMeta := meta[obj.Id]
+		if thumbnailId, ok := postMeta["_thumbnail_id"]; ok {
+			p.FeaturedMediaId, _ = strconv.ParseInt(thumbnailId, 10, 64)
+			delete(postMeta, "_thumbnail_id")
+		}
 
-				p.Meta = meta
+		if postExtras(c) {
+			p.Template = postMeta["_wp_page_template"]
+			p.EditLock = parseEditLock(postMeta["_edit_lock"])
+		}
 
-				done <- nil
+		// clear the internal use metadata
+		for metaKey := range postMeta {
+			if strings.HasPrefix(metaKey, "_") {
+				delete(postMeta, metaKey)
 			}
-		}()
+		}
 
-		counter++
-		go func() {
-			if it, err := p.GetTaxonomy(c, TaxonomyCategory); err != nil {
-				done <- err
-			} else {
-				p.CategoryIds, err = it.Slice()
-				done <- err
-			}
-		}()
-
-		counter++
-		go func() {
-			if it, err := p.GetTaxonomy(c, TaxonomyPostTag); err != nil {
-				done <- err
-			} else {
-				p.TagIds, err = it.Slice()
-				done <- err
-			}
-		}()
+		p.Meta = postMeta
 
 		// insert into return set
 		for _, index := range idMap[p.Id] {
-			ret[index] = &p
+			ret[index] = p
 		}
 	}
 
-	for ; counter > 0; counter-- {
-		if err := <-done; err != nil {
+	if missing && partialResults(c) {
+		found := make([]*Post, 0, len(ret))
+		for _, p := range ret {
+			if p != nil {
+				found = append(found, p)
+			}
+		}
+
+		if found, err = afterGetPostsFilters.apply(c, found); err != nil {
 			return nil, err
 		}
+
+		return found, mre
 	}
 
-	return ret, nil
+	return afterGetPostsFilters.apply(c, ret)
 }
 
 // QueryPosts returns the ids of the posts that match the query
 func QueryPosts(c context.Context, opts *ObjectQueryOptions) (Iterator, error) {
-	c, span := trace.StartSpan(c, "/wordpress.QueryPosts")
+	c, span := startSpan(c, "/wordpress.QueryPosts")
+	defer span.End()
+
+	opts = opts.clone()
+	setPostDefaults(opts)
+
+	return queryObjects(c, opts)
+}
+
+// hydratedPostsBatchSize is the number of posts loaded at a time by the iterators of `QueryPostsHydrated`
+const hydratedPostsBatchSize = 20
+
+// PostIterator iterates over fully loaded posts
+type PostIterator interface {
+	// Next returns the next post, or `Done` if there are no more posts
+	Next() (*Post, error)
+
+	// Cursor returns the cursor of the last post returned by `Next`
+	Cursor() string
+
+	// PrevCursor returns the cursor of the first post in the result page, like `Iterator.PrevCursor`
+	PrevCursor() string
+
+	// Len returns the number of posts in the result page, including those already read
+	Len() int
+}
+
+// QueryPostsHydrated is like `QueryPosts`, but iterates over the posts instead of their ids
+//
+// The posts are loaded in batches as they are iterated over.
+// Posts that are deleted before they are loaded are skipped.
+func QueryPostsHydrated(c context.Context, opts *ObjectQueryOptions) (PostIterator, error) {
+	c, span := startSpan(c, "/wordpress.QueryPostsHydrated")
+	defer span.End()
+
+	it, err := QueryPosts(c, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	return &postIterator{c: c, ids: it, cursor: it.Cursor()}, nil
+}
+
+type postIterator struct {
+	// the context that the batches are loaded with
+	c context.Context
+
+	ids    Iterator
+	cursor string
+
+	batch   []*Post
+	cursors map[int64]string
+}
+
+func (it *postIterator) Next() (*Post, error) {
+	if len(it.batch) == 0 {
+		if err := it.load(); err != nil {
+			return nil, err
+		}
+	}
+
+	p := it.batch[0]
+	it.batch = it.batch[1:]
+	it.cursor = it.cursors[p.Id]
+
+	return p, nil
+}
+
+// load reads the next batch of ids and loads their posts
+func (it *postIterator) load() error {
+	it.cursors = make(map[int64]string)
+	for len(it.batch) == 0 {
+		var ids []int64
+		for len(ids) < hydratedPostsBatchSize {
+			id, err := it.ids.Next()
+			if err == Done {
+				break
+			} else if err != nil {
+				return err
+			}
+
+			ids = append(ids, id)
+			it.cursors[id] = it.ids.Cursor()
+		}
+
+		if len(ids) == 0 {
+			return Done
+		}
+
+		posts, err := GetPosts(it.c, ids...)
+		if mre, ok := err.(MissingResourcesError); ok {
+			if ids = without(ids, mre); len(ids) == 0 {
+				// the whole batch was deleted, so try the next one
+				continue
+			}
+
+			posts, err = GetPosts(it.c, ids...)
+		}
+		if err != nil {
+			return err
+		}
+
+		it.batch = posts
+	}
+
+	return nil
+}
+
+func (it *postIterator) Cursor() string {
+	return it.cursor
+}
+
+func (it *postIterator) PrevCursor() string {
+	return it.ids.PrevCursor()
+}
+
+func (it *postIterator) Len() int {
+	return it.ids.Len()
+}
+
+// GetPostBySlug gets the published post with the given slug
+//
+// Returns `ErrNotFound` if there is no such post.
+func GetPostBySlug(c context.Context, slug string) (*Post, error) {
+	c, span := startSpan(c, "/wordpress.GetPostBySlug")
 	defer span.End()
 
-	if opts.PostStatus == "" {
+	it, err := QueryPosts(c, &ObjectQueryOptions{Name: slug, Limit: 1})
+	if err != nil {
+		return nil, err
+	}
+
+	id, err := it.Next()
+	if err == Done {
+		return nil, ErrNotFound
+	} else if err != nil {
+		return nil, err
+	}
+
+	posts, err := GetPosts(c, id)
+	if err != nil {
+		return nil, err
+	}
+
+	return posts[0], nil
+}
+
+// RelatedPostsOptions represents the available parameters for finding related posts
+type RelatedPostsOptions struct {
+	// The taxonomies whose shared terms relate posts, categories and tags by default
+	Taxonomies []Taxonomy
+}
+
+// GetRelatedPosts gets the published posts that share the most terms with the given post,
+// ordered by the number of shared terms and then by recency
+func GetRelatedPosts(c context.Context, postId int64, limit int, opts *RelatedPostsOptions) ([]*Post, error) {
+	c, span := startSpan(c, "/wordpress.GetRelatedPosts")
+	defer span.End()
+
+	taxonomies := []string{string(TaxonomyCategory), string(TaxonomyPostTag)}
+	if opts != nil && len(opts.Taxonomies) > 0 {
+		taxonomies = nil
+		for _, taxonomy := range opts.Taxonomies {
+			taxonomies = append(taxonomies, string(taxonomy))
+		}
+	}
+
+	if limit <= 0 {
+		limit = 5
+	}
+
+	// the term taxonomies of the post in the related taxonomies
+	terms := sqrl.Select("r.term_taxonomy_id").
+		From(table(c, "term_relationships") + " AS r").
+		Join(table(c, "term_taxonomy") + " AS tt ON tt.term_taxonomy_id = r.term_taxonomy_id").
+		Where(sqrl.Eq{"r.object_id": postId, "tt.taxonomy": taxonomies})
+	shared := inSubquery{column: "tr.term_taxonomy_id", query: terms}
+
+	stmt, args, err := sqrl.Select("p.ID").
+		From(table(c, "term_relationships")+" AS tr").
+		Join(table(c, "posts")+" AS p ON p.ID = tr.object_id").
+		Where(shared).
+		Where(sqrl.NotEq{"p.ID": postId}).
+		Where(sqrl.Eq{"p.post_status": string(PostStatusPublish), "p.post_type": string(PostTypePost)}).
+		GroupBy("p.ID", "p.post_date").
+		OrderBy("COUNT(*) DESC", "p.post_date DESC", "p.ID DESC").
+		Limit(uint64(limit)).ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	spanString(span, "wp/object/query", stmt)
+
+	rows, err := database(c).QueryContext(c, stmt, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+
+		ids = append(ids, id)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(ids) == 0 {
+		return []*Post{}, nil
+	}
+
+	return GetPosts(c, ids...)
+}
+
+// GetPostsByAuthorSlug is like `GetPostsByAuthor`, but looks the user up by their slug
+// (i.e. `user_nicename`) for author archive urls
+//
+// Returns `ErrNotFound` if there is no user with the slug.
+func GetPostsByAuthorSlug(c context.Context, authorSlug string, opts *ObjectQueryOptions) (Iterator, error) {
+	c, span := startSpan(c, "/wordpress.GetPostsByAuthorSlug")
+	defer span.End()
+
+	it, err := QueryUsers(c, &UserQueryOptions{Slug: authorSlug, Limit: 1})
+	if err != nil {
+		return nil, err
+	}
+
+	authorId, err := it.Next()
+	if err == Done {
+		return nil, ErrNotFound
+	} else if err != nil {
+		return nil, err
+	}
+
+	return GetPostsByAuthor(c, authorId, opts)
+}
+
+// GetPostsByAuthor returns the ids of the posts written by the user
+func GetPostsByAuthor(c context.Context, authorId int64, opts *ObjectQueryOptions) (Iterator, error) {
+	c, span := startSpan(c, "/wordpress.GetPostsByAuthor")
+	defer span.End()
+
+	opts = opts.clone()
+
+	// the author takes precedence over any other author filters
+	opts.Author = authorId
+	opts.AuthorName = ""
+
+	return QueryPosts(c, opts)
+}
+
+// CountPostsByAuthor returns the number of published posts written by the user
+//
+// The count is cached along with the query results if the query cache is enabled.
+func CountPostsByAuthor(c context.Context, authorId int64) (int, error) {
+	c, span := startSpan(c, "/wordpress.CountPostsByAuthor")
+	defer span.End()
+
+	key := queryResultKey(c, "posts", struct{ AuthorPostCount int64 }{authorId})
+	if key != "" {
+		var count int
+		if err := cacheManager(c).Get(c, key, &count); err == nil {
+			return count, nil
+		}
+	}
+
+	opts := &ObjectQueryOptions{Author: authorId}
+	setPostDefaults(opts)
+
+	count, err := countObjects(c, opts)
+	if err != nil {
+		return 0, err
+	}
+
+	if key != "" {
+		if err := cacheManager(c).Set(c, key, count); err != nil {
+			spanString(span, "wp/cache/error", err.Error())
+		}
+	}
+
+	return count, nil
+}
+
+// GetDraftsByAuthor returns the ids of the author's draft, pending, and private posts
+func GetDraftsByAuthor(c context.Context, authorId int64, opts *ObjectQueryOptions) (Iterator, error) {
+	c, span := startSpan(c, "/wordpress.GetDraftsByAuthor")
+	defer span.End()
+
+	opts = opts.clone()
+
+	opts.PostStatus = ""
+	opts.PostStatusIn = []PostStatus{PostStatusDraft, PostStatusPending, PostStatusPrivate}
+
+	// the author takes precedence over any other author filters
+	opts.Author = authorId
+	opts.AuthorName = ""
+
+	return QueryPosts(c, opts)
+}
+
+// GetPostsExcludingCategories returns the ids of the posts that are not in
+// any of the given categories or their descendants
+func GetPostsExcludingCategories(c context.Context, categoryIds []int64, opts *ObjectQueryOptions) (Iterator, error) {
+	c, span := startSpan(c, "/wordpress.GetPostsExcludingCategories")
+	defer span.End()
+
+	opts = opts.clone()
+	opts.CategoryNotIn = append(opts.CategoryNotIn[:len(opts.CategoryNotIn):len(opts.CategoryNotIn)], categoryIds...)
+
+	return QueryPosts(c, opts)
+}
+
+// CountPosts returns the total number of posts that match the query
+//
+// Pagination options such as `After` and `Limit` are ignored
+func CountPosts(c context.Context, opts *ObjectQueryOptions) (int, error) {
+	c, span := startSpan(c, "/wordpress.CountPosts")
+	defer span.End()
+
+	opts = opts.clone()
+	setPostDefaults(opts)
+
+	return countObjects(c, opts)
+}
+
+// GetLatestModified returns the most recent GMT modified time of the posts that match the query
+//
+// The zero time is returned if no posts match. Pagination options such as `After` and `Limit` are ignored
+func GetLatestModified(c context.Context, opts *ObjectQueryOptions) (time.Time, error) {
+	c, span := startSpan(c, "/wordpress.GetLatestModified")
+	defer span.End()
+
+	opts = opts.clone()
+	setPostDefaults(opts)
+
+	return latestModified(c, opts)
+}
+
+// CreatePost inserts the post into the database and returns its new id
+//
+// Unset dates default to now in the site's timezone, the status defaults to draft, and the type defaults to post.
+// The post's metadata, featured media, categories, and tags are saved along with it.
+func CreatePost(c context.Context, p *Post) (int64, error) {
+	c, span := startSpan(c, "/wordpress.CreatePost")
+	defer span.End()
+
+	loc, err := GetTimezone(c)
+	if err != nil {
+		return 0, err
+	}
+
+	setNewPostDefaults(p, loc)
+
+	// options are read before the transaction, which holds on to a connection of its own
+	var home string
+	if p.Guid == "" {
+		if home, err = GetOption(c, "home"); err != nil {
+			return 0, err
+		}
+	}
+
+	err = inTx(c, func(tx *sql.Tx) error {
+		res, err := execTx(c, tx, sqrl.Insert(table(c, "posts")).SetMap(objectColumns(&p.Object)))
+		if err != nil {
+			return err
+		}
+
+		id, err := res.LastInsertId()
+		if err != nil {
+			return err
+		}
+
+		if p.Guid == "" {
+			p.Guid = strings.TrimRight(home, "/") + "/?p=" + strconv.FormatInt(id, 10)
+			if _, err := execTx(c, tx, sqrl.Update(table(c, "posts")).
+				Set("guid", p.Guid).
+				Where(sqrl.Eq{"ID": id})); err != nil {
+				return err
+			}
+		}
+
+		p.Id = id
+
+		return savePostRelations(c, tx, p)
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return p.Id, nil
+}
+
+// UpdatePost saves the changes to an existing post
+//
+// Only the given columns of the post are written, or every column whose field is not the zero value if none are given,
+// so a partly filled `Post` leaves the rest of the stored post untouched. A column must be given to clear it, i.e.
+// `UpdatePost(c, p, "post_excerpt")` with an empty `p.Excerpt`. Columns that don't differ from what is stored are skipped,
+// and the modified dates are set to now in the site's timezone if any column changed.
+//
+// Metadata keys missing from `p.Meta` are deleted. A nil `Meta`, `CategoryIds`, or `TagIds` leaves the stored values untouched.
+// The counts of all of the post's terms are recalculated if its status changed.
+func UpdatePost(c context.Context, p *Post, columns ...string) error {
+	c, span := startSpan(c, "/wordpress.UpdatePost")
+	defer span.End()
+
+	values := objectColumns(&p.Object)
+
+	written := make(map[string]bool, len(values))
+	if len(columns) > 0 {
+		for _, column := range columns {
+			if _, ok := values[column]; !ok {
+				return fmt.Errorf("wordpress: unknown post column %q", column)
+			}
+
+			written[column] = true
+		}
+	} else {
+		zero := objectColumns(&Object{})
+		for column, value := range values {
+			if value != zero[column] {
+				written[column] = true
+			}
+		}
+	}
+
+	loc, err := GetTimezone(c)
+	if err != nil {
+		return err
+	}
+
+	if err := inTx(c, func(tx *sql.Tx) error {
+		// compare against the locked row rather than a possibly stale cached copy
+		old, err := getObjectTx(c, tx, p.Id)
+		if err != nil {
+			return err
+		}
+
+		oldColumns := objectColumns(old)
+		changes := make(map[string]interface{})
+		for column := range written {
+			if column != "post_modified" && column != "post_modified_gmt" && oldColumns[column] != values[column] {
+				changes[column] = values[column]
+			}
+		}
+
+		if len(changes) > 0 {
+			now := time.Now()
+			p.Modified = now.In(loc)
+			p.ModifiedGmt = now.UTC()
+			changes["post_modified"] = formatDate(p.Modified)
+			changes["post_modified_gmt"] = formatDate(p.ModifiedGmt)
+
+			if _, err := execTx(c, tx, sqrl.Update(table(c, "posts")).
+				SetMap(changes).
+				Where(sqrl.Eq{"ID": p.Id})); err != nil {
+				return err
+			}
+		}
+
+		if p.Meta != nil || p.FeaturedMediaId != 0 {
+			oldMeta, err := getMetaTx(c, tx, p.Id)
+			if err != nil {
+				return err
+			}
+
+			if err := updatePostMeta(c, tx, p, oldMeta); err != nil {
+				return err
+			}
+		}
+
+		if p.CategoryIds != nil {
+			if err := setObjectTerms(c, tx, p.Id, TaxonomyCategory, p.CategoryIds); err != nil {
+				return err
+			}
+		}
+
+		if p.TagIds != nil {
+			if err := setObjectTerms(c, tx, p.Id, TaxonomyPostTag, p.TagIds); err != nil {
+				return err
+			}
+		}
+
+		// only published posts are counted
+		if _, ok := changes["post_status"]; ok {
+			ttIds, err := objectTermTaxonomyIdsTx(c, tx, p.Id)
+			if err != nil {
+				return err
+			}
+
+			return updateTermCounts(c, tx, ttIds)
+		}
+
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	return InvalidatePost(c, p.Id)
+}
+
+// updatePostMeta writes the changed metadata and deletes the removed metadata
+//
+// Internal metadata (keys starting with `_`) is never deleted,
+// since it isn't loaded into `Post.Meta` in the first place.
+func updatePostMeta(c context.Context, tx *sql.Tx, p *Post, oldMeta map[string]string) error {
+	changed := make(map[string]string)
+	var removed []string
+
+	if p.Meta != nil {
+		for key, value := range p.Meta {
+			if old, ok := oldMeta[key]; !ok || old != value {
+				changed[key] = value
+			}
+		}
+
+		for key := range oldMeta {
+			if _, ok := p.Meta[key]; !ok && !strings.HasPrefix(key, "_") {
+				removed = append(removed, key)
+			}
+		}
+	}
+
+	if p.FeaturedMediaId != 0 {
+		if thumbnailId := strconv.FormatInt(p.FeaturedMediaId, 10); oldMeta["_thumbnail_id"] != thumbnailId {
+			changed["_thumbnail_id"] = thumbnailId
+		}
+	}
+
+	if len(removed) > 0 {
+		if _, err := execTx(c, tx, sqrl.Delete(table(c, "postmeta")).
+			Where(sqrl.Eq{"post_id": p.Id, "meta_key": removed})); err != nil {
+			return err
+		}
+	}
+
+	return setMetaTx(c, tx, p.Id, changed, nil)
+}
+
+// DeletePost moves the post to the trash, or deletes it along with
+// its metadata and term relationships if permanent is true
+//
+// Like WordPress, the status of a trashed post is kept in its
+// `_wp_trash_meta_status` metadata so that it can be restored with `RestorePost`.
+func DeletePost(c context.Context, id int64, permanent bool) error {
+	c, span := startSpan(c, "/wordpress.DeletePost")
+	defer span.End()
+
+	if err := inTx(c, func(tx *sql.Tx) error {
+		status, err := postStatusTx(c, tx, id)
+		if err != nil {
+			return err
+		}
+
+		ttIds, err := objectTermTaxonomyIdsTx(c, tx, id)
+		if err != nil {
+			return err
+		}
+
+		if permanent {
+			for _, q := range []sqrl.Sqlizer{
+				sqrl.Delete(table(c, "postmeta")).Where(sqrl.Eq{"post_id": id}),
+				sqrl.Delete(table(c, "term_relationships")).Where(sqrl.Eq{"object_id": id}),
+				sqrl.Delete(table(c, "posts")).Where(sqrl.Eq{"ID": id}),
+			} {
+				if _, err := execTx(c, tx, q); err != nil {
+					return err
+				}
+			}
+
+			return updateTermCounts(c, tx, ttIds)
+		}
+
+		if status == PostStatusTrash {
+			return nil
+		}
+
+		if err := setMetaTx(c, tx, id, map[string]string{
+			"_wp_trash_meta_status": string(status),
+			"_wp_trash_meta_time":   strconv.FormatInt(time.Now().Unix(), 10),
+		}, nil); err != nil {
+			return err
+		}
+
+		if _, err := execTx(c, tx, sqrl.Update(table(c, "posts")).
+			Set("post_status", string(PostStatusTrash)).
+			Where(sqrl.Eq{"ID": id})); err != nil {
+			return err
+		}
+
+		return updateTermCounts(c, tx, ttIds)
+	}); err != nil {
+		return err
+	}
+
+	return InvalidatePost(c, id)
+}
+
+// RestorePost moves the post out of the trash, back to the status it had before it was trashed
+func RestorePost(c context.Context, id int64) error {
+	c, span := startSpan(c, "/wordpress.RestorePost")
+	defer span.End()
+
+	if err := inTx(c, func(tx *sql.Tx) error {
+		status, err := postStatusTx(c, tx, id)
+		if err != nil {
+			return err
+		} else if status != PostStatusTrash {
+			return nil
+		}
+
+		stmt, args, err := sqrl.Select("meta_value").
+			From(table(c, "postmeta")).
+			Where(sqrl.Eq{"post_id": id, "meta_key": "_wp_trash_meta_status"}).ToSql()
+		if err != nil {
+			return err
+		}
+
+		// posts trashed without the metadata are restored as drafts
+		var previous string
+		if err := tx.QueryRowContext(c, stmt, args...).Scan(&previous); err == sql.ErrNoRows || previous == "" {
+			previous = string(PostStatusDraft)
+		} else if err != nil {
+			return err
+		}
+
+		if _, err := execTx(c, tx, sqrl.Update(table(c, "posts")).
+			Set("post_status", previous).
+			Where(sqrl.Eq{"ID": id})); err != nil {
+			return err
+		}
+
+		if _, err := execTx(c, tx, sqrl.Delete(table(c, "postmeta")).
+			Where(sqrl.Eq{"post_id": id, "meta_key": []string{"_wp_trash_meta_status", "_wp_trash_meta_time"}})); err != nil {
+			return err
+		}
+
+		ttIds, err := objectTermTaxonomyIdsTx(c, tx, id)
+		if err != nil {
+			return err
+		}
+
+		return updateTermCounts(c, tx, ttIds)
+	}); err != nil {
+		return err
+	}
+
+	return InvalidatePost(c, id)
+}
+
+// postStatusTx returns the status of the post, locking its row for the rest of the transaction
+func postStatusTx(c context.Context, tx *sql.Tx, id int64) (PostStatus, error) {
+	stmt, args, err := sqrl.Select("post_status").
+		From(table(c, "posts")).
+		Where(sqrl.Eq{"ID": id}).
+		Suffix("FOR UPDATE").ToSql()
+	if err != nil {
+		return "", err
+	}
+
+	var status string
+	if err := tx.QueryRowContext(c, stmt, args...).Scan(&status); err == sql.ErrNoRows {
+		return "", MissingResourcesError{id}
+	} else if err != nil {
+		return "", err
+	}
+
+	return PostStatus(status), nil
+}
+
+// setNewPostDefaults sets the unset fields of a new post, with dates in the site's timezone
+func setNewPostDefaults(p *Post, loc *time.Location) {
+	if p.Date.IsZero() {
+		p.Date = time.Now().In(loc)
+	}
+
+	if p.DateGmt.IsZero() {
+		p.DateGmt = p.Date.UTC()
+	}
+
+	if p.Modified.IsZero() {
+		p.Modified = p.Date
+	}
+
+	if p.ModifiedGmt.IsZero() {
+		p.ModifiedGmt = p.DateGmt
+	}
+
+	if p.Status == "" {
+		p.Status = PostStatusDraft
+	}
+
+	if p.Type == "" {
+		p.Type = PostTypePost
+	}
+}
+
+// savePostRelations writes the post's metadata, featured media, and terms
+func savePostRelations(c context.Context, tx *sql.Tx, p *Post) error {
+	meta := make(map[string]string, len(p.Meta)+1)
+	for key, value := range p.Meta {
+		meta[key] = value
+	}
+
+	if p.FeaturedMediaId != 0 {
+		meta["_thumbnail_id"] = strconv.FormatInt(p.FeaturedMediaId, 10)
+	}
+
+	if err := setMetaTx(c, tx, p.Id, meta, nil); err != nil {
+		return err
+	}
+
+	if err := setObjectTerms(c, tx, p.Id, TaxonomyCategory, p.CategoryIds); err != nil {
+		return err
+	}
+
+	return setObjectTerms(c, tx, p.Id, TaxonomyPostTag, p.TagIds)
+}
+
+// parseEditLock parses an `_edit_lock` value of the form `timestamp:user`
+func parseEditLock(value string) *EditLock {
+	parts := strings.SplitN(value, ":", 2)
+	if len(parts) != 2 {
+		return nil
+	}
+
+	timestamp, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil {
+		return nil
+	}
+
+	userId, err := strconv.ParseInt(parts[1], 10, 64)
+	if err != nil {
+		return nil
+	}
+
+	return &EditLock{UserId: userId, Time: time.Unix(timestamp, 0)}
+}
+
+func setPostDefaults(opts *ObjectQueryOptions) {
+	if opts.PostStatus == "" && len(opts.PostStatusIn) == 0 {
 		opts.PostStatus = PostStatusPublish
 	}
 
 	if opts.PostType == "" {
 		opts.PostType = PostTypePost
 	}
-
-	return queryObjects(c, opts)
 }
@@ -0,0 +1,509 @@
+package wordpress
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestSetNewPostDefaultsTimezone(t *testing.T) {
+	loc := time.FixedZone("UTC+09:00", 9*60*60)
+
+	var p Post
+	setNewPostDefaults(&p, loc)
+
+	if p.Date.Location() != loc {
+		t.Errorf("expected the date to be in the site's timezone, got %v", p.Date.Location())
+	}
+
+	if p.DateGmt.Location() != time.UTC {
+		t.Errorf("expected the gmt date to be in UTC, got %v", p.DateGmt.Location())
+	}
+
+	if !p.Date.Equal(p.DateGmt) {
+		t.Errorf("expected both dates to be the same instant, got %v and %v", p.Date, p.DateGmt)
+	}
+
+	// the dates are stored by their wall clocks
+	if got, want := formatDate(p.Date), formatDate(p.DateGmt.Add(9*time.Hour)); got != want {
+		t.Errorf("expected the local date to be stored as %s, got %s", want, got)
+	}
+
+	if p.Status != PostStatusDraft || p.Type != PostTypePost {
+		t.Errorf("expected a draft post, got %s %s", p.Status, p.Type)
+	}
+}
+
+func TestGetPostsConcurrent(t *testing.T) {
+	c := newTestContext(t)
+
+	category := testTerm(t, c, TaxonomyCategory, "News", 0)
+
+	var ids []int64
+	tags := make(map[int64]int64)
+	for i := 0; i < 10; i++ {
+		id := testPost(t, c, "Post "+strconv.Itoa(i), time.Date(2020, 1, i+1, 0, 0, 0, 0, time.UTC))
+		tag := testTerm(t, c, TaxonomyPostTag, "Tag "+strconv.Itoa(i), 0)
+		testRelate(t, c, id, category, tag)
+		testMeta(t, c, id, map[string]string{"index": strconv.Itoa(i)})
+
+		ids = append(ids, id)
+		tags[id] = tag
+	}
+
+	// several calls at once, each of which runs its own queries concurrently
+	var wg sync.WaitGroup
+	errs := make(chan error, 4)
+	for i := 0; i < cap(errs); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			posts, err := GetPosts(WithConcurrency(c, 3), ids...)
+			if err != nil {
+				errs <- err
+				return
+			}
+
+			for i, p := range posts {
+				if p.Id != ids[i] {
+					errs <- fmt.Errorf("expected post %d at %d, got %d", ids[i], i, p.Id)
+				} else if !reflect.DeepEqual(p.CategoryIds, []int64{category}) {
+					errs <- fmt.Errorf("expected post %d to be in category %d, got %v", p.Id, category, p.CategoryIds)
+				} else if !reflect.DeepEqual(p.TagIds, []int64{tags[p.Id]}) {
+					errs <- fmt.Errorf("expected post %d to be tagged %d, got %v", p.Id, tags[p.Id], p.TagIds)
+				} else if p.Meta["index"] != strconv.Itoa(i) {
+					errs <- fmt.Errorf("expected post %d to have index %d, got %q", p.Id, i, p.Meta["index"])
+				}
+			}
+		}()
+	}
+
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		t.Error(err)
+	}
+}
+
+func TestCountPosts(t *testing.T) {
+	c := newTestContext(t)
+
+	news := testTerm(t, c, TaxonomyCategory, "News", 0)
+
+	for i := 0; i < 12; i++ {
+		id := testPost(t, c, "Post "+strconv.Itoa(i), testDate.Add(time.Duration(i)*time.Hour))
+		if i%3 == 0 {
+			testRelate(t, c, id, news)
+		}
+	}
+
+	tests := []struct {
+		name string
+		opts ObjectQueryOptions
+		want int
+	}{
+		{
+			name: "pagination is ignored",
+			opts: ObjectQueryOptions{Limit: 5},
+			want: 12,
+		},
+		{
+			name: "category",
+			opts: ObjectQueryOptions{CategoryIn: []int64{news}, Limit: 2},
+			want: 4,
+		},
+		{
+			name: "excluded category",
+			opts: ObjectQueryOptions{CategoryNotIn: []int64{news}},
+			want: 8,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if count, err := CountPosts(c, &tt.opts); err != nil || count != tt.want {
+				t.Errorf("expected %d, got %d, %v", tt.want, count, err)
+			}
+		})
+	}
+}
+
+func TestGetPostsByAuthorSlug(t *testing.T) {
+	c := newTestContext(t)
+
+	testExec(t, c, "INSERT INTO wp_users (ID, user_login, user_nicename) VALUES (1, 'alice', 'alice'), (2, 'bob', 'bob')")
+
+	alices := testObject(t, c, map[string]interface{}{"post_title": "Alice's", "post_author": 1})
+	testObject(t, c, map[string]interface{}{"post_title": "Bob's", "post_author": 2})
+
+	it, err := GetPostsByAuthorSlug(c, "alice", &ObjectQueryOptions{Author: 2})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if ids, err := it.Slice(); err != nil || !reflect.DeepEqual(ids, []int64{alices}) {
+		t.Errorf("expected only alice's post %d, got %v, %v", alices, ids, err)
+	}
+
+	if _, err := GetPostsByAuthorSlug(c, "carol", nil); err != ErrNotFound {
+		t.Errorf("expected ErrNotFound for an unknown author, got %v", err)
+	}
+}
+
+func TestGetLatestModified(t *testing.T) {
+	c := newTestContext(t)
+
+	news := testTerm(t, c, TaxonomyCategory, "News", 0)
+
+	modified := func(title string, date time.Time, status PostStatus) int64 {
+		return testObject(t, c, map[string]interface{}{
+			"post_title":        title,
+			"post_status":       string(status),
+			"post_modified":     date.Format("2006-01-02 15:04:05"),
+			"post_modified_gmt": date.Format("2006-01-02 15:04:05"),
+		})
+	}
+
+	testRelate(t, c, modified("Old News", testDate, PostStatusPublish), news)
+	testRelate(t, c, modified("New News", testDate.Add(time.Hour), PostStatusPublish), news)
+	modified("Other", testDate.Add(2*time.Hour), PostStatusPublish)
+	testRelate(t, c, modified("Draft News", testDate.Add(3*time.Hour), PostStatusDraft), news)
+
+	tests := []struct {
+		name string
+		opts ObjectQueryOptions
+		want time.Time
+	}{
+		{
+			name: "all published",
+			want: testDate.Add(2 * time.Hour),
+		},
+		{
+			name: "category",
+			opts: ObjectQueryOptions{CategoryIn: []int64{news}},
+			want: testDate.Add(time.Hour),
+		},
+		{
+			name: "pagination is ignored",
+			opts: ObjectQueryOptions{CategoryIn: []int64{news}, Limit: 1, After: "not a cursor"},
+			want: testDate.Add(time.Hour),
+		},
+		{
+			name: "no matches",
+			opts: ObjectQueryOptions{Name: "missing"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got, err := GetLatestModified(c, &tt.opts); err != nil || !got.Equal(tt.want) {
+				t.Errorf("expected %v, got %v, %v", tt.want, got, err)
+			}
+		})
+	}
+}
+
+func TestCreatePost(t *testing.T) {
+	c := newTestContext(t)
+
+	testOption(t, c, "home", "https://example.com/")
+
+	category := testTerm(t, c, TaxonomyCategory, "News", 0)
+	tag := testTerm(t, c, TaxonomyPostTag, "Go", 0)
+
+	p := &Post{
+		Object: Object{
+			Title:  "Hello World",
+			Name:   "hello-world",
+			Status: PostStatusPublish,
+			Date:   testDate,
+		},
+		FeaturedMediaId: 42,
+		CategoryIds:     []int64{category},
+		TagIds:          []int64{tag, tag},
+		Meta:            map[string]string{"subtitle": "Greetings"},
+	}
+
+	id, err := CreatePost(c, p)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if id == 0 || p.Id != id {
+		t.Fatalf("expected the post to get its new id, got %d and %d", id, p.Id)
+	}
+
+	if want := "https://example.com/?p=" + strconv.FormatInt(id, 10); p.Guid != want {
+		t.Errorf("expected the guid %q, got %q", want, p.Guid)
+	}
+
+	posts, err := GetPosts(c, id)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	got := posts[0]
+	if got.Title != p.Title || got.Name != p.Name || got.Status != PostStatusPublish || got.Type != PostTypePost || got.Guid != p.Guid {
+		t.Errorf("expected the stored post to match, got %q %q %s %s %q", got.Title, got.Name, got.Status, got.Type, got.Guid)
+	}
+
+	if !got.Date.Equal(testDate) || !got.Modified.Equal(testDate) {
+		t.Errorf("expected the dates to be %v, got %v and %v", testDate, got.Date, got.Modified)
+	}
+
+	if got.FeaturedMediaId != 42 || got.Meta["subtitle"] != "Greetings" {
+		t.Errorf("expected the metadata to be saved, got %d and %v", got.FeaturedMediaId, got.Meta)
+	}
+
+	if !reflect.DeepEqual(got.CategoryIds, []int64{category}) || !reflect.DeepEqual(got.TagIds, []int64{tag}) {
+		t.Errorf("expected the terms %d and %d, got %v and %v", category, tag, got.CategoryIds, got.TagIds)
+	}
+
+	if n := testTermCount(t, c, category); n != 1 {
+		t.Errorf("expected the category to count the post once, got %d", n)
+	}
+
+	if n := testTermCount(t, c, tag); n != 1 {
+		t.Errorf("expected the tag to count the post once, got %d", n)
+	}
+
+	// drafts are not counted
+	if _, err := CreatePost(c, &Post{Object: Object{Title: "Draft"}, CategoryIds: []int64{category}}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if n := testTermCount(t, c, category); n != 1 {
+		t.Errorf("expected the draft not to be counted, got %d", n)
+	}
+
+	// nothing is written for missing terms
+	_, err = CreatePost(c, &Post{Object: Object{Title: "Missing"}, CategoryIds: []int64{category + 100}})
+	if mre, ok := err.(MissingResourcesError); !ok || !reflect.DeepEqual(mre, MissingResourcesError{category + 100}) {
+		t.Fatalf("expected a missing resources error, got %v", err)
+	}
+
+	if n, err := CountPosts(c, &ObjectQueryOptions{PostStatusIn: []PostStatus{PostStatusPublish, PostStatusDraft}}); err != nil || n != 2 {
+		t.Errorf("expected 2 posts, got %d, %v", n, err)
+	}
+}
+
+func TestUpdatePost(t *testing.T) {
+	c := newTestContext(t)
+
+	news := testTerm(t, c, TaxonomyCategory, "News", 0)
+	sports := testTerm(t, c, TaxonomyCategory, "Sports", 0)
+
+	id := testObject(t, c, map[string]interface{}{
+		"post_title":        "Hello World",
+		"post_content":      "Content",
+		"post_excerpt":      "Excerpt",
+		"post_status":       string(PostStatusDraft),
+		"post_date":         formatDate(testDate),
+		"post_date_gmt":     formatDate(testDate),
+		"post_modified":     formatDate(testDate),
+		"post_modified_gmt": formatDate(testDate),
+	})
+	testRelate(t, c, id, news)
+	testExec(t, c, "UPDATE wp_term_taxonomy SET count = 0")
+	testMeta(t, c, id, map[string]string{"kept": "1", "changed": "old", "removed": "1", "_edit_last": "1"})
+
+	get := func() *Post {
+		t.Helper()
+
+		posts, err := GetPosts(c, id)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		return posts[0]
+	}
+
+	// nothing changed, so nothing is written
+	if err := UpdatePost(c, &Post{Object: Object{Id: id, Title: "Hello World"}}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if p := get(); !p.Modified.Equal(testDate) {
+		t.Errorf("expected the modified date to be left as %v, got %v", testDate, p.Modified)
+	}
+
+	// only the set fields are written
+	if err := UpdatePost(c, &Post{
+		Object:      Object{Id: id, Title: "Goodbye World", Status: PostStatusPublish},
+		CategoryIds: []int64{sports},
+		Meta:        map[string]string{"kept": "1", "changed": "new", "added": "1"},
+	}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	p := get()
+	if p.Title != "Goodbye World" || p.Status != PostStatusPublish {
+		t.Errorf("expected the title and status to be updated, got %q %s", p.Title, p.Status)
+	}
+
+	if p.Content != "Content" || p.Excerpt != "Excerpt" || !p.Date.Equal(testDate) || p.Type != PostTypePost {
+		t.Errorf("expected the unset fields to be left untouched, got %q %q %v %q", p.Content, p.Excerpt, p.Date, p.Type)
+	}
+
+	if !p.Modified.After(testDate) {
+		t.Errorf("expected the modified date to be refreshed, got %v", p.Modified)
+	}
+
+	if want := map[string]string{"kept": "1", "changed": "new", "added": "1"}; !reflect.DeepEqual(p.Meta, want) {
+		t.Errorf("expected the metadata %v, got %v", want, p.Meta)
+	}
+
+	if !reflect.DeepEqual(p.CategoryIds, []int64{sports}) {
+		t.Errorf("expected the category %d, got %v", sports, p.CategoryIds)
+	}
+
+	var internal int
+	if err := database(c).QueryRowContext(c, "SELECT COUNT(*) FROM wp_postmeta WHERE post_id = ? AND meta_key = '_edit_last'", id).Scan(&internal); err != nil || internal != 1 {
+		t.Errorf("expected the internal metadata to be kept, got %d, %v", internal, err)
+	}
+
+	if n := testTermCount(t, c, news); n != 0 {
+		t.Errorf("expected the removed category to have no posts, got %d", n)
+	}
+
+	if n := testTermCount(t, c, sports); n != 1 {
+		t.Errorf("expected the added category to count the published post, got %d", n)
+	}
+
+	// changing the status alone recounts the terms
+	if err := UpdatePost(c, &Post{Object: Object{Id: id, Status: PostStatusPrivate}}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if n := testTermCount(t, c, sports); n != 0 {
+		t.Errorf("expected the private post not to be counted, got %d", n)
+	}
+
+	// named columns are written even when empty
+	if err := UpdatePost(c, &Post{Object: Object{Id: id, Title: "Ignored"}}, "post_excerpt"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if p := get(); p.Excerpt != "" || p.Title != "Goodbye World" {
+		t.Errorf("expected only the excerpt to be cleared, got %q %q", p.Excerpt, p.Title)
+	}
+
+	if err := UpdatePost(c, &Post{Object: Object{Id: id}}, "ID"); err == nil {
+		t.Error("expected an error for an unknown column")
+	}
+
+	err := UpdatePost(c, &Post{Object: Object{Id: id + 100, Title: "Missing"}})
+	if mre, ok := err.(MissingResourcesError); !ok || !reflect.DeepEqual(mre, MissingResourcesError{id + 100}) {
+		t.Errorf("expected a missing resources error, got %v", err)
+	}
+}
+
+func TestDeleteAndRestorePost(t *testing.T) {
+	c := newTestContext(t)
+
+	category := testTerm(t, c, TaxonomyCategory, "News", 0)
+
+	id := testPost(t, c, "Hello World", testDate)
+	testRelate(t, c, id, category)
+	testMeta(t, c, id, map[string]string{"subtitle": "Greetings"})
+
+	status := func(id int64) PostStatus {
+		t.Helper()
+
+		var status string
+		if err := database(c).QueryRowContext(c, "SELECT post_status FROM wp_posts WHERE ID = ?", id).Scan(&status); err != nil {
+			t.Fatal(err)
+		}
+
+		return PostStatus(status)
+	}
+
+	if err := DeletePost(c, id, false); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if s := status(id); s != PostStatusTrash {
+		t.Errorf("expected the post to be trashed, got %s", s)
+	}
+
+	if n := testTermCount(t, c, category); n != 0 {
+		t.Errorf("expected the trashed post not to be counted, got %d", n)
+	}
+
+	// trashing again keeps the original status
+	if err := DeletePost(c, id, false); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if err := RestorePost(c, id); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if s := status(id); s != PostStatusPublish {
+		t.Errorf("expected the post to be published again, got %s", s)
+	}
+
+	if n := testTermCount(t, c, category); n != 1 {
+		t.Errorf("expected the restored post to be counted, got %d", n)
+	}
+
+	var trashMeta int
+	if err := database(c).QueryRowContext(c, "SELECT COUNT(*) FROM wp_postmeta WHERE post_id = ? AND meta_key LIKE '_wp_trash_meta_%'", id).Scan(&trashMeta); err != nil || trashMeta != 0 {
+		t.Errorf("expected the trash metadata to be deleted, got %d, %v", trashMeta, err)
+	}
+
+	// posts trashed without the metadata are restored as drafts
+	trashed := testObject(t, c, map[string]interface{}{"post_status": string(PostStatusTrash)})
+	if err := RestorePost(c, trashed); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if s := status(trashed); s != PostStatusDraft {
+		t.Errorf("expected the post to be restored as a draft, got %s", s)
+	}
+
+	if err := DeletePost(c, id, true); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	for _, table := range []string{"wp_posts WHERE ID = ?", "wp_postmeta WHERE post_id = ?", "wp_term_relationships WHERE object_id = ?"} {
+		var n int
+		if err := database(c).QueryRowContext(c, "SELECT COUNT(*) FROM "+table, id).Scan(&n); err != nil || n != 0 {
+			t.Errorf("expected no rows left in %s, got %d, %v", table, n, err)
+		}
+	}
+
+	if n := testTermCount(t, c, category); n != 0 {
+		t.Errorf("expected the deleted post not to be counted, got %d", n)
+	}
+
+	for name, err := range map[string]error{
+		"DeletePost":  DeletePost(c, id, false),
+		"RestorePost": RestorePost(c, id),
+	} {
+		if mre, ok := err.(MissingResourcesError); !ok || !reflect.DeepEqual(mre, MissingResourcesError{id}) {
+			t.Errorf("expected %s to fail with a missing resources error, got %v", name, err)
+		}
+	}
+}
+
+func TestGetPostsEmptyMetaKey(t *testing.T) {
+	c := newTestContext(t)
+
+	id := testPost(t, c, "Hello World", testDate)
+	testMeta(t, c, id, map[string]string{"": "empty", "_internal": "1", "subtitle": "Greetings"})
+
+	posts, err := GetPosts(c, id)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if want := map[string]string{"": "empty", "subtitle": "Greetings"}; !reflect.DeepEqual(posts[0].Meta, want) {
+		t.Errorf("expected only the internal metadata to be cleared, got %v", posts[0].Meta)
+	}
+}
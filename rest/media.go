@@ -0,0 +1,162 @@
+package rest
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/ssttevee/go-wordpress"
+	"golang.org/x/net/context"
+)
+
+type mediaDetails struct {
+	Width  int    `json:"width,omitempty"`
+	Height int    `json:"height,omitempty"`
+	File   string `json:"file"`
+}
+
+type media struct {
+	Id           int64        `json:"id"`
+	Date         string       `json:"date"`
+	DateGmt      string       `json:"date_gmt"`
+	Modified     string       `json:"modified"`
+	ModifiedGmt  string       `json:"modified_gmt"`
+	Slug         string       `json:"slug"`
+	Type         string       `json:"type"`
+	Title        rendered     `json:"title"`
+	Author       int64        `json:"author"`
+	Caption      rendered     `json:"caption"`
+	AltText      string       `json:"alt_text"`
+	MediaType    string       `json:"media_type"`
+	MimeType     string       `json:"mime_type"`
+	MediaDetails mediaDetails `json:"media_details"`
+	Post         int64        `json:"post"`
+	SourceUrl    string       `json:"source_url"`
+}
+
+// MediaHandler returns a handler that serves attachments
+//
+// Supported query parameters are `page`, `per_page`, `search`, `author`,
+// `include`, `exclude`, `parent`, `slug`, `orderby`, and `order`
+func MediaHandler(wp *wordpress.WordPress) http.Handler {
+	return handlerFunc(serveMedia).handler(wp)
+}
+
+func serveMedia(c context.Context, w http.ResponseWriter, r *http.Request, id int64) (interface{}, error) {
+	if id != 0 {
+		it, err := wordpress.QueryAttachments(c, &wordpress.ObjectQueryOptions{Post: id, Limit: 1})
+		if err != nil {
+			return nil, err
+		}
+
+		if _, err := it.Next(); err == wordpress.Done {
+			return nil, notFound("rest_post_invalid_id")
+		} else if err != nil {
+			return nil, err
+		}
+
+		attachments, err := getMedia(c, []int64{id})
+		if err != nil {
+			return nil, err
+		}
+
+		return attachments[0], nil
+	}
+
+	page, perPage, err := pagination(r)
+	if err != nil {
+		return nil, err
+	}
+
+	opts, err := mediaQueryOptions(r)
+	if err != nil {
+		return nil, err
+	}
+
+	total, err := wordpress.CountAttachments(c, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	if pastLastPage(page, perPage, total) {
+		return nil, invalidPage()
+	}
+
+	opts.Limit = perPage
+	opts.Page = page
+
+	it, err := wordpress.QueryAttachments(c, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	ids, err := it.Slice()
+	if err != nil {
+		return nil, err
+	}
+
+	setTotals(w, total, perPage)
+
+	return getMedia(c, ids)
+}
+
+// mediaQueryOptions translates the request's query parameters
+func mediaQueryOptions(r *http.Request) (*wordpress.ObjectQueryOptions, error) {
+	opts, err := postQueryOptions(r)
+	if err != nil {
+		return nil, err
+	}
+
+	// categories and tags don't apply to attachments
+	opts.CategoryIn, opts.CategoryNotIn = nil, nil
+	opts.TagIdIn, opts.TagIdNotIn = nil, nil
+
+	if opts.ParentIn, err = idsParam(r, "parent"); err != nil {
+		return nil, err
+	}
+
+	return opts, nil
+}
+
+func getMedia(c context.Context, ids []int64) ([]*media, error) {
+	attachments, err := wordpress.GetAttachments(c, ids...)
+	if err != nil {
+		return nil, err
+	}
+
+	ret := make([]*media, 0, len(attachments))
+	for _, att := range attachments {
+		if att != nil {
+			ret = append(ret, newMedia(att))
+		}
+	}
+
+	return ret, nil
+}
+
+func newMedia(att *wordpress.Attachment) *media {
+	mediaType := "file"
+	if strings.HasPrefix(att.MimeType, "image/") {
+		mediaType = "image"
+	}
+
+	return &media{
+		Id:          att.Id,
+		Date:        formatDate(att.Date),
+		DateGmt:     formatDate(att.DateGmt),
+		Modified:    formatDate(att.Modified),
+		ModifiedGmt: formatDate(att.ModifiedGmt),
+		Slug:        att.Name,
+		Type:        "attachment",
+		Title:       rendered{Rendered: att.Title},
+		Author:      att.AuthorId,
+		Caption:     rendered{Rendered: att.Caption},
+		AltText:     att.AltText,
+		MediaType:   mediaType,
+		MimeType:    att.MimeType,
+		MediaDetails: mediaDetails{
+			Width:  att.Width,
+			Height: att.Height,
+			File:   att.FileName},
+		Post:      int64(att.ParentId),
+		SourceUrl: att.Url}
+}
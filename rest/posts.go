@@ -0,0 +1,369 @@
+package rest
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ssttevee/go-wordpress"
+	"golang.org/x/net/context"
+)
+
+const dateFormat = "2006-01-02T15:04:05"
+
+var postOrderColumns = map[string]string{
+	"date":     "post_date",
+	"modified": "post_modified",
+	"title":    "post_title",
+	"slug":     "post_name",
+	"id":       "ID",
+}
+
+type rendered struct {
+	Rendered  string `json:"rendered"`
+	Protected bool   `json:"protected,omitempty"`
+}
+
+type post struct {
+	Id            int64             `json:"id"`
+	Date          string            `json:"date"`
+	DateGmt       string            `json:"date_gmt"`
+	Guid          rendered          `json:"guid"`
+	Modified      string            `json:"modified"`
+	ModifiedGmt   string            `json:"modified_gmt"`
+	Slug          string            `json:"slug"`
+	Status        string            `json:"status"`
+	Type          string            `json:"type"`
+	Title         rendered          `json:"title"`
+	Content       rendered          `json:"content"`
+	Excerpt       rendered          `json:"excerpt"`
+	Author        int64             `json:"author"`
+	FeaturedMedia int64             `json:"featured_media"`
+	CommentStatus string            `json:"comment_status"`
+	PingStatus    string            `json:"ping_status"`
+	Meta          map[string]string `json:"meta"`
+	Categories    []int64           `json:"categories"`
+	Tags          []int64           `json:"tags"`
+
+	Embedded map[string]interface{} `json:"_embedded,omitempty"`
+}
+
+type user struct {
+	Id          int64             `json:"id"`
+	Name        string            `json:"name"`
+	Url         string            `json:"url"`
+	Description string            `json:"description"`
+	Slug        string            `json:"slug"`
+	AvatarUrls  map[string]string `json:"avatar_urls"`
+}
+
+// PostsHandler returns a handler that serves published posts
+//
+// Supported query parameters are `page`, `per_page`, `search`, `author`,
+// `author_exclude`, `categories`, `categories_exclude`, `tags`, `tags_exclude`,
+// `include`, `exclude`, `slug`, `orderby`, `order`, and `_embed`
+func PostsHandler(wp *wordpress.WordPress) http.Handler {
+	return handlerFunc(servePosts).handler(wp)
+}
+
+func servePosts(c context.Context, w http.ResponseWriter, r *http.Request, id int64) (interface{}, error) {
+	_, embed := r.URL.Query()["_embed"]
+
+	if id != 0 {
+		it, err := wordpress.QueryPosts(c, &wordpress.ObjectQueryOptions{Post: id, Limit: 1})
+		if err != nil {
+			return nil, err
+		}
+
+		if _, err := it.Next(); err == wordpress.Done {
+			return nil, notFound("rest_post_invalid_id")
+		} else if err != nil {
+			return nil, err
+		}
+
+		posts, err := getPosts(c, []int64{id}, embed)
+		if err != nil {
+			return nil, err
+		}
+
+		return posts[0], nil
+	}
+
+	page, perPage, err := pagination(r)
+	if err != nil {
+		return nil, err
+	}
+
+	opts, err := postQueryOptions(r)
+	if err != nil {
+		return nil, err
+	}
+
+	total, err := wordpress.CountPosts(c, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	if pastLastPage(page, perPage, total) {
+		return nil, invalidPage()
+	}
+
+	opts.Limit = perPage
+	opts.Page = page
+
+	it, err := wordpress.QueryPosts(c, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	ids, err := it.Slice()
+	if err != nil {
+		return nil, err
+	}
+
+	setTotals(w, total, perPage)
+
+	return getPosts(c, ids, embed)
+}
+
+// postQueryOptions translates the request's query parameters
+func postQueryOptions(r *http.Request) (*wordpress.ObjectQueryOptions, error) {
+	opts := &wordpress.ObjectQueryOptions{
+		Query:  r.URL.Query().Get("search"),
+		NameIn: stringsParam(r, "slug")}
+
+	if orderBy := r.URL.Query().Get("orderby"); orderBy != "" {
+		column, ok := postOrderColumns[orderBy]
+		if !ok {
+			return nil, invalidParam("orderby")
+		}
+
+		opts.Order = column
+	}
+
+	switch strings.ToLower(r.URL.Query().Get("order")) {
+	case "", "desc":
+	case "asc":
+		opts.OrderAscending = true
+	default:
+		return nil, invalidParam("order")
+	}
+
+	for name, dst := range map[string]*[]int64{
+		"author":             &opts.AuthorIn,
+		"author_exclude":     &opts.AuthorNotIn,
+		"categories":         &opts.CategoryIn,
+		"categories_exclude": &opts.CategoryNotIn,
+		"tags":               &opts.TagIdIn,
+		"tags_exclude":       &opts.TagIdNotIn,
+		"include":            &opts.PostIn,
+		"exclude":            &opts.PostNotIn,
+	} {
+		ids, err := idsParam(r, name)
+		if err != nil {
+			return nil, err
+		}
+
+		*dst = ids
+	}
+
+	return opts, nil
+}
+
+func getPosts(c context.Context, ids []int64, embed bool) ([]*post, error) {
+	posts, err := wordpress.GetPosts(c, ids...)
+	if err != nil {
+		return nil, err
+	}
+
+	ret := make([]*post, 0, len(posts))
+	for _, p := range posts {
+		if p == nil {
+			continue
+		}
+
+		ret = append(ret, newPost(p))
+	}
+
+	if embed {
+		if err := embedPosts(c, posts, ret); err != nil {
+			return nil, err
+		}
+	}
+
+	return ret, nil
+}
+
+func newPost(p *wordpress.Post) *post {
+	ret := &post{
+		Id:            p.Id,
+		Date:          formatDate(p.Date),
+		DateGmt:       formatDate(p.DateGmt),
+		Guid:          rendered{Rendered: p.Guid},
+		Modified:      formatDate(p.Modified),
+		ModifiedGmt:   formatDate(p.ModifiedGmt),
+		Slug:          p.Name,
+		Status:        string(wordpress.PostStatusPublish),
+		Type:          string(p.Type),
+		Title:         rendered{Rendered: p.Title},
+		Content:       rendered{Rendered: p.Content},
+		Excerpt:       rendered{Rendered: p.Excerpt},
+		Author:        p.AuthorId,
+		FeaturedMedia: p.FeaturedMediaId,
+		CommentStatus: openClosed(p.CommentStatus),
+		PingStatus:    openClosed(p.PingStatus),
+		Meta:          p.Meta,
+		Categories:    p.CategoryIds,
+		Tags:          p.TagIds}
+
+	if ret.Categories == nil {
+		ret.Categories = []int64{}
+	}
+
+	if ret.Tags == nil {
+		ret.Tags = []int64{}
+	}
+
+	if p.Password != "" {
+		ret.Content = rendered{Protected: true}
+		ret.Excerpt = rendered{Protected: true}
+	}
+
+	return ret
+}
+
+// embedPosts loads the authors, featured media, and terms of all the posts at once
+func embedPosts(c context.Context, posts []*wordpress.Post, out []*post) error {
+	var userIds, mediaIds, categoryIds, tagIds []int64
+	for _, p := range posts {
+		if p == nil {
+			continue
+		}
+
+		userIds = append(userIds, p.AuthorId)
+		if p.FeaturedMediaId > 0 {
+			mediaIds = append(mediaIds, p.FeaturedMediaId)
+		}
+
+		categoryIds = append(categoryIds, p.CategoryIds...)
+		tagIds = append(tagIds, p.TagIds...)
+	}
+
+	// resources that no longer exist are left out of the embeds
+	var users []*wordpress.User
+	if err := loadExisting(userIds, func(ids []int64) (err error) {
+		users, err = wordpress.GetUsers(c, ids...)
+		return
+	}); err != nil {
+		return err
+	}
+
+	var attachments []*wordpress.Attachment
+	if err := loadExisting(mediaIds, func(ids []int64) (err error) {
+		attachments, err = wordpress.GetAttachments(c, ids...)
+		return
+	}); err != nil {
+		return err
+	}
+
+	var categories []*wordpress.Category
+	if err := loadExisting(categoryIds, func(ids []int64) (err error) {
+		categories, err = wordpress.GetCategories(c, ids...)
+		return
+	}); err != nil {
+		return err
+	}
+
+	var tags []*wordpress.Tag
+	if err := loadExisting(tagIds, func(ids []int64) (err error) {
+		tags, err = wordpress.GetTags(c, ids...)
+		return
+	}); err != nil {
+		return err
+	}
+
+	usersById := make(map[int64]*user)
+	for _, u := range users {
+		if u != nil {
+			usersById[u.Id] = newUser(u)
+		}
+	}
+
+	mediaById := make(map[int64]*media)
+	for _, att := range attachments {
+		if att != nil {
+			mediaById[att.Id] = newMedia(att)
+		}
+	}
+
+	termsById := make(map[int64]*term)
+	for _, cat := range categories {
+		if cat != nil {
+			termsById[cat.Id] = newTerm(&cat.Term, cat.Link)
+		}
+	}
+
+	for _, tag := range tags {
+		if tag != nil {
+			termsById[tag.Id] = newTerm(&tag.Term, tag.Link)
+		}
+	}
+
+	for _, p := range out {
+		p.Embedded = make(map[string]interface{})
+
+		if u, ok := usersById[p.Author]; ok {
+			p.Embedded["author"] = []*user{u}
+		}
+
+		if m, ok := mediaById[p.FeaturedMedia]; ok {
+			p.Embedded["wp:featuredmedia"] = []*media{m}
+		}
+
+		cats := make([]*term, 0, len(p.Categories))
+		for _, id := range p.Categories {
+			if t, ok := termsById[id]; ok {
+				cats = append(cats, t)
+			}
+		}
+
+		tags := make([]*term, 0, len(p.Tags))
+		for _, id := range p.Tags {
+			if t, ok := termsById[id]; ok {
+				tags = append(tags, t)
+			}
+		}
+
+		p.Embedded["wp:term"] = [][]*term{cats, tags}
+	}
+
+	return nil
+}
+
+func newUser(u *wordpress.User) *user {
+	avatarUrls := make(map[string]string)
+	for _, size := range []int{24, 48, 96} {
+		avatarUrls[strconv.Itoa(size)] = u.GravatarURL(size, "mm")
+	}
+
+	return &user{
+		Id:          u.Id,
+		Name:        u.Name,
+		Url:         u.Website,
+		Description: u.Description,
+		Slug:        u.Slug,
+		AvatarUrls:  avatarUrls}
+}
+
+func openClosed(open bool) string {
+	if open {
+		return "open"
+	}
+
+	return "closed"
+}
+
+func formatDate(t time.Time) string {
+	return t.Format(dateFormat)
+}
@@ -0,0 +1,214 @@
+// Package rest serves read-only json that is roughly compatible with
+// the WordPress REST API (i.e. `/wp-json/wp/v2/posts`)
+//
+// Only published content is exposed, so no nonces or secrets are required.
+package rest
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/ssttevee/go-wordpress"
+	"golang.org/x/net/context"
+)
+
+const (
+	defaultPerPage = 10
+	maxPerPage     = 100
+)
+
+// NewHandler returns a handler that serves the posts, categories, tags,
+// and media collections under `/wp-json/wp/v2/`
+func NewHandler(wp *wordpress.WordPress) http.Handler {
+	mux := http.NewServeMux()
+	mux.Handle("/wp-json/wp/v2/posts", PostsHandler(wp))
+	mux.Handle("/wp-json/wp/v2/posts/", http.StripPrefix("/wp-json/wp/v2/posts", PostsHandler(wp)))
+	mux.Handle("/wp-json/wp/v2/categories", CategoriesHandler(wp))
+	mux.Handle("/wp-json/wp/v2/categories/", http.StripPrefix("/wp-json/wp/v2/categories", CategoriesHandler(wp)))
+	mux.Handle("/wp-json/wp/v2/tags", TagsHandler(wp))
+	mux.Handle("/wp-json/wp/v2/tags/", http.StripPrefix("/wp-json/wp/v2/tags", TagsHandler(wp)))
+	mux.Handle("/wp-json/wp/v2/media", MediaHandler(wp))
+	mux.Handle("/wp-json/wp/v2/media/", http.StripPrefix("/wp-json/wp/v2/media", MediaHandler(wp)))
+
+	return mux
+}
+
+// handlerFunc serves a single resource if id is non-zero, otherwise the collection
+type handlerFunc func(c context.Context, w http.ResponseWriter, r *http.Request, id int64) (interface{}, error)
+
+func (fn handlerFunc) handler(wp *wordpress.WordPress) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			writeError(w, &Error{Code: "rest_no_route", Message: "No route was found matching the URL and request method", Status: http.StatusNotFound})
+			return
+		}
+
+		var id int64
+		if path := strings.Trim(r.URL.Path, "/"); path != "" {
+			var err error
+			if id, err = strconv.ParseInt(path, 10, 64); err != nil || id <= 0 {
+				writeError(w, &Error{Code: "rest_no_route", Message: "No route was found matching the URL and request method", Status: http.StatusNotFound})
+				return
+			}
+		}
+
+		ret, err := fn(wordpress.NewContext(r.Context(), wp), w, r, id)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+		json.NewEncoder(w).Encode(ret)
+	})
+}
+
+// Error represents a WordPress REST API error response
+type Error struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+	Status  int    `json:"-"`
+}
+
+func (err *Error) Error() string {
+	return "rest: " + err.Message
+}
+
+func writeError(w http.ResponseWriter, err error) {
+	restErr, ok := err.(*Error)
+	if !ok {
+		restErr = &Error{Code: "rest_internal_error", Message: err.Error(), Status: http.StatusInternalServerError}
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	w.WriteHeader(restErr.Status)
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"code":    restErr.Code,
+		"message": restErr.Message,
+		"data":    map[string]int{"status": restErr.Status}})
+}
+
+func invalidParam(name string) error {
+	return &Error{Code: "rest_invalid_param", Message: "Invalid parameter: " + name, Status: http.StatusBadRequest}
+}
+
+// setTotals sets the pagination headers
+func setTotals(w http.ResponseWriter, total, perPage int) {
+	w.Header().Set("X-WP-Total", strconv.Itoa(total))
+	w.Header().Set("X-WP-TotalPages", strconv.Itoa(totalPages(total, perPage)))
+}
+
+func totalPages(total, perPage int) int {
+	return (total + perPage - 1) / perPage
+}
+
+// pastLastPage returns whether the page is after the last page of results,
+// which is not queried so that huge page numbers do not scan the whole table
+//
+// The first page is never past the last, even if there are no results.
+func pastLastPage(page, perPage, total int) bool {
+	return page > 1 && page > totalPages(total, perPage)
+}
+
+func invalidPage() error {
+	return &Error{
+		Code:    "rest_post_invalid_page_number",
+		Message: "The page number requested is larger than the number of pages available.",
+		Status:  http.StatusBadRequest}
+}
+
+// pagination reads the `page` and `per_page` parameters
+func pagination(r *http.Request) (page, perPage int, err error) {
+	if page, err = intParam(r, "page", 1); err != nil || page < 1 {
+		return 0, 0, invalidParam("page")
+	}
+
+	if perPage, err = intParam(r, "per_page", defaultPerPage); err != nil || perPage < 1 || perPage > maxPerPage {
+		return 0, 0, invalidParam("per_page")
+	}
+
+	return page, perPage, nil
+}
+
+func intParam(r *http.Request, name string, def int) (int, error) {
+	value := r.URL.Query().Get(name)
+	if value == "" {
+		return def, nil
+	}
+
+	return strconv.Atoi(value)
+}
+
+// idsParam reads a comma-separated or repeated list of ids
+func idsParam(r *http.Request, name string) ([]int64, error) {
+	var ids []int64
+	for _, value := range r.URL.Query()[name] {
+		for _, part := range strings.Split(value, ",") {
+			if part = strings.TrimSpace(part); part == "" {
+				continue
+			}
+
+			id, err := strconv.ParseInt(part, 10, 64)
+			if err != nil {
+				return nil, invalidParam(name)
+			}
+
+			ids = append(ids, id)
+		}
+	}
+
+	return ids, nil
+}
+
+// stringsParam reads a comma-separated or repeated list of strings
+func stringsParam(r *http.Request, name string) []string {
+	var values []string
+	for _, value := range r.URL.Query()[name] {
+		for _, part := range strings.Split(value, ",") {
+			if part = strings.TrimSpace(part); part != "" {
+				values = append(values, part)
+			}
+		}
+	}
+
+	return values
+}
+
+func notFound(code string) error {
+	return &Error{Code: code, Message: "Invalid ID.", Status: http.StatusNotFound}
+}
+
+// loadExisting calls load with the ids, retrying without the ids of any missing resources
+func loadExisting(ids []int64, load func(ids []int64) error) error {
+	for len(ids) > 0 {
+		err := load(ids)
+
+		var mre wordpress.MissingResourcesError
+		if !errors.As(err, &mre) {
+			return err
+		}
+
+		skip := make(map[int64]bool)
+		for _, id := range mre.IDs() {
+			skip[id] = true
+		}
+
+		var remaining []int64
+		for _, id := range ids {
+			if !skip[id] {
+				remaining = append(remaining, id)
+			}
+		}
+
+		ids = remaining
+	}
+
+	return nil
+}
+
+// likeEscaper escapes the wildcards of a search term used in a `LIKE` pattern
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
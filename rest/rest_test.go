@@ -0,0 +1,72 @@
+package rest
+
+import (
+	"reflect"
+	"strconv"
+	"testing"
+
+	"github.com/ssttevee/go-wordpress"
+)
+
+func TestLoadExistingMissingId(t *testing.T) {
+	var loaded []int64
+	var calls int
+	err := loadExisting([]int64{1, 2}, func(ids []int64) error {
+		calls++
+		for _, id := range ids {
+			if id == 2 {
+				return wordpress.MissingResourcesError{id}
+			}
+		}
+
+		loaded = ids
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if !reflect.DeepEqual(loaded, []int64{1}) {
+		t.Errorf("expected only the present id to be loaded, got %v", loaded)
+	}
+
+	if calls != 2 {
+		t.Errorf("expected 2 calls, got %d", calls)
+	}
+}
+
+func TestPastLastPage(t *testing.T) {
+	tests := []struct {
+		page, perPage, total int
+		want                 bool
+	}{
+		{1, 10, 0, false},
+		{2, 10, 0, true},
+		{2, 10, 10, true},
+		{2, 10, 11, false},
+		{3, 10, 21, false},
+		{4, 10, 21, true},
+		{1 << 40, 100, 1000, true},
+	}
+
+	for _, tt := range tests {
+		if got := pastLastPage(tt.page, tt.perPage, tt.total); got != tt.want {
+			t.Errorf("pastLastPage(%d, %d, %d) = %v, expected %v", tt.page, tt.perPage, tt.total, got, tt.want)
+		}
+	}
+}
+
+func TestNewUserAvatarUrls(t *testing.T) {
+	u := &wordpress.User{Email: "MyEmailAddress@example.com"}
+
+	avatars := newUser(u).AvatarUrls
+	if len(avatars) != 3 {
+		t.Fatalf("expected 3 avatar sizes, got %v", avatars)
+	}
+
+	for _, size := range []int{24, 48, 96} {
+		if got, want := avatars[strconv.Itoa(size)], u.GravatarURL(size, "mm"); got != want {
+			t.Errorf("expected the %dpx avatar %q, got %q", size, want, got)
+		}
+	}
+}
@@ -0,0 +1,158 @@
+package rest
+
+import (
+	"net/http"
+
+	"github.com/ssttevee/go-wordpress"
+	"golang.org/x/net/context"
+)
+
+type term struct {
+	Id          int64  `json:"id"`
+	Count       int64  `json:"count"`
+	Description string `json:"description"`
+	Link        string `json:"link"`
+	Name        string `json:"name"`
+	Slug        string `json:"slug"`
+	Taxonomy    string `json:"taxonomy"`
+	Parent      int64  `json:"parent"`
+}
+
+// CategoriesHandler returns a handler that serves categories
+//
+// Supported query parameters are `page`, `per_page`, `include`, `exclude`,
+// `parent`, `post`, and `slug`
+func CategoriesHandler(wp *wordpress.WordPress) http.Handler {
+	return handlerFunc(func(c context.Context, w http.ResponseWriter, r *http.Request, id int64) (interface{}, error) {
+		return serveTerms(c, w, r, id, wordpress.TaxonomyCategory)
+	}).handler(wp)
+}
+
+// TagsHandler returns a handler that serves tags
+//
+// Supported query parameters are `page`, `per_page`, `include`, `exclude`,
+// `post`, and `slug`
+func TagsHandler(wp *wordpress.WordPress) http.Handler {
+	return handlerFunc(func(c context.Context, w http.ResponseWriter, r *http.Request, id int64) (interface{}, error) {
+		return serveTerms(c, w, r, id, wordpress.TaxonomyPostTag)
+	}).handler(wp)
+}
+
+func serveTerms(c context.Context, w http.ResponseWriter, r *http.Request, id int64, taxonomy wordpress.Taxonomy) (interface{}, error) {
+	if id != 0 {
+		terms, err := queryTerms(c, &wordpress.TermQueryOptions{Id: id}, taxonomy)
+		if err != nil {
+			return nil, err
+		}
+
+		if len(terms) == 0 {
+			return nil, &Error{Code: "rest_term_invalid", Message: "Term does not exist.", Status: http.StatusNotFound}
+		}
+
+		return terms[0], nil
+	}
+
+	page, perPage, err := pagination(r)
+	if err != nil {
+		return nil, err
+	}
+
+	opts := &wordpress.TermQueryOptions{
+		Taxonomy: taxonomy,
+		SlugIn:   stringsParam(r, "slug"),
+		Limit:    perPage,
+		Page:     page}
+
+	if search := r.URL.Query().Get("search"); search != "" {
+		opts.NameLike = "%" + likeEscaper.Replace(search) + "%"
+	}
+
+	if opts.IdIn, err = idsParam(r, "include"); err != nil {
+		return nil, err
+	}
+
+	if opts.IdNotIn, err = idsParam(r, "exclude"); err != nil {
+		return nil, err
+	}
+
+	if objectIds, err := idsParam(r, "post"); err != nil {
+		return nil, err
+	} else if len(objectIds) > 0 {
+		opts.ObjectId = objectIds[0]
+	}
+
+	if taxonomy == wordpress.TaxonomyCategory {
+		if parents, err := idsParam(r, "parent"); err != nil {
+			return nil, err
+		} else if len(parents) > 0 {
+			opts.ParentId = &parents[0]
+		}
+	}
+
+	total, err := wordpress.CountTerms(c, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	setTotals(w, total, perPage)
+
+	// unlike posts, terms past the last page are an empty page
+	if pastLastPage(page, perPage, total) {
+		return []*term{}, nil
+	}
+
+	return queryTerms(c, opts, taxonomy)
+}
+
+// queryTerms loads the terms that match the query
+func queryTerms(c context.Context, opts *wordpress.TermQueryOptions, taxonomy wordpress.Taxonomy) ([]*term, error) {
+	query := wordpress.QueryTags
+	if taxonomy == wordpress.TaxonomyCategory {
+		query = wordpress.QueryCategories
+	}
+
+	it, err := query(c, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	ids, err := it.Slice()
+	if err != nil {
+		return nil, err
+	}
+
+	ret := make([]*term, 0, len(ids))
+	if taxonomy == wordpress.TaxonomyCategory {
+		categories, err := wordpress.GetCategories(c, ids...)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, cat := range categories {
+			ret = append(ret, newTerm(&cat.Term, cat.Link))
+		}
+	} else {
+		tags, err := wordpress.GetTags(c, ids...)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, tag := range tags {
+			ret = append(ret, newTerm(&tag.Term, tag.Link))
+		}
+	}
+
+	return ret, nil
+}
+
+func newTerm(t *wordpress.Term, link string) *term {
+	return &term{
+		Id:          t.Id,
+		Count:       t.Count,
+		Description: t.Description,
+		Link:        link,
+		Name:        t.Name,
+		Slug:        t.Slug,
+		Taxonomy:    t.Taxonomy,
+		Parent:      t.Parent}
+}
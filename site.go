@@ -0,0 +1,139 @@
+package wordpress
+
+import (
+	"database/sql"
+	"fmt"
+	"strconv"
+	"time"
+
+	"golang.org/x/net/context"
+)
+
+// GetSiteLogo gets the attachment set as the active theme's custom logo
+//
+// Returns nil if no logo is set, or if the logo no longer exists.
+func GetSiteLogo(c context.Context) (*Attachment, error) {
+	c, span := startSpan(c, "/wordpress.GetSiteLogo")
+	defer span.End()
+
+	mods, err := getThemeMods(c)
+	if err != nil {
+		return nil, err
+	}
+
+	return getSiteAttachment(c, mods["custom_logo"])
+}
+
+// GetSiteIcon gets the attachment set as the site icon
+//
+// Returns nil if no icon is set, or if the icon no longer exists.
+func GetSiteIcon(c context.Context) (*Attachment, error) {
+	c, span := startSpan(c, "/wordpress.GetSiteIcon")
+	defer span.End()
+
+	value, err := GetOption(c, "site_icon")
+	if err == sql.ErrNoRows {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	return getSiteAttachment(c, value)
+}
+
+// getThemeMods gets the decoded theme modifications of the active theme
+func getThemeMods(c context.Context) (map[interface{}]interface{}, error) {
+	stylesheet, err := GetOption(c, "stylesheet")
+	if err == sql.ErrNoRows {
+		return map[interface{}]interface{}{}, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	dec, err := GetOptionSerialized(c, "theme_mods_"+stylesheet)
+	if err == sql.ErrNoRows {
+		return map[interface{}]interface{}{}, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	mods, ok := dec.(map[interface{}]interface{})
+	if !ok {
+		return map[interface{}]interface{}{}, nil
+	}
+
+	return mods, nil
+}
+
+// getSiteAttachment loads the attachment referenced by a serialized or plain option value
+func getSiteAttachment(c context.Context, value interface{}) (*Attachment, error) {
+	var id int64
+	switch v := value.(type) {
+	case int64:
+		id = v
+	case string:
+		id, _ = strconv.ParseInt(v, 10, 64)
+	}
+
+	if id <= 0 {
+		return nil, nil
+	}
+
+	// the option may still point at an attachment that was deleted
+	attachments, err := GetAttachments(c, id)
+	if _, missing := err.(MissingResourcesError); missing {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	} else if len(attachments) == 0 {
+		return nil, nil
+	}
+
+	return attachments[0], nil
+}
+
+// GetTimezone gets the site's timezone
+//
+// The named `timezone_string` option is preferred over the `gmt_offset` option,
+// which may be fractional (e.g. 5.5). UTC is returned if neither is set.
+func GetTimezone(c context.Context) (*time.Location, error) {
+	c, span := startSpan(c, "/wordpress.GetTimezone")
+	defer span.End()
+
+	name, err := GetOption(c, "timezone_string")
+	if err != nil && err != sql.ErrNoRows {
+		return nil, err
+	}
+
+	if name != "" {
+		if loc, err := time.LoadLocation(name); err == nil {
+			return loc, nil
+		}
+	}
+
+	offset, err := GetOption(c, "gmt_offset")
+	if err == sql.ErrNoRows || offset == "" {
+		return time.UTC, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	hours, err := strconv.ParseFloat(offset, 64)
+	if err != nil || hours == 0 {
+		return time.UTC, nil
+	}
+
+	seconds := int(hours * 3600)
+
+	sign := "+"
+	if seconds < 0 {
+		sign = "-"
+	}
+
+	abs := seconds
+	if abs < 0 {
+		abs = -abs
+	}
+
+	return time.FixedZone(fmt.Sprintf("UTC%s%02d:%02d", sign, abs/3600, abs%3600/60), seconds), nil
+}
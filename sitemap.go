@@ -0,0 +1,290 @@
+package wordpress
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"golang.org/x/net/context"
+)
+
+// MaxSitemapURLs is the maximum number of urls allowed in a single sitemap file
+const MaxSitemapURLs = 50000
+
+// sitemapBatchSize is the number of objects or terms loaded at a time
+const sitemapBatchSize = 500
+
+// SitemapOptions represents the available parameters for generating sitemaps
+type SitemapOptions struct {
+	// The url prepended to every link, defaults to the `home` option
+	BaseURL string
+
+	// The maximum number of urls per sitemap file, defaults to `MaxSitemapURLs`
+	MaxURLs int
+
+	// The sitemap file to generate, starting at 1
+	Page int
+
+	// The url of each sitemap file in the sitemap index,
+	// where `%d` is replaced by the page number (i.e. `https://example.com/sitemap-%d.xml`)
+	PageURL string
+}
+
+type sitemapEntry struct {
+	loc     string
+	lastmod time.Time
+}
+
+// GenerateSitemap writes a sitemap of all published posts, pages, categories, and tags
+//
+// Content is loaded in batches, so the whole site is never held in memory.
+// If there are more than `opts.MaxURLs` urls, only the urls of the sitemap file
+// selected by `opts.Page` are written. Use `GenerateSitemapIndex` to list all the files.
+func GenerateSitemap(c context.Context, w io.Writer, opts SitemapOptions) error {
+	c, span := startSpan(c, "/wordpress.GenerateSitemap")
+	defer span.End()
+
+	if err := opts.setDefaults(c); err != nil {
+		return err
+	}
+
+	if _, err := io.WriteString(w, xml.Header+`<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">`+"\n"); err != nil {
+		return err
+	}
+
+	start := (opts.Page - 1) * opts.MaxURLs
+	if _, err := walkSitemap(c, start, start+opts.MaxURLs, func(entry *sitemapEntry) error {
+		return writeSitemapEntry(w, "url", opts.BaseURL+entry.loc, entry.lastmod)
+	}); err != nil {
+		return err
+	}
+
+	_, err := io.WriteString(w, "</urlset>\n")
+	return err
+}
+
+// GenerateSitemapIndex writes a sitemap index listing every sitemap file
+// needed to hold all the urls, using `opts.PageURL` for each file's url
+func GenerateSitemapIndex(c context.Context, w io.Writer, opts SitemapOptions) error {
+	c, span := startSpan(c, "/wordpress.GenerateSitemapIndex")
+	defer span.End()
+
+	if !strings.Contains(opts.PageURL, "%d") {
+		return fmt.Errorf("wordpress: sitemap page url must contain %%d: %q", opts.PageURL)
+	}
+
+	if err := opts.setDefaults(c); err != nil {
+		return err
+	}
+
+	total, err := walkSitemap(c, 0, 0, nil)
+	if err != nil {
+		return err
+	}
+
+	spanInt64(span, "wp/sitemap/count", int64(total))
+
+	if _, err := io.WriteString(w, xml.Header+`<sitemapindex xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">`+"\n"); err != nil {
+		return err
+	}
+
+	for page := 1; page == 1 || (page-1)*opts.MaxURLs < total; page++ {
+		if err := writeSitemapEntry(w, "sitemap", fmt.Sprintf(opts.PageURL, page), time.Time{}); err != nil {
+			return err
+		}
+	}
+
+	_, err = io.WriteString(w, "</sitemapindex>\n")
+	return err
+}
+
+func (opts *SitemapOptions) setDefaults(c context.Context) error {
+	if opts.BaseURL == "" {
+		home, err := GetOption(c, "home")
+		if err != nil {
+			return err
+		}
+
+		opts.BaseURL = home
+	}
+
+	opts.BaseURL = strings.TrimRight(opts.BaseURL, "/")
+
+	if opts.MaxURLs <= 0 || opts.MaxURLs > MaxSitemapURLs {
+		opts.MaxURLs = MaxSitemapURLs
+	}
+
+	if opts.Page < 1 {
+		opts.Page = 1
+	}
+
+	return nil
+}
+
+func writeSitemapEntry(w io.Writer, tag, loc string, lastmod time.Time) error {
+	if _, err := io.WriteString(w, "\t<"+tag+"><loc>"); err != nil {
+		return err
+	}
+
+	if err := xml.EscapeText(w, []byte(loc)); err != nil {
+		return err
+	}
+
+	entry := "</loc>"
+	if !lastmod.IsZero() {
+		entry += "<lastmod>" + lastmod.UTC().Format(time.RFC3339) + "</lastmod>"
+	}
+
+	_, err := io.WriteString(w, entry+"</"+tag+">\n")
+	return err
+}
+
+// walkSitemap calls fn for every sitemap entry whose index is in [start, end)
+// and returns the number of entries that were walked
+//
+// Entries outside of the range are counted without loading their data.
+func walkSitemap(c context.Context, start, end int, fn func(*sitemapEntry) error) (int, error) {
+	var index int
+
+	links, err := newPermalinkResolver(c)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, postType := range []PostType{PostTypePost, PostTypePage} {
+		if end > 0 && index >= end {
+			return index, nil
+		}
+
+		after := ""
+		for {
+			it, err := QueryPosts(c, &ObjectQueryOptions{
+				PostType: postType,
+				After:    after,
+				Limit:    sitemapBatchSize})
+			if err != nil {
+				return 0, err
+			}
+
+			ids, err := it.Slice()
+			if err != nil {
+				return 0, err
+			}
+
+			var window []int64
+			for _, id := range ids {
+				if index >= start && index < end {
+					window = append(window, id)
+				}
+
+				index++
+			}
+
+			if len(window) > 0 {
+				objects, err := getObjects(c, window...)
+				if err != nil {
+					return 0, err
+				}
+
+				for _, obj := range objects {
+					link, err := links.link(c, obj, nil)
+					if err != nil {
+						return 0, err
+					}
+
+					if err := fn(&sitemapEntry{loc: link, lastmod: obj.ModifiedGmt}); err != nil {
+						return 0, err
+					}
+				}
+			}
+
+			if len(ids) < sitemapBatchSize || (end > 0 && index >= end) {
+				break
+			}
+
+			after = it.Cursor()
+		}
+	}
+
+	for _, taxonomy := range []Taxonomy{TaxonomyCategory, TaxonomyPostTag} {
+		if end > 0 && index >= end {
+			return index, nil
+		}
+
+		after := ""
+		for {
+			// empty archives are not worth indexing
+			it, err := queryTerms(c, &TermQueryOptions{
+				Taxonomy:  taxonomy,
+				HideEmpty: true,
+				After:     after,
+				Limit:     sitemapBatchSize})
+			if err != nil {
+				return 0, err
+			}
+
+			ids, err := it.Slice()
+			if err != nil {
+				return 0, err
+			}
+
+			var window []int64
+			for _, id := range ids {
+				if index >= start && index < end {
+					window = append(window, id)
+				}
+
+				index++
+			}
+
+			if len(window) > 0 {
+				links, err := termLinks(c, taxonomy, window)
+				if err != nil {
+					return 0, err
+				}
+
+				for _, link := range links {
+					if err := fn(&sitemapEntry{loc: link}); err != nil {
+						return 0, err
+					}
+				}
+			}
+
+			if len(ids) < sitemapBatchSize || (end > 0 && index >= end) {
+				break
+			}
+
+			after = it.Cursor()
+		}
+	}
+
+	return index, nil
+}
+
+// termLinks returns the links of the categories or tags, in the same order
+func termLinks(c context.Context, taxonomy Taxonomy, ids []int64) ([]string, error) {
+	links := make([]string, 0, len(ids))
+	if taxonomy == TaxonomyCategory {
+		categories, err := GetCategories(c, ids...)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, cat := range categories {
+			links = append(links, cat.Link)
+		}
+	} else {
+		tags, err := GetTags(c, ids...)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, tag := range tags {
+			links = append(links, tag.Link)
+		}
+	}
+
+	return links, nil
+}
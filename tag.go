@@ -1,7 +1,6 @@
 package wordpress
 
 import (
-	"go.opencensus.io/trace"
 	"encoding/json"
 	"golang.org/x/net/context"
 	"strings"
@@ -23,9 +22,16 @@ func (tag *Tag) MarshalJSON() ([]byte, error) {
 		"url":  tag.Link})
 }
 
+// CreateTag inserts the tag into the database and returns the new term id
+func CreateTag(c context.Context, tag *Term) (int64, error) {
+	tag.Taxonomy = string(TaxonomyPostTag)
+
+	return CreateTerm(c, tag)
+}
+
 // GetTags gets all tag data from the database
 func GetTags(c context.Context, tagIds ...int64) ([]*Tag, error) {
-	c, span := trace.StartSpan(c, "/wordpress.GetTags")
+	c, span := startSpan(c, "/wordpress.GetTags")
 	defer span.End()
 
 	if len(tagIds) == 0 {
@@ -40,10 +46,15 @@ func GetTags(c context.Context, tagIds ...int64) ([]*Tag, error) {
 		return nil, err
 	}
 
+	base, err := termBase(c, TaxonomyPostTag)
+	if err != nil {
+		return nil, err
+	}
+
 	for _, term := range terms {
 		t := Tag{Term: *term}
 
-		t.Link = "/tag/" + t.Slug
+		t.Link = base + "/" + t.Slug
 
 		// insert into return set
 		for _, index := range idMap[t.Id] {
@@ -51,22 +62,36 @@ func GetTags(c context.Context, tagIds ...int64) ([]*Tag, error) {
 		}
 	}
 
-	return ret, nil
+	return afterGetTagsFilters.apply(c, ret)
+}
+
+// QueryTags returns the ids of the tags that match the query
+func QueryTags(c context.Context, opts *TermQueryOptions) (Iterator, error) {
+	c, span := startSpan(c, "/wordpress.QueryTags")
+	defer span.End()
+
+	opts.Taxonomy = TaxonomyPostTag
+
+	return queryTerms(c, opts)
 }
 
 // GetTagIdBySlug returns the id of the category that matches the given slug
 func GetTagIdBySlug(c context.Context, slug string) (int64, error) {
-	c, span := trace.StartSpan(c, "/wordpress.GetTagIdBySlug")
+	c, span := startSpan(c, "/wordpress.GetTagIdBySlug")
 	span.End()
 
 	parts := strings.Split(slug, "/")
 
 	var tagId int64
 	for _, part := range parts {
-		it, err := queryTerms(c, &TermQueryOptions{
+		opts := &TermQueryOptions{
 			Taxonomy: TaxonomyPostTag,
-			Slug:     part,
-			ParentId: tagId})
+			Slug:     part}
+		if tagId != 0 {
+			opts.ParentId = &tagId
+		}
+
+		it, err := queryTerms(c, opts)
 		if err != nil {
 			return 0, err
 		}
@@ -1,14 +1,18 @@
 package wordpress
 
 import (
-	"go.opencensus.io/trace"
-	"encoding/base64"
+	"database/sql"
+	"errors"
 	"fmt"
 	"github.com/elgris/sqrl"
 	"golang.org/x/net/context"
+	"regexp"
 	"strconv"
+	"strings"
 )
 
+var regexpSlugSeparators = regexp.MustCompile("[^a-z0-9]+")
+
 // Term represents a WordPress term
 type Term struct {
 	// Term ID.
@@ -44,6 +48,14 @@ type TermQueryOptions struct {
 	After string `param:"after"`
 	Limit int    `param:"limit"`
 
+	// Page is the 1-based page of `Limit` terms to return,
+	// it cannot be combined with `After`
+	Page int `param:"page"`
+
+	// Before is the `PrevCursor` of a page, to return the page before it,
+	// it cannot be combined with `After` or `Page`
+	Before string `param:"before"`
+
 	Id      int64   `param:"term_id"`
 	IdIn    []int64 `param:"term_id__in"`
 	IdNotIn []int64 `param:"term_id__not_in"`
@@ -52,11 +64,15 @@ type TermQueryOptions struct {
 	NameIn    []string `param:"term_name__in"`
 	NameNotIn []string `param:"term_name__not_in"`
 
+	// NameLike is a case-insensitive `LIKE` pattern, so it may contain `%` and `_` wildcards
+	NameLike string `param:"term_name__like"`
+
 	ObjectId      int64   `param:"object_id"`
 	ObjectIdIn    []int64 `param:"object_id__in"`
 	ObjectIdNotIn []int64 `param:"object_id__not_in"`
 
-	ParentId      int64   `param:"parent_id"`
+	// ParentId is a pointer so that top-level terms can be matched with a zero id
+	ParentId      *int64  `param:"parent_id"`
 	ParentIdIn    []int64 `param:"parent_id__in"`
 	ParentIdNotIn []int64 `param:"parent_id__not_in"`
 
@@ -67,6 +83,174 @@ type TermQueryOptions struct {
 	Taxonomy      Taxonomy   `param:"taxonomy"`
 	TaxonomyIn    []Taxonomy `param:"taxonomy__in"`
 	TaxonomyNotIn []Taxonomy `param:"taxonomy__not_in"`
+
+	// HideEmpty excludes the terms that are not assigned to any published object,
+	// like WordPress's `hide_empty`
+	HideEmpty bool `param:"hide_empty"`
+}
+
+// CreateTerm inserts the term and its taxonomy into the database and returns the new term id
+//
+// The slug is derived from the name if it is empty.
+// A `*SlugConflictError` is returned if the slug is already used in the taxonomy.
+func CreateTerm(c context.Context, t *Term) (int64, error) {
+	c, span := startSpan(c, "/wordpress.CreateTerm")
+	defer span.End()
+
+	if t.Name == "" || t.Taxonomy == "" {
+		return 0, errors.New("wordpress: terms require a name and taxonomy")
+	}
+
+	slug := t.Slug
+	if slug == "" {
+		slug = strings.Trim(regexpSlugSeparators.ReplaceAllString(strings.ToLower(t.Name), "-"), "-")
+		if slug == "" {
+			return 0, errors.New("wordpress: unable to derive a slug from the term name")
+		}
+	}
+
+	var termId, ttId int64
+	if err := inTx(c, func(tx *sql.Tx) error {
+		stmt, args, err := sqrl.Select("COUNT(*)").
+			From(table(c, "terms") + " AS t").
+			Join(table(c, "term_taxonomy") + " AS tt ON tt.term_id = t.term_id").
+			Where(sqrl.Eq{"t.slug": slug, "tt.taxonomy": t.Taxonomy}).ToSql()
+		if err != nil {
+			return err
+		}
+
+		spanString(span, "wp/term/query", stmt)
+
+		var n int
+		if err := tx.QueryRowContext(c, stmt, args...).Scan(&n); err != nil {
+			return err
+		} else if n > 0 {
+			return &SlugConflictError{Taxonomy: Taxonomy(t.Taxonomy), Slug: slug}
+		}
+
+		res, err := execTx(c, tx, sqrl.Insert(table(c, "terms")).
+			Columns("name", "slug", "term_group").
+			Values(t.Name, slug, t.Group))
+		if err != nil {
+			return err
+		}
+
+		if termId, err = res.LastInsertId(); err != nil {
+			return err
+		}
+
+		res, err = execTx(c, tx, sqrl.Insert(table(c, "term_taxonomy")).
+			Columns("term_id", "taxonomy", "description", "parent", "count").
+			Values(termId, t.Taxonomy, t.Description, t.Parent, 0))
+		if err != nil {
+			return err
+		}
+
+		ttId, err = res.LastInsertId()
+
+		return err
+	}); err != nil {
+		return 0, err
+	}
+
+	t.Id = termId
+	t.Slug = slug
+	t.TaxonomyId = ttId
+	t.Count = 0
+
+	return termId, nil
+}
+
+// AssignTerms relates the terms to the object
+//
+// Terms that are already related to the object are left as-is.
+func AssignTerms(c context.Context, objectId int64, termIds ...int64) error {
+	c, span := startSpan(c, "/wordpress.AssignTerms")
+	defer span.End()
+
+	if len(termIds) == 0 {
+		return nil
+	}
+
+	termIds, _ = dedupe(termIds)
+
+	return inTx(c, func(tx *sql.Tx) error {
+		ttIds, err := termTaxonomyIds(c, tx, "", termIds)
+		if err != nil {
+			return err
+		}
+
+		stmt, args, err := sqrl.Select("term_taxonomy_id").
+			From(table(c, "term_relationships")).
+			Where(sqrl.Eq{"object_id": objectId, "term_taxonomy_id": ttIds}).ToSql()
+		if err != nil {
+			return err
+		}
+
+		existing, err := queryIdsTx(c, tx, stmt, args)
+		if err != nil {
+			return err
+		}
+
+		assigned := make(map[int64]bool)
+		for _, ttId := range existing {
+			assigned[ttId] = true
+		}
+
+		var added []int64
+		insert := sqrl.Insert(table(c, "term_relationships")).Columns("object_id", "term_taxonomy_id")
+		for _, ttId := range ttIds {
+			if !assigned[ttId] {
+				insert = insert.Values(objectId, ttId)
+				added = append(added, ttId)
+			}
+		}
+
+		if len(added) == 0 {
+			return nil
+		}
+
+		if _, err := execTx(c, tx, insert); err != nil {
+			return err
+		}
+
+		return updateTermCounts(c, tx, added)
+	})
+}
+
+// RemoveTerms removes the relationships between the terms and the object
+//
+// Terms that are not related to the object are ignored.
+func RemoveTerms(c context.Context, objectId int64, termIds ...int64) error {
+	c, span := startSpan(c, "/wordpress.RemoveTerms")
+	defer span.End()
+
+	if len(termIds) == 0 {
+		return nil
+	}
+
+	termIds, _ = dedupe(termIds)
+
+	return inTx(c, func(tx *sql.Tx) error {
+		ttIds, err := termTaxonomyIds(c, tx, "", termIds)
+		if err != nil {
+			return err
+		}
+
+		res, err := execTx(c, tx, sqrl.Delete(table(c, "term_relationships")).
+			Where(sqrl.Eq{"object_id": objectId, "term_taxonomy_id": ttIds}))
+		if err != nil {
+			return err
+		}
+
+		if n, err := res.RowsAffected(); err != nil {
+			return err
+		} else if n == 0 {
+			return nil
+		}
+
+		return updateTermCounts(c, tx, ttIds)
+	})
 }
 
 // GetTerms gets all term data from the database
@@ -77,6 +261,20 @@ func getTerms(c context.Context, termIds ...int64) ([]*Term, error) {
 
 	ids, idMap := dedupe(termIds)
 
+	ret := make([]*Term, len(termIds))
+
+	var cached []*Term
+	hitIds := getCached(c, termCacheKey, ids, &cached)
+	for i, id := range hitIds {
+		for _, index := range idMap[id] {
+			ret[index] = cached[i]
+		}
+	}
+
+	if ids = without(ids, hitIds); len(ids) == 0 {
+		return afterGetTermsFilters.apply(c, ret)
+	}
+
 	stmt, args, err := sqrl.Select("t.term_id", "t.name", "t.slug", "t.term_group", "tt.term_taxonomy_id", "tt.taxonomy", "tt.description", "tt.parent", "tt.count").
 		From(table(c, "terms") + " AS t").
 		Join(table(c, "term_taxonomy") + " AS tt ON tt.term_id = t.term_id").
@@ -85,14 +283,15 @@ func getTerms(c context.Context, termIds ...int64) ([]*Term, error) {
 		return nil, err
 	}
 
-	trace.FromContext(c).AddAttributes(trace.StringAttribute("wp/term/query", stmt))
+	spanString(spanFromContext(c), "wp/term/query", stmt)
 
-	rows, err := database(c).Query(stmt, args...)
+	rows, err := database(c).QueryContext(c, stmt, args...)
 	if err != nil {
-		return nil, fmt.Errorf("Term SQL query fail: %v", err)
+		return nil, fmt.Errorf("Term SQL query fail: %w", err)
 	}
 
-	ret := make([]*Term, len(termIds))
+	defer rows.Close()
+
 	for rows.Next() {
 		var t Term
 		if err := rows.Scan(
@@ -105,16 +304,22 @@ func getTerms(c context.Context, termIds ...int64) ([]*Term, error) {
 			&t.Description,
 			&t.Parent,
 			&t.Count); err != nil {
-			return nil, fmt.Errorf("unable to read term data: %v", err)
+			return nil, fmt.Errorf("unable to read term data: %w", err)
 		}
 
+		setCached(c, termCacheKey, t.Id, &t)
+
 		// redupe and insert into return set
 		for _, index := range idMap[t.Id] {
 			ret[index] = &t
 		}
 	}
 
-	trace.FromContext(c).AddAttributes(trace.Int64Attribute("wp/term/count", int64(len(ret))))
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	spanInt64(spanFromContext(c), "wp/term/count", int64(len(ret)))
 
 	var mre MissingResourcesError
 	for i, term := range ret {
@@ -124,6 +329,49 @@ func getTerms(c context.Context, termIds ...int64) ([]*Term, error) {
 	}
 
 	if len(mre) > 0 {
+		return nil, mre
+	}
+
+	return afterGetTermsFilters.apply(c, ret)
+}
+
+// getObjectsTermIds gets the ids of the terms of the taxonomy related to each of the objects in a single query
+//
+// The ids are in ascending order, like those of `Object.GetTaxonomy`.
+func getObjectsTermIds(c context.Context, objectIds []int64, taxonomy Taxonomy) (map[int64][]int64, error) {
+	ret := make(map[int64][]int64, len(objectIds))
+	if len(objectIds) == 0 {
+		return ret, nil
+	}
+
+	stmt, args, err := sqrl.Select("tr.object_id", "tt.term_id").
+		From(table(c, "term_relationships") + " AS tr").
+		Join(table(c, "term_taxonomy") + " AS tt ON tt.term_taxonomy_id = tr.term_taxonomy_id").
+		Where(sqrl.Eq{"tt.taxonomy": string(taxonomy), "tr.object_id": objectIds}).
+		OrderBy("tt.term_id ASC").ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	spanString(spanFromContext(c), "wp/term/query", stmt)
+
+	rows, err := database(c).QueryContext(c, stmt, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		var objectId, termId int64
+		if err := rows.Scan(&objectId, &termId); err != nil {
+			return nil, err
+		}
+
+		ret[objectId] = append(ret[objectId], termId)
+	}
+
+	if err := rows.Err(); err != nil {
 		return nil, err
 	}
 
@@ -132,10 +380,112 @@ func getTerms(c context.Context, termIds ...int64) ([]*Term, error) {
 
 // queryTerms returns the ids of the terms that match the query
 func queryTerms(c context.Context, opts *TermQueryOptions) (Iterator, error) {
-	q := sqrl.Select("t.term_id").
-		From(table(c, "terms") + " AS t").
-		OrderBy("t.term_id ASC")
+	q := filterTerms(c, sqrl.Select("t.term_id").
+		From(table(c, "terms")+" AS t"), opts)
+
+	if opts.Page < 0 {
+		return nil, errors.New("wordpress: page must not be negative")
+	} else if opts.Page > 0 && opts.After != "" {
+		return nil, errors.New("wordpress: page and after cannot be combined")
+	} else if opts.Before != "" && (opts.Page > 0 || opts.After != "") {
+		return nil, errors.New("wordpress: before cannot be combined with page or after")
+	}
+
+	if opts.After != "" {
+		cur, err := decodeCursor(opts.After, "t.term_id")
+		if err != nil {
+			return nil, err
+		}
+
+		q = q.Where("t.term_id > ?", cur.Id)
+	}
+
+	// pages before a cursor are queried in reverse, then flipped back
+	if opts.Before != "" {
+		cur, err := decodeCursor(opts.Before, "t.term_id")
+		if err != nil {
+			return nil, err
+		}
+
+		q = q.Where("t.term_id < ?", cur.Id).OrderBy("t.term_id DESC")
+	} else {
+		q = q.OrderBy("t.term_id ASC")
+	}
+
+	if opts.Limit == 0 {
+		opts.Limit = 10
+	}
+
+	if opts.Limit >= 0 {
+		q = q.Limit(uint64(opts.Limit))
+
+		// the first page starts at the first term
+		if opts.Page > 1 {
+			q = q.Offset(uint64((opts.Page - 1) * opts.Limit))
+		}
+	}
+
+	sql, args, err := q.ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	spanString(spanFromContext(c), "wp/term/query", sql)
+
+	rows, err := database(c).QueryContext(c, sql, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		if err = rows.Scan(&id); err != nil {
+			return nil, err
+		}
+
+		ids = append(ids, id)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	spanInt64(spanFromContext(c), "wp/term/count", int64(len(ids)))
+
+	if opts.Before != "" {
+		for i, j := 0, len(ids)-1; i < j; i, j = i+1, j-1 {
+			ids[i], ids[j] = ids[j], ids[i]
+		}
+	}
+
+	it := iteratorImpl{cursor: opts.After, length: len(ids)}
+	if len(ids) > 0 {
+		it.prevCursor = encodeCursor("t.term_id", strconv.FormatInt(ids[0], 10), ids[0])
+	}
+
+	var counter int
+	it.next = func() (id int64, err error) {
+		if counter < len(ids) {
+			id = ids[counter]
+			it.cursor = encodeCursor("t.term_id", strconv.FormatInt(id, 10), id)
+			counter++
+		} else {
+			return it.exit(Done)
+		}
 
+		return id, err
+	}
+
+	return &it, nil
+}
+
+// filterTerms adds the query's conditions and the joins they require to the select
+//
+// Pagination options are ignored
+func filterTerms(c context.Context, q *sqrl.SelectBuilder, opts *TermQueryOptions) *sqrl.SelectBuilder {
 	var requireTaxonomy, requireRelationships bool
 
 	if opts.Name != "" {
@@ -146,6 +496,10 @@ func queryTerms(c context.Context, opts *TermQueryOptions) (Iterator, error) {
 		q = q.Where(sqrl.NotEq{"t.name": opts.NameNotIn})
 	}
 
+	if opts.NameLike != "" {
+		q = q.Where("LOWER(t.name) LIKE LOWER(?)", opts.NameLike)
+	}
+
 	if opts.ObjectId > 0 {
 		requireRelationships = true
 		q = q.Where(sqrl.Eq{"tr.object_id": opts.ObjectId})
@@ -157,9 +511,9 @@ func queryTerms(c context.Context, opts *TermQueryOptions) (Iterator, error) {
 		q = q.Where(sqrl.NotEq{"tr.object_id": opts.ObjectIdNotIn})
 	}
 
-	if opts.ParentId > 0 {
+	if opts.ParentId != nil {
 		requireTaxonomy = true
-		q = q.Where(sqrl.Eq{"tt.parent": opts.ParentId})
+		q = q.Where(sqrl.Eq{"tt.parent": *opts.ParentId})
 	} else if opts.ParentIdIn != nil && len(opts.ParentIdIn) > 0 {
 		requireTaxonomy = true
 		q = q.Where(sqrl.Eq{"tt.parent": opts.ParentIdIn})
@@ -197,6 +551,11 @@ func queryTerms(c context.Context, opts *TermQueryOptions) (Iterator, error) {
 		q = q.Where(sqrl.NotEq{"tt.taxonomy": taxonomies})
 	}
 
+	if opts.HideEmpty {
+		requireTaxonomy = true
+		q = q.Where("tt.count > 0")
+	}
+
 	if opts.Id > 0 {
 		q = q.Where(sqrl.Eq{"t.term_id": opts.Id})
 	} else if opts.IdIn != nil && len(opts.IdIn) > 0 {
@@ -205,67 +564,100 @@ func queryTerms(c context.Context, opts *TermQueryOptions) (Iterator, error) {
 		q = q.Where(sqrl.NotEq{"t.term_id": opts.IdNotIn})
 	}
 
-	if opts.After != "" {
-		// ignore `q.After` if any errors occur
-		if b, err := base64.URLEncoding.DecodeString(opts.After); err == nil {
-			q = q.Where("t.term_id > ?", string(b))
-		}
+	if requireTaxonomy || requireRelationships {
+		q = q.Join(table(c, "term_taxonomy") + " AS tt ON tt.term_id = t.term_id")
 	}
 
-	if opts.Limit == 0 {
-		opts.Limit = 10
+	if requireRelationships {
+		q = q.Join(table(c, "term_relationships") + " AS tr ON tr.term_taxonomy_id = tt.term_taxonomy_id")
 	}
 
-	if opts.Limit >= 0 {
-		q = q.Limit(uint64(opts.Limit))
+	return q
+}
+
+// CountTerms returns the number of terms that match the query
+//
+// Pagination options are ignored
+func CountTerms(c context.Context, opts *TermQueryOptions) (int, error) {
+	c, span := startSpan(c, "/wordpress.CountTerms")
+	defer span.End()
+
+	q := filterTerms(c, sqrl.Select("COUNT(DISTINCT t.term_id)").
+		From(table(c, "terms")+" AS t"), opts)
+
+	stmt, args, err := q.ToSql()
+	if err != nil {
+		return 0, err
 	}
 
-	if requireTaxonomy || requireRelationships {
-		q = q.Join(table(c, "term_taxonomy") + " AS tt ON tt.term_id = t.term_id")
+	spanString(span, "wp/term/query", stmt)
+
+	var count int
+	if err := database(c).QueryRowContext(c, stmt, args...).Scan(&count); err != nil {
+		return 0, err
 	}
 
-	if requireRelationships {
-		q = q.Join(table(c, "term_relationships") + " AS tr ON tr.term_taxonomy_id = tt.term_taxonomy_id")
+	return count, nil
+}
+
+// GetPopularTerms gets the terms of the taxonomy that are used by the most published objects
+//
+// The terms' `Count` is the live number of published objects rather than the cached count,
+// and terms that aren't used by any published objects are omitted.
+func GetPopularTerms(c context.Context, taxonomy Taxonomy, limit int) ([]*Term, error) {
+	c, span := startSpan(c, "/wordpress.GetPopularTerms")
+	defer span.End()
+
+	q := sqrl.Select("tt.term_id", "COUNT(DISTINCT p.ID) AS live_count").
+		From(table(c, "term_taxonomy")+" AS tt").
+		Join(table(c, "term_relationships")+" AS tr ON tr.term_taxonomy_id = tt.term_taxonomy_id").
+		Join(table(c, "posts")+" AS p ON p.ID = tr.object_id").
+		Where(sqrl.Eq{"tt.taxonomy": string(taxonomy), "p.post_status": string(PostStatusPublish)}).
+		GroupBy("tt.term_id").
+		OrderBy("live_count DESC", "tt.term_id ASC")
+
+	if limit > 0 {
+		q = q.Limit(uint64(limit))
 	}
 
-	sql, args, err := q.ToSql()
+	stmt, args, err := q.ToSql()
 	if err != nil {
 		return nil, err
 	}
 
-	trace.FromContext(c).AddAttributes(trace.StringAttribute("wp/term/query", sql))
+	spanString(span, "wp/term/query", stmt)
 
-	rows, err := database(c).Query(sql, args...)
+	rows, err := database(c).QueryContext(c, stmt, args...)
 	if err != nil {
 		return nil, err
 	}
 
+	defer rows.Close()
+
 	var ids []int64
+	counts := make(map[int64]int64)
 	for rows.Next() {
-		var id int64
-		if err = rows.Scan(&id); err != nil {
+		var id, count int64
+		if err := rows.Scan(&id, &count); err != nil {
 			return nil, err
 		}
 
 		ids = append(ids, id)
+		counts[id] = count
 	}
 
-	trace.FromContext(c).AddAttributes(trace.Int64Attribute("wp/term/count", int64(len(ids))))
-
-	it := iteratorImpl{cursor: opts.After}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
 
-	var counter int
-	it.next = func() (id int64, err error) {
-		if counter < len(ids) {
-			id = ids[counter]
-			it.cursor = base64.URLEncoding.EncodeToString([]byte(strconv.FormatInt(id, 10)))
-			counter++
-		} else {
-			return it.exit(Done)
-		}
+	terms, err := getTerms(c, ids...)
+	if err != nil {
+		return nil, err
+	}
 
-		return id, err
+	for _, t := range terms {
+		t.Count = counts[t.Id]
 	}
 
-	return &it, nil
+	return terms, nil
 }
@@ -0,0 +1,236 @@
+package wordpress
+
+import (
+	"reflect"
+	"strconv"
+	"testing"
+)
+
+func TestQueryTermsPages(t *testing.T) {
+	c := newTestContext(t)
+
+	all := make(map[int64]bool)
+	for i := 0; i < 25; i++ {
+		all[testTerm(t, c, TaxonomyCategory, "Category "+strconv.Itoa(i), 0)] = true
+	}
+
+	seen := make(map[int64]int)
+	for page, want := range map[int]int{1: 10, 2: 10, 3: 5} {
+		it, err := queryTerms(c, &TermQueryOptions{Taxonomy: TaxonomyCategory, Limit: 10, Page: page})
+		if err != nil {
+			t.Fatalf("unexpected error for page %d: %v", page, err)
+		}
+
+		ids, err := it.Slice()
+		if err != nil {
+			t.Fatalf("unexpected error for page %d: %v", page, err)
+		}
+
+		if len(ids) != want {
+			t.Errorf("expected %d terms on page %d, got %d", want, page, len(ids))
+		}
+
+		for _, id := range ids {
+			if prev, ok := seen[id]; ok {
+				t.Errorf("term %d is on both page %d and page %d", id, prev, page)
+			}
+
+			seen[id] = page
+		}
+	}
+
+	for id := range all {
+		if _, ok := seen[id]; !ok {
+			t.Errorf("term %d is not on any page", id)
+		}
+	}
+
+	if _, err := queryTerms(c, &TermQueryOptions{Page: -1}); err == nil {
+		t.Error("expected a negative page to be rejected")
+	}
+}
+
+func TestCountTerms(t *testing.T) {
+	c := newTestContext(t)
+
+	post := testPost(t, c, "Hello World", testDate)
+
+	for i := 0; i < 15; i++ {
+		id := testTerm(t, c, TaxonomyCategory, "Category "+strconv.Itoa(i), 0)
+		if i < 5 {
+			testRelate(t, c, post, id)
+		}
+	}
+
+	testTerm(t, c, TaxonomyPostTag, "Tag", 0)
+
+	tests := []struct {
+		name string
+		opts TermQueryOptions
+		want int
+	}{
+		{
+			name: "pagination is ignored",
+			opts: TermQueryOptions{Taxonomy: TaxonomyCategory, Limit: 10, Page: 2},
+			want: 15,
+		},
+		{
+			name: "hide empty",
+			opts: TermQueryOptions{Taxonomy: TaxonomyCategory, HideEmpty: true},
+			want: 5,
+		},
+		{
+			name: "other taxonomy",
+			opts: TermQueryOptions{Taxonomy: TaxonomyPostTag},
+			want: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if count, err := CountTerms(c, &tt.opts); err != nil || count != tt.want {
+				t.Errorf("expected %d, got %d, %v", tt.want, count, err)
+			}
+		})
+	}
+}
+
+func TestCreateTerm(t *testing.T) {
+	c := newTestContext(t)
+
+	parent := testTerm(t, c, TaxonomyCategory, "News", 0)
+
+	term := &Term{Name: "World Affairs & Politics", Taxonomy: string(TaxonomyCategory), Description: "Abroad", Parent: parent}
+
+	id, err := CreateTerm(c, term)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if term.Id != id || term.Slug != "world-affairs-politics" || term.TaxonomyId == 0 {
+		t.Errorf("expected the term to be filled in, got %d %q %d", term.Id, term.Slug, term.TaxonomyId)
+	}
+
+	terms, err := getTerms(c, id)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	got := terms[0]
+	if got.Name != term.Name || got.Slug != term.Slug || got.Taxonomy != term.Taxonomy || got.Description != "Abroad" || got.Parent != parent || got.Count != 0 {
+		t.Errorf("expected the stored term to match, got %+v", got)
+	}
+
+	// the same slug is allowed in another taxonomy
+	if _, err := CreateTerm(c, &Term{Name: "World Affairs & Politics", Taxonomy: string(TaxonomyPostTag)}); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	_, err = CreateTerm(c, &Term{Name: "World", Slug: "world-affairs-politics", Taxonomy: string(TaxonomyCategory)})
+	if conflict, ok := err.(*SlugConflictError); !ok || conflict.Slug != "world-affairs-politics" || conflict.Taxonomy != TaxonomyCategory {
+		t.Errorf("expected a slug conflict, got %v", err)
+	}
+
+	for _, invalid := range []*Term{
+		{Taxonomy: string(TaxonomyCategory)},
+		{Name: "No Taxonomy"},
+		{Name: "!!!", Taxonomy: string(TaxonomyCategory)},
+	} {
+		if _, err := CreateTerm(c, invalid); err == nil {
+			t.Errorf("expected an error for %+v", invalid)
+		}
+	}
+}
+
+func TestAssignAndRemoveTerms(t *testing.T) {
+	c := newTestContext(t)
+
+	category := testTerm(t, c, TaxonomyCategory, "News", 0)
+	tag := testTerm(t, c, TaxonomyPostTag, "Go", 0)
+
+	published := testPost(t, c, "Published", testDate)
+	draft := testObject(t, c, map[string]interface{}{"post_status": string(PostStatusDraft)})
+
+	termIds := func(id int64) []int64 {
+		t.Helper()
+
+		stmt := "SELECT tt.term_id FROM wp_term_relationships AS tr " +
+			"JOIN wp_term_taxonomy AS tt ON tt.term_taxonomy_id = tr.term_taxonomy_id " +
+			"WHERE tr.object_id = ? ORDER BY tt.term_id"
+
+		rows, err := database(c).QueryContext(c, stmt, id)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		defer rows.Close()
+
+		var ids []int64
+		for rows.Next() {
+			var id int64
+			if err := rows.Scan(&id); err != nil {
+				t.Fatal(err)
+			}
+
+			ids = append(ids, id)
+		}
+
+		return ids
+	}
+
+	if err := AssignTerms(c, published, category, tag, category); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// assigning again is a no-op
+	if err := AssignTerms(c, published, tag); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if err := AssignTerms(c, draft, category); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if ids := termIds(published); !reflect.DeepEqual(ids, []int64{category, tag}) {
+		t.Errorf("expected the terms %d and %d, got %v", category, tag, ids)
+	}
+
+	// only the published post is counted
+	if n := testTermCount(t, c, category); n != 1 {
+		t.Errorf("expected the category to count 1 post, got %d", n)
+	}
+
+	if n := testTermCount(t, c, tag); n != 1 {
+		t.Errorf("expected the tag to count 1 post, got %d", n)
+	}
+
+	err := AssignTerms(c, published, tag+100)
+	if mre, ok := err.(MissingResourcesError); !ok || !reflect.DeepEqual(mre, MissingResourcesError{tag + 100}) {
+		t.Errorf("expected a missing resources error, got %v", err)
+	}
+
+	if err := RemoveTerms(c, published, category); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// removing unrelated terms is ignored
+	if err := RemoveTerms(c, draft, tag); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if ids := termIds(published); !reflect.DeepEqual(ids, []int64{tag}) {
+		t.Errorf("expected the term %d, got %v", tag, ids)
+	}
+
+	if ids := termIds(draft); !reflect.DeepEqual(ids, []int64{category}) {
+		t.Errorf("expected the term %d, got %v", category, ids)
+	}
+
+	if n := testTermCount(t, c, category); n != 0 {
+		t.Errorf("expected the category to count no posts, got %d", n)
+	}
+
+	if n := testTermCount(t, c, tag); n != 1 {
+		t.Errorf("expected the tag to count 1 post, got %d", n)
+	}
+}
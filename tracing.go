@@ -0,0 +1,151 @@
+package wordpress
+
+import (
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"golang.org/x/net/context"
+)
+
+// instrumentationName identifies the package's spans to OpenTelemetry
+const instrumentationName = "github.com/ssttevee/go-wordpress"
+
+// Tracer starts the spans that trace the package's calls and queries
+//
+// It lets callers plug in their own tracing library in place of OpenTelemetry.
+type Tracer interface {
+	// StartSpan starts a child of the context's current span
+	StartSpan(c context.Context, name string) (context.Context, Span)
+
+	// SpanFromContext returns the context's current span, or nil if there is none
+	SpanFromContext(c context.Context) Span
+}
+
+// Span represents a single traced operation
+type Span interface {
+	End()
+
+	// IsRecording returns whether attributes added to the span are kept
+	IsRecording() bool
+
+	AddStringAttribute(key, value string)
+	AddInt64Attribute(key string, value int64)
+}
+
+// WithTracer returns a derived context whose spans are started by the tracer
+//
+// OpenTelemetry's global tracer provider is used by default,
+// which does nothing until a provider is configured.
+func WithTracer(parent context.Context, t Tracer) context.Context {
+	return context.WithValue(parent, tracerKey, t)
+}
+
+func tracer(c context.Context) Tracer {
+	if t, ok := c.Value(tracerKey).(Tracer); ok && t != nil {
+		return t
+	}
+
+	return openTelemetryTracer{otel.Tracer(instrumentationName)}
+}
+
+// WithoutTracing returns a derived context in which no spans are started
+// and no span attributes are recorded
+func WithoutTracing(parent context.Context) context.Context {
+	return context.WithValue(parent, tracingDisabledKey, true)
+}
+
+func tracingDisabled(c context.Context) bool {
+	disabled, _ := c.Value(tracingDisabledKey).(bool)
+	return disabled
+}
+
+// noopSpan is used when tracing is disabled or there is no current span
+type noopSpan struct{}
+
+func (noopSpan) End()                                {}
+func (noopSpan) IsRecording() bool                   { return false }
+func (noopSpan) AddStringAttribute(_, _ string)      {}
+func (noopSpan) AddInt64Attribute(_ string, _ int64) {}
+
+// startSpan starts a span unless tracing is disabled
+//
+// The returned span is never nil, so it is always safe to end.
+func startSpan(c context.Context, name string) (context.Context, Span) {
+	if tracingDisabled(c) {
+		return c, noopSpan{}
+	}
+
+	c, span := tracer(c).StartSpan(c, name)
+	if span == nil {
+		return c, noopSpan{}
+	}
+
+	if prefix, ok := c.Value(prefixKey).(string); ok {
+		spanString(span, "wp/table_prefix", prefix)
+	}
+
+	return c, span
+}
+
+// spanFromContext returns the current span, or a span that records nothing
+// if tracing is disabled or there is no current span
+func spanFromContext(c context.Context) Span {
+	if tracingDisabled(c) {
+		return noopSpan{}
+	}
+
+	if span := tracer(c).SpanFromContext(c); span != nil {
+		return span
+	}
+
+	return noopSpan{}
+}
+
+// spanString records the attribute only if the span is being recorded,
+// so that nothing is allocated otherwise
+func spanString(span Span, key, value string) {
+	if span.IsRecording() {
+		span.AddStringAttribute(key, value)
+	}
+}
+
+// spanInt64 records the attribute only if the span is being recorded
+func spanInt64(span Span, key string, value int64) {
+	if span.IsRecording() {
+		span.AddInt64Attribute(key, value)
+	}
+}
+
+// openTelemetryTracer is the default tracer
+type openTelemetryTracer struct {
+	tracer trace.Tracer
+}
+
+func (t openTelemetryTracer) StartSpan(c context.Context, name string) (context.Context, Span) {
+	c, span := t.tracer.Start(c, name)
+	return c, openTelemetrySpan{span}
+}
+
+func (openTelemetryTracer) SpanFromContext(c context.Context) Span {
+	return openTelemetrySpan{trace.SpanFromContext(c)}
+}
+
+type openTelemetrySpan struct {
+	span trace.Span
+}
+
+func (s openTelemetrySpan) End() {
+	s.span.End()
+}
+
+func (s openTelemetrySpan) IsRecording() bool {
+	return s.span.IsRecording()
+}
+
+func (s openTelemetrySpan) AddStringAttribute(key, value string) {
+	s.span.SetAttributes(attribute.String(key, value))
+}
+
+func (s openTelemetrySpan) AddInt64Attribute(key string, value int64) {
+	s.span.SetAttributes(attribute.Int64(key, value))
+}
@@ -0,0 +1,76 @@
+package wordpress
+
+import (
+	"testing"
+
+	"golang.org/x/net/context"
+)
+
+// recordingTracer keeps every attribute like a tracing library that exports its spans
+type recordingTracer struct{}
+
+func (recordingTracer) StartSpan(c context.Context, _ string) (context.Context, Span) {
+	span := &recordingSpan{}
+	return context.WithValue(c, recordingSpanKey{}, span), span
+}
+
+func (recordingTracer) SpanFromContext(c context.Context) Span {
+	if span, ok := c.Value(recordingSpanKey{}).(*recordingSpan); ok {
+		return span
+	}
+
+	return nil
+}
+
+type recordingSpanKey struct{}
+
+type recordingSpan struct {
+	attributes map[string]interface{}
+}
+
+func (s *recordingSpan) End()              {}
+func (s *recordingSpan) IsRecording() bool { return true }
+
+func (s *recordingSpan) AddStringAttribute(key, value string) {
+	s.add(key, value)
+}
+
+func (s *recordingSpan) AddInt64Attribute(key string, value int64) {
+	s.add(key, value)
+}
+
+func (s *recordingSpan) add(key string, value interface{}) {
+	if s.attributes == nil {
+		s.attributes = map[string]interface{}{}
+	}
+
+	s.attributes[key] = value
+}
+
+func BenchmarkGetOptionTracing(b *testing.B) {
+	wp := newTestWordPress(b)
+	wp.Tracer = recordingTracer{}
+
+	traced := NewContext(context.Background(), wp)
+	testOption(b, traced, "blogname", "Test")
+
+	contexts := []struct {
+		name string
+		c    context.Context
+	}{
+		{name: "traced", c: traced},
+		{name: "without tracing", c: WithoutTracing(traced)},
+	}
+
+	for _, bc := range contexts {
+		b.Run(bc.name, func(b *testing.B) {
+			b.ReportAllocs()
+
+			for i := 0; i < b.N; i++ {
+				if _, err := GetOption(bc.c, "blogname"); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
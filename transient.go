@@ -0,0 +1,88 @@
+package wordpress
+
+import (
+	"database/sql"
+	"strconv"
+	"time"
+
+	"github.com/elgris/sqrl"
+	"golang.org/x/net/context"
+)
+
+// option name prefixes of a transient's value and expiry
+const (
+	transientPrefix        = "_transient_"
+	transientTimeoutPrefix = "_transient_timeout_"
+)
+
+// GetTransient gets the value of the WordPress transient
+//
+// Returns false if the transient does not exist or has expired.
+// Like WordPress, expired transients are deleted when they are read.
+func GetTransient(c context.Context, key string) (string, bool, error) {
+	c, span := startSpan(c, "/wordpress.GetTransient")
+	defer span.End()
+
+	timeout, err := GetOptionInt(c, transientTimeoutPrefix+key)
+	if _, invalid := err.(*strconv.NumError); invalid || err == nil && int64(timeout) < time.Now().Unix() {
+		// an unparseable timeout is treated as expired, like WordPress does
+		if err := DeleteTransient(c, key); err != nil {
+			return "", false, err
+		}
+
+		return "", false, nil
+	} else if err != nil && err != sql.ErrNoRows {
+		return "", false, err
+	}
+
+	value, err := GetOption(c, transientPrefix+key)
+	if err == sql.ErrNoRows {
+		return "", false, nil
+	} else if err != nil {
+		return "", false, err
+	}
+
+	return value, true, nil
+}
+
+// SetTransient inserts or updates the WordPress transient
+//
+// The transient never expires if ttl is not positive.
+// Like WordPress, only transients that never expire are autoloaded.
+func SetTransient(c context.Context, key, value string, ttl time.Duration) error {
+	c, span := startSpan(c, "/wordpress.SetTransient")
+	defer span.End()
+
+	if ttl <= 0 {
+		if err := DeleteOption(c, transientTimeoutPrefix+key); err != nil {
+			return err
+		}
+
+		return SetOptionAutoload(c, transientPrefix+key, value, true)
+	}
+
+	expires := time.Now().Add(ttl).Unix()
+	if err := SetOptionAutoload(c, transientTimeoutPrefix+key, strconv.FormatInt(expires, 10), false); err != nil {
+		return err
+	}
+
+	return SetOptionAutoload(c, transientPrefix+key, value, false)
+}
+
+// DeleteTransient deletes the WordPress transient along with its expiry
+func DeleteTransient(c context.Context, key string) error {
+	c, span := startSpan(c, "/wordpress.DeleteTransient")
+	defer span.End()
+
+	stmt, args, err := sqrl.Delete(table(c, "options")).
+		Where(sqrl.Eq{"option_name": []string{transientPrefix + key, transientTimeoutPrefix + key}}).ToSql()
+	if err != nil {
+		return err
+	}
+
+	spanString(span, "wp/query", stmt)
+
+	_, err = database(c).ExecContext(c, stmt, args...)
+
+	return err
+}
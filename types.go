@@ -1,8 +1,10 @@
 package wordpress
 
 import (
+	"database/sql/driver"
 	"errors"
 	"strings"
+	"time"
 )
 
 // URLList represents a list of urls
@@ -13,14 +15,71 @@ import (
 type URLList []string
 
 // Scan formats incoming data from a sql database
-func (list URLList) Scan(src interface{}) error {
-	if str, ok := src.([]uint8); ok {
-		if len(str) > 0 {
-			list = append(list, strings.Split(string(str), " ")...)
-		}
+//
+// The urls may be separated by any amount of whitespace.
+func (list *URLList) Scan(src interface{}) error {
+	switch src := src.(type) {
+	case nil:
+		*list = nil
+	case []uint8:
+		*list = strings.Fields(string(src))
+	case string:
+		*list = strings.Fields(src)
+	default:
+		return errors.New("the source is not a string")
+	}
+
+	return nil
+}
+
+// Value formats the list for a sql database
+func (list URLList) Value() (driver.Value, error) {
+	return strings.Join(list, " "), nil
+}
+
+// zeroDate is how MySQL stores an unset date
+const zeroDate = "0000-00-00 00:00:00"
+
+// nullTime scans a date column into a time.Time
+//
+// Null and zero dates (i.e. `0000-00-00 00:00:00`) are scanned as the zero time
+// instead of failing the whole row.
+type nullTime time.Time
 
+// Scan formats incoming data from a sql database
+func (t *nullTime) Scan(src interface{}) error {
+	switch src := src.(type) {
+	case nil:
+		*t = nullTime{}
+	case time.Time:
+		*t = nullTime(src)
+	case []uint8:
+		return t.parse(string(src))
+	case string:
+		return t.parse(src)
+	default:
+		return errors.New("the source is not a time")
+	}
+
+	return nil
+}
+
+func (t *nullTime) parse(str string) error {
+	if str == "" || strings.HasPrefix(str, "0000-00-00") {
+		*t = nullTime{}
 		return nil
 	}
 
-	return errors.New("the source is not a string")
+	// ignore any fractional seconds
+	if len(str) > len(zeroDate) {
+		str = str[:len(zeroDate)]
+	}
+
+	parsed, err := time.Parse("2006-01-02 15:04:05", str)
+	if err != nil {
+		return err
+	}
+
+	*t = nullTime(parsed)
+	return nil
 }
@@ -0,0 +1,81 @@
+package wordpress
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestURLListRoundTrip(t *testing.T) {
+	const urls = "http://a.com http://b.com"
+
+	for _, src := range []interface{}{urls, []byte(urls)} {
+		var list URLList
+		if err := list.Scan(src); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if want := (URLList{"http://a.com", "http://b.com"}); !reflect.DeepEqual(list, want) {
+			t.Errorf("expected %T %q to scan as %q, got %q", src, src, want, list)
+		}
+
+		value, err := list.Value()
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if value != urls {
+			t.Errorf("expected the value to be %q, got %q", urls, value)
+		}
+	}
+}
+
+func TestNullTimeScan(t *testing.T) {
+	tests := []struct {
+		src  interface{}
+		want time.Time
+	}{
+		{src: nil},
+		{src: ""},
+		{src: zeroDate},
+		{src: []byte(zeroDate)},
+		{src: "2020-01-02 03:04:05", want: testDate},
+		{src: []byte("2020-01-02 03:04:05.000000"), want: testDate},
+		{src: testDate, want: testDate},
+	}
+
+	for _, tt := range tests {
+		var got time.Time
+		if err := (*nullTime)(&got).Scan(tt.src); err != nil {
+			t.Fatalf("unexpected error scanning %T %v: %v", tt.src, tt.src, err)
+		}
+
+		if !got.Equal(tt.want) {
+			t.Errorf("expected %T %v to scan as %v, got %v", tt.src, tt.src, tt.want, got)
+		}
+	}
+}
+
+func TestGetPostsZeroDate(t *testing.T) {
+	c := newTestContext(t)
+
+	// drafts have a zero gmt date until they are published
+	id := testObject(t, c, map[string]interface{}{
+		"post_title":  "Draft",
+		"post_status": string(PostStatusDraft),
+		"post_date":   testDate.Format("2006-01-02 15:04:05"),
+	})
+
+	posts, err := GetPosts(c, id)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if !posts[0].DateGmt.IsZero() {
+		t.Errorf("expected the zero date to scan as the zero time, got %v", posts[0].DateGmt)
+	}
+
+	if !posts[0].Date.Equal(testDate) {
+		t.Errorf("expected the date to be %v, got %v", testDate, posts[0].Date)
+	}
+}
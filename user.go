@@ -1,12 +1,14 @@
 package wordpress
 
 import (
-	"go.opencensus.io/trace"
 	"crypto/md5"
-	"encoding/base64"
+	"database/sql"
 	"fmt"
 	"github.com/elgris/sqrl"
+	"github.com/wulijun/go-php-serialize/phpserialize"
 	"golang.org/x/net/context"
+	"net/url"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
@@ -43,7 +45,7 @@ type UserQueryOptions struct {
 
 // GetUsers gets all user data from the database
 func GetUsers(c context.Context, userIds ...int64) ([]*User, error) {
-	c, span := trace.StartSpan(c, "/wordpress.GetUsers")
+	c, span := startSpan(c, "/wordpress.GetUsers")
 	defer span.End()
 
 	if len(userIds) == 0 {
@@ -52,29 +54,46 @@ func GetUsers(c context.Context, userIds ...int64) ([]*User, error) {
 
 	ids, idMap := dedupe(userIds)
 
-	stmt, args, err := sqrl.Select("u.ID", "u.user_nicename", "u.display_name", "um.meta_value", "u.user_email", "u.user_url", "u.user_registered").
-		From(table(c, "users") + " AS u").
-		Join(table(c, "usermeta") + " AS um ON um.user_id = u.ID").
-		Where(sqrl.Eq{"meta_key": "description"}).
+	ret := make([]*User, len(userIds))
+
+	var cached []*User
+	hitIds := getCached(c, userCacheKey, ids, &cached)
+	for i, id := range hitIds {
+		for _, index := range idMap[id] {
+			ret[index] = cached[i]
+		}
+	}
+
+	if ids = without(ids, hitIds); len(ids) == 0 {
+		return afterGetUsersFilters.apply(c, ret)
+	}
+
+	// users without a description are still found
+	stmt, args, err := sqrl.Select("u.ID", "u.user_nicename", "u.display_name", "COALESCE(um.meta_value, '')", "u.user_email", "u.user_url", "u.user_registered").
+		From(table(c, "users")+" AS u").
+		LeftJoin(table(c, "usermeta")+" AS um ON um.user_id = u.ID AND um.meta_key = ?", "description").
 		GroupBy("u.ID, um.meta_value").
 		Where(sqrl.Eq{"u.ID": ids}).ToSql()
 	if err != nil {
 		return nil, err
 	}
 
-	rows, err := database(c).Query(stmt, args...)
+	rows, err := database(c).QueryContext(c, stmt, args...)
 	if err != nil {
 		return nil, err
 	}
 
-	ret := make([]*User, len(userIds))
+	defer rows.Close()
+
 	for rows.Next() {
 		var u User
-		if err := rows.Scan(&u.Id, &u.Slug, &u.Name, &u.Description, &u.Email, &u.Website, &u.Registered); err != nil {
+		if err := rows.Scan(&u.Id, &u.Slug, &u.Name, &u.Description, &u.Email, &u.Website, (*nullTime)(&u.Registered)); err != nil {
 			return nil, err
 		}
 
-		u.Gravatar = fmt.Sprintf("%x", md5.Sum([]byte(strings.ToLower(strings.TrimSpace(u.Email)))))
+		u.Gravatar = gravatarHash(u.Email)
+
+		setCached(c, userCacheKey, u.Id, &u)
 
 		// insert into return set
 		for _, index := range idMap[u.Id] {
@@ -82,6 +101,10 @@ func GetUsers(c context.Context, userIds ...int64) ([]*User, error) {
 		}
 	}
 
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
 	var mre MissingResourcesError
 	for i, term := range ret {
 		if term == nil {
@@ -90,15 +113,99 @@ func GetUsers(c context.Context, userIds ...int64) ([]*User, error) {
 	}
 
 	if len(mre) > 0 {
+		return nil, mre
+	}
+
+	return afterGetUsersFilters.apply(c, ret)
+}
+
+// GravatarURL returns the https url of the user's Gravatar image
+//
+// The size is in pixels, and the default image is used when the user has no Gravatar,
+// either a url or one of Gravatar's keywords (i.e. mp, identicon, retro). Both are omitted if empty.
+//
+// Like WordPress, the hash is left empty if the user has no email,
+// so that Gravatar serves the default image.
+func (u *User) GravatarURL(size int, defaultImg string) string {
+	hash := u.Gravatar
+	if u.Email != "" {
+		hash = gravatarHash(u.Email)
+	}
+
+	params := url.Values{"r": {"g"}}
+	if size > 0 {
+		params.Set("s", strconv.Itoa(size))
+	}
+
+	if defaultImg != "" {
+		params.Set("d", defaultImg)
+	}
+
+	return "https://www.gravatar.com/avatar/" + hash + "?" + params.Encode()
+}
+
+// gravatarHash returns the md5 hash of the trimmed and lowercased email
+// that Gravatar identifies the user by, or an empty string if there is no email
+func gravatarHash(email string) string {
+	email = strings.ToLower(strings.TrimSpace(email))
+	if email == "" {
+		return ""
+	}
+
+	return fmt.Sprintf("%x", md5.Sum([]byte(email)))
+}
+
+// GetMeta gets the user's metadata from the database
+//
+// Returns all metadata if no metadata keys are given
+func (u *User) GetMeta(c context.Context, keys ...string) (map[string]string, error) {
+	c, span := startSpan(c, "/wordpress.User.GetMeta")
+	defer span.End()
+
+	q := sqrl.Select("meta_key", "meta_value").
+		From(table(c, "usermeta")).
+		Where(sqrl.Eq{"user_id": u.Id})
+
+	if len(keys) > 0 {
+		q = q.Where(sqrl.Eq{"meta_key": keys})
+	}
+
+	stmt, args, err := q.ToSql()
+	if err != nil {
 		return nil, err
 	}
 
-	return ret, nil
+	spanString(span, "wp/meta/query", stmt)
+
+	rows, err := database(c).QueryContext(c, stmt, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	meta := make(map[string]string)
+	for rows.Next() {
+		var key, val string
+		if err := rows.Scan(&key, &val); err != nil {
+			return nil, fmt.Errorf("User GetMeta - Scan: %w", err)
+		}
+
+		meta[key] = val
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	spanInt64(span, "wp/meta/count", int64(len(meta)))
+
+	return meta, nil
 }
 
 // QueryUsers returns the ids of the users that match the query
 func QueryUsers(c context.Context, opts *UserQueryOptions) (Iterator, error) {
-	c, span := trace.StartSpan(c, "/wordpress.QueryUsers")
+	c, span := startSpan(c, "/wordpress.QueryUsers")
 	defer span.End()
 
 	q := sqrl.Select("ID").From(table(c, "users")).OrderBy("ID ASC")
@@ -120,10 +227,12 @@ func QueryUsers(c context.Context, opts *UserQueryOptions) (Iterator, error) {
 	}
 
 	if opts.After != "" {
-		// ignore `q.After` if any errors occur
-		if b, err := base64.URLEncoding.DecodeString(opts.After); err == nil {
-			q = q.Where("ID > ?", string(b))
+		cur, err := decodeCursor(opts.After, "ID")
+		if err != nil {
+			return nil, err
 		}
+
+		q = q.Where("ID > ?", cur.Id)
 	}
 
 	if opts.Limit == 0 {
@@ -139,13 +248,15 @@ func QueryUsers(c context.Context, opts *UserQueryOptions) (Iterator, error) {
 		return nil, err
 	}
 
-	span.AddAttributes(trace.StringAttribute("wp/user/query", stmt))
+	spanString(span, "wp/user/query", stmt)
 
-	rows, err := database(c).Query(stmt, args...)
+	rows, err := database(c).QueryContext(c, stmt, args...)
 	if err != nil {
 		return nil, err
 	}
 
+	defer rows.Close()
+
 	var ids []int64
 	for rows.Next() {
 		var id int64
@@ -156,15 +267,19 @@ func QueryUsers(c context.Context, opts *UserQueryOptions) (Iterator, error) {
 		ids = append(ids, id)
 	}
 
-	span.AddAttributes(trace.Int64Attribute("wp/user/count", int64(len(ids))))
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
 
-	it := iteratorImpl{cursor: opts.After}
+	spanInt64(span, "wp/user/count", int64(len(ids)))
+
+	it := iteratorImpl{cursor: opts.After, length: len(ids)}
 
 	var counter int
 	it.next = func() (id int64, err error) {
 		if counter < len(ids) {
 			id = ids[counter]
-			it.cursor = base64.URLEncoding.EncodeToString([]byte(strconv.FormatInt(id, 10)))
+			it.cursor = encodeCursor("ID", strconv.FormatInt(id, 10), id)
 			counter++
 		} else {
 			return it.exit(Done)
@@ -175,3 +290,138 @@ func QueryUsers(c context.Context, opts *UserQueryOptions) (Iterator, error) {
 
 	return &it, nil
 }
+
+// Roles returns the names of the user's roles, i.e. administrator, editor, author
+func (u *User) Roles(c context.Context) ([]string, error) {
+	c, span := startSpan(c, "/wordpress.User.Roles")
+	defer span.End()
+
+	granted, err := u.capabilities(c)
+	if err != nil {
+		return nil, err
+	}
+
+	roles, err := getRoleCapabilities(c)
+	if err != nil {
+		return nil, err
+	}
+
+	var ret []string
+	for name, ok := range granted {
+		// individually granted capabilities are stored alongside the roles
+		if _, isRole := roles[name]; ok && (isRole || len(roles) == 0) {
+			ret = append(ret, name)
+		}
+	}
+
+	sort.Strings(ret)
+
+	return ret, nil
+}
+
+// HasCapability returns whether the user has the capability, i.e. edit_posts,
+// either granted individually or through one of their roles
+func (u *User) HasCapability(c context.Context, capability string) (bool, error) {
+	c, span := startSpan(c, "/wordpress.User.HasCapability")
+	defer span.End()
+
+	granted, err := u.capabilities(c)
+	if err != nil {
+		return false, err
+	}
+
+	// individually granted or denied capabilities take precedence over roles
+	if ok, exists := granted[capability]; exists {
+		return ok, nil
+	}
+
+	roles, err := getRoleCapabilities(c)
+	if err != nil {
+		return false, err
+	}
+
+	for name, ok := range granted {
+		if ok && roles[name][capability] {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// capabilities decodes the user's `<prefix>capabilities` metadata
+func (u *User) capabilities(c context.Context) (map[string]bool, error) {
+	key := table(c, "capabilities")
+
+	meta, err := u.GetMeta(c, key)
+	if err != nil {
+		return nil, err
+	}
+
+	ret := make(map[string]bool)
+	if meta[key] == "" {
+		return ret, nil
+	}
+
+	dec, err := phpserialize.Decode(meta[key])
+	if err != nil {
+		return nil, err
+	}
+
+	arr, _ := dec.(map[interface{}]interface{})
+	for name, value := range arr {
+		if name, ok := name.(string); ok {
+			ret[name] = phpTruthy(value)
+		}
+	}
+
+	return ret, nil
+}
+
+// getRoleCapabilities decodes the capabilities of each role from the `<prefix>user_roles` option
+func getRoleCapabilities(c context.Context) (map[string]map[string]bool, error) {
+	dec, err := GetOptionSerialized(c, table(c, "user_roles"))
+	if err == sql.ErrNoRows {
+		return map[string]map[string]bool{}, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	ret := make(map[string]map[string]bool)
+
+	roles, _ := dec.(map[interface{}]interface{})
+	for name, role := range roles {
+		name, ok := name.(string)
+		if !ok {
+			continue
+		}
+
+		ret[name] = make(map[string]bool)
+
+		role, _ := role.(map[interface{}]interface{})
+		caps, _ := role["capabilities"].(map[interface{}]interface{})
+		for capability, value := range caps {
+			if capability, ok := capability.(string); ok {
+				ret[name][capability] = phpTruthy(value)
+			}
+		}
+	}
+
+	return ret, nil
+}
+
+// phpTruthy returns whether the decoded PHP value is true, as PHP would cast it
+func phpTruthy(value interface{}) bool {
+	switch v := value.(type) {
+	case bool:
+		return v
+	case int64:
+		return v != 0
+	case float64:
+		return v != 0
+	case string:
+		return v != "" && v != "0"
+	}
+
+	return false
+}
@@ -0,0 +1,69 @@
+package wordpress
+
+import "testing"
+
+func TestGravatarURL(t *testing.T) {
+	tests := []struct {
+		name       string
+		user       User
+		size       int
+		defaultImg string
+		want       string
+	}{
+		{
+			name: "known hash",
+			user: User{Email: "myemailaddress@example.com"},
+			want: "https://www.gravatar.com/avatar/0bc83cb571cd1c50ba6f3e8a78ef1346?r=g",
+		},
+		{
+			name: "trimmed and lowercased",
+			user: User{Email: " MyEmailAddress@example.com "},
+			want: "https://www.gravatar.com/avatar/0bc83cb571cd1c50ba6f3e8a78ef1346?r=g",
+		},
+		{
+			name:       "size and default",
+			user:       User{Email: "myemailaddress@example.com"},
+			size:       96,
+			defaultImg: "mp",
+			want:       "https://www.gravatar.com/avatar/0bc83cb571cd1c50ba6f3e8a78ef1346?d=mp&r=g&s=96",
+		},
+		{
+			name: "precomputed hash",
+			user: User{Gravatar: "0bc83cb571cd1c50ba6f3e8a78ef1346"},
+			want: "https://www.gravatar.com/avatar/0bc83cb571cd1c50ba6f3e8a78ef1346?r=g",
+		},
+		{
+			name:       "blank email",
+			user:       User{Email: "  "},
+			defaultImg: "mp",
+			want:       "https://www.gravatar.com/avatar/?d=mp&r=g",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.user.GravatarURL(tt.size, tt.defaultImg); got != tt.want {
+				t.Errorf("expected %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestGetUsersGravatar(t *testing.T) {
+	c := newTestContext(t)
+
+	testExec(t, c, "INSERT INTO wp_users (ID, user_login, user_email) VALUES (1, 'a', ' MyEmailAddress@example.com '), (2, 'b', '')")
+
+	users, err := GetUsers(c, 1, 2)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if want := "0bc83cb571cd1c50ba6f3e8a78ef1346"; users[0].Gravatar != want {
+		t.Errorf("expected the hash of the normalized email %q, got %q", want, users[0].Gravatar)
+	}
+
+	if users[1].Gravatar != "" {
+		t.Errorf("expected no hash for a blank email, got %q", users[1].Gravatar)
+	}
+}
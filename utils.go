@@ -1,5 +1,10 @@
 package wordpress
 
+import (
+	"strings"
+	"sync"
+)
+
 func dedupe(ids []int64) (deduped []int64, idMap map[int64][]int) {
 	idMap = make(map[int64][]int)
 	for i, id := range ids {
@@ -12,3 +17,106 @@ func dedupe(ids []int64) (deduped []int64, idMap map[int64][]int) {
 
 	return
 }
+
+// without returns the ids that are not in the excluded ids
+func without(ids []int64, excluded []int64) []int64 {
+	skip := make(map[int64]bool)
+	for _, id := range excluded {
+		skip[id] = true
+	}
+
+	var ret []int64
+	for _, id := range ids {
+		if !skip[id] {
+			ret = append(ret, id)
+		}
+	}
+
+	return ret
+}
+
+// placeholders returns n comma-separated bind parameters (i.e. `?,?,?`)
+func placeholders(n int) string {
+	if n <= 0 {
+		return ""
+	}
+
+	return strings.Repeat("?,", n-1) + "?"
+}
+
+// expandIn returns an `IN` predicate on the column for hand-written sql
+//
+// An empty set of values matches nothing, rather than producing invalid sql.
+func expandIn(column string, values []int64) (string, []interface{}) {
+	if len(values) == 0 {
+		return "1=0", nil
+	}
+
+	args := make([]interface{}, len(values))
+	for i, value := range values {
+		args[i] = value
+	}
+
+	return column + " IN (" + placeholders(len(values)) + ")", args
+}
+
+// runLimited runs the tasks with at most limit running at the same time
+//
+// The first error is returned once all of the started tasks have finished,
+// and tasks that were not started yet are skipped.
+// A limit of 1 runs the tasks in order in the calling goroutine.
+func runLimited(limit int, tasks []func() error) error {
+	if limit == 1 {
+		for _, task := range tasks {
+			if err := task(); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}
+
+	if limit <= 0 || limit > len(tasks) {
+		limit = len(tasks)
+	}
+
+	queue := make(chan func() error)
+
+	var mu sync.Mutex
+	var firstErr error
+
+	var wg sync.WaitGroup
+	for i := 0; i < limit; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			for task := range queue {
+				if err := task(); err != nil {
+					mu.Lock()
+					if firstErr == nil {
+						firstErr = err
+					}
+					mu.Unlock()
+				}
+			}
+		}()
+	}
+
+	for _, task := range tasks {
+		mu.Lock()
+		failed := firstErr != nil
+		mu.Unlock()
+
+		if failed {
+			break
+		}
+
+		queue <- task
+	}
+
+	close(queue)
+	wg.Wait()
+
+	return firstErr
+}
@@ -0,0 +1,85 @@
+package wordpress
+
+import (
+	"errors"
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+func TestRunLimited(t *testing.T) {
+	var running, maxRunning, ran int32
+	var mu sync.Mutex
+
+	tasks := make([]func() error, 10)
+	for i := range tasks {
+		tasks[i] = func() error {
+			n := atomic.AddInt32(&running, 1)
+			defer atomic.AddInt32(&running, -1)
+
+			mu.Lock()
+			if n > maxRunning {
+				maxRunning = n
+			}
+			mu.Unlock()
+
+			atomic.AddInt32(&ran, 1)
+			return nil
+		}
+	}
+
+	if err := runLimited(3, tasks); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if ran != 10 {
+		t.Errorf("expected all 10 tasks to run, got %d", ran)
+	}
+
+	if maxRunning > 3 {
+		t.Errorf("expected at most 3 tasks at once, got %d", maxRunning)
+	}
+}
+
+func TestRunLimitedError(t *testing.T) {
+	errTask := errors.New("task failed")
+
+	for _, limit := range []int{1, 3} {
+		tasks := make([]func() error, 10)
+		for i := range tasks {
+			i := i
+			tasks[i] = func() error {
+				if i == 4 {
+					return errTask
+				}
+
+				return nil
+			}
+		}
+
+		if err := runLimited(limit, tasks); err != errTask {
+			t.Errorf("expected the task's error with a limit of %d, got %v", limit, err)
+		}
+	}
+}
+
+func TestExpandIn(t *testing.T) {
+	tests := []struct {
+		values []int64
+		pred   string
+		args   []interface{}
+	}{
+		{values: nil, pred: "1=0"},
+		{values: []int64{}, pred: "1=0"},
+		{values: []int64{1}, pred: "ID IN (?)", args: []interface{}{int64(1)}},
+		{values: []int64{1, 2, 3}, pred: "ID IN (?,?,?)", args: []interface{}{int64(1), int64(2), int64(3)}},
+	}
+
+	for _, tt := range tests {
+		pred, args := expandIn("ID", tt.values)
+		if pred != tt.pred || !reflect.DeepEqual(args, tt.args) {
+			t.Errorf("expected %v to expand to %q %v, got %q %v", tt.values, tt.pred, tt.args, pred, args)
+		}
+	}
+}
@@ -2,11 +2,13 @@ package wordpress
 
 import (
 	"database/sql"
+	"strconv"
+	"strings"
 
 	// WordPress needs mysql
-	"go.opencensus.io/trace"
 	"github.com/elgris/sqrl"
 	_ "github.com/go-sql-driver/mysql"
+	"github.com/wulijun/go-php-serialize/phpserialize"
 	"golang.org/x/net/context"
 )
 
@@ -15,13 +17,45 @@ type ctxKey int
 var (
 	databaseKey interface{} = ctxKey(0)
 	prefixKey   interface{} = ctxKey(1)
+
+	postExtrasKey      interface{} = ctxKey(2)
+	tracingDisabledKey interface{} = ctxKey(3)
+	cacheKey           interface{} = ctxKey(4)
+	queryCacheKey      interface{} = ctxKey(5)
+	dialectKey         interface{} = ctxKey(6)
+	blogKey            interface{} = ctxKey(7)
+	tracerKey          interface{} = ctxKey(8)
+	concurrencyKey     interface{} = ctxKey(9)
+	partialResultsKey  interface{} = ctxKey(10)
 )
 
+// globalTables are the tables shared by every blog of a multisite network
+var globalTables = map[string]bool{
+	"users":            true,
+	"usermeta":         true,
+	"blogs":            true,
+	"blogmeta":         true,
+	"blog_versions":    true,
+	"registration_log": true,
+	"signups":          true,
+	"site":             true,
+	"sitemeta":         true,
+}
+
 // WordPress represents access to the WordPress database
 type WordPress struct {
 	db *sql.DB
 
 	TablePrefix string
+
+	// Dialect is the flavour of SQL spoken by the database, MySQL if empty
+	Dialect Dialect
+
+	// DisableTracing prevents spans from being started for any context created by `NewContext`
+	DisableTracing bool
+
+	// Tracer starts the spans of any context created by `NewContext`, OpenTelemetry if nil
+	Tracer Tracer
 }
 
 // New creates and returns a new WordPress connection
@@ -30,12 +64,27 @@ func New(host, user, password, database string) (*WordPress, error) {
 		user += ":" + password
 	}
 
-	db, err := sql.Open("mysql", user+"@"+host+"/"+database+"?parseTime=true")
+	return NewFromDSN(user+"@"+host+"/"+database+"?parseTime=true", "")
+}
+
+// NewFromDSN creates and returns a new WordPress connection to the MySQL database
+//
+// The dsn is in the format of github.com/go-sql-driver/mysql,
+// e.g. "user:password@unix(/var/run/mysqld/mysqld.sock)/wordpress?tls=true"
+func NewFromDSN(dsn, prefix string) (*WordPress, error) {
+	db, err := sql.Open(string(DialectMySQL), dsn)
 	if err != nil {
 		return nil, err
 	}
 
-	return &WordPress{db: db}, nil
+	return NewWithDB(db, prefix), nil
+}
+
+// NewWithDB returns a WordPress that uses the already opened MySQL database
+//
+// Set `Dialect` if the database is not MySQL.
+func NewWithDB(db *sql.DB, prefix string) *WordPress {
+	return &WordPress{db: db, TablePrefix: prefix, Dialect: DialectMySQL}
 }
 
 // SetMaxOpenConns sets the max number open connections
@@ -52,23 +101,85 @@ func (wp *WordPress) Close() error {
 
 // NewContext returns a derived context containing the database connection
 func NewContext(parent context.Context, wp *WordPress) context.Context {
-	parent = context.WithValue(parent, databaseKey, wp.db)
+	db := &dialectDB{DB: wp.db}
+	if wp.Dialect == DialectPostgres {
+		db.placeholders = sqrl.Dollar
+	}
+
+	parent = context.WithValue(parent, databaseKey, db)
 	parent = context.WithValue(parent, prefixKey, wp.TablePrefix)
+	parent = context.WithValue(parent, dialectKey, wp.Dialect)
+
+	if wp.DisableTracing {
+		parent = WithoutTracing(parent)
+	} else if wp.Tracer != nil {
+		parent = WithTracer(parent, wp.Tracer)
+	}
 
 	return parent
 }
 
+// WithBlog returns a derived context whose queries are made against the tables of the blog
+// of a multisite network
+//
+// The main blog (id 1) uses the unnumbered tables, and users are shared by all blogs.
+func WithBlog(parent context.Context, blogId int64) context.Context {
+	return context.WithValue(parent, blogKey, blogId)
+}
+
+// WithConcurrency returns a derived context in which at most limit queries are run at the same time
+// by functions that load related data in parallel, such as `GetPosts`
+//
+// A limit of 1 runs the queries one after another in the calling goroutine, which is useful for debugging.
+// A limit that is not positive restores the default of `GetPostsConcurrency`.
+//
+// Each running query holds a connection, so the limit should not exceed the database's `SetMaxOpenConns`,
+// less any connections the caller itself holds (i.e. by iterating rows or in a transaction),
+// otherwise the queries wait for each other's connections.
+func WithConcurrency(parent context.Context, limit int) context.Context {
+	return context.WithValue(parent, concurrencyKey, limit)
+}
+
+func concurrency(c context.Context) int {
+	if limit, ok := c.Value(concurrencyKey).(int); ok && limit > 0 {
+		return limit
+	}
+
+	return GetPostsConcurrency
+}
+
+// WithPartialResults returns a derived context in which `GetPosts` and `GetCategories`
+// return the resources that exist, in order, along with a `MissingResourcesError` of those that do not,
+// instead of only the error
+func WithPartialResults(parent context.Context) context.Context {
+	return context.WithValue(parent, partialResultsKey, true)
+}
+
+func partialResults(c context.Context) bool {
+	partial, _ := c.Value(partialResultsKey).(bool)
+	return partial
+}
+
+func blog(c context.Context) int64 {
+	blogId, _ := c.Value(blogKey).(int64)
+	return blogId
+}
+
 func table(c context.Context, table string) string {
 	prefix, ok := c.Value(prefixKey).(string)
 	if !ok {
 		panic("non-wordpress context")
 	}
 
+	if blogId := blog(c); blogId > 1 && !globalTables[table] {
+		prefix += strconv.FormatInt(blogId, 10) + "_"
+	}
+
 	return prefix + table
 }
 
-func database(c context.Context) *sql.DB {
-	db, ok := c.Value(databaseKey).(*sql.DB)
+func database(c context.Context) *dialectDB {
+	db, ok := c.Value(databaseKey).(*dialectDB)
 	if !ok {
 		panic("non-wordpress context")
 	}
@@ -78,10 +189,10 @@ func database(c context.Context) *sql.DB {
 
 // GetOption returns the string value of the WordPress option
 func GetOption(c context.Context, name string) (string, error) {
-	c, span := trace.StartSpan(c, "/wordpress.GetOption")
+	c, span := startSpan(c, "/wordpress.GetOption")
 	defer span.End()
 
-	span.AddAttributes(trace.StringAttribute("wp/option/name", name))
+	spanString(span, "wp/option/name", name)
 
 	stmt, args, err := sqrl.Select("option_value").
 		From(table(c, "options")).
@@ -90,13 +201,117 @@ func GetOption(c context.Context, name string) (string, error) {
 		return "", err
 	}
 
-	span.AddAttributes(trace.StringAttribute("wp/query", stmt))
+	spanString(span, "wp/query", stmt)
 
 	var value string
-	err = database(c).QueryRow(stmt, args...).Scan(&value)
+	err = database(c).QueryRowContext(c, stmt, args...).Scan(&value)
 	if err != nil {
 		return "", err
 	}
 
 	return value, err
 }
+
+// GetOptionInt gets the WordPress option as an integer
+func GetOptionInt(c context.Context, name string) (int, error) {
+	value, err := GetOption(c, name)
+	if err != nil {
+		return 0, err
+	}
+
+	return strconv.Atoi(strings.TrimSpace(value))
+}
+
+// GetOptionBool gets the WordPress option as a boolean
+//
+// Only "1", "yes", and "true" are treated as true.
+func GetOptionBool(c context.Context, name string) (bool, error) {
+	value, err := GetOption(c, name)
+	if err != nil {
+		return false, err
+	}
+
+	switch strings.ToLower(strings.TrimSpace(value)) {
+	case "1", "yes", "true":
+		return true, nil
+	}
+
+	return false, nil
+}
+
+// GetOptionSerialized gets the WordPress option decoded from PHP's serialization format
+//
+// Arrays are decoded as `map[interface{}]interface{}`, and an empty option is decoded as nil.
+func GetOptionSerialized(c context.Context, name string) (interface{}, error) {
+	value, err := GetOption(c, name)
+	if err != nil || value == "" {
+		return nil, err
+	}
+
+	return phpserialize.Decode(value)
+}
+
+// SetOption inserts or updates the WordPress option
+//
+// New options are autoloaded, while existing options keep their autoload setting.
+func SetOption(c context.Context, name, value string) error {
+	c, span := startSpan(c, "/wordpress.SetOption")
+	defer span.End()
+
+	return setOption(c, name, value, "yes", "option_value")
+}
+
+// SetOptionAutoload inserts or updates the WordPress option along with whether
+// it is loaded by WordPress on every request
+func SetOptionAutoload(c context.Context, name, value string, autoload bool) error {
+	c, span := startSpan(c, "/wordpress.SetOptionAutoload")
+	defer span.End()
+
+	yesNo := "no"
+	if autoload {
+		yesNo = "yes"
+	}
+
+	return setOption(c, name, value, yesNo, "option_value", "autoload")
+}
+
+// setOption inserts the option, or updates the columns of the existing option
+func setOption(c context.Context, name, value, autoload string, columns ...string) error {
+	span := spanFromContext(c)
+
+	spanString(span, "wp/option/name", name)
+
+	stmt, args, err := sqrl.Insert(table(c, "options")).
+		Columns("option_name", "option_value", "autoload").
+		Values(name, value, autoload).
+		Suffix(upsert(c, "option_name", columns...)).ToSql()
+	if err != nil {
+		return err
+	}
+
+	spanString(span, "wp/query", stmt)
+
+	_, err = database(c).ExecContext(c, stmt, args...)
+
+	return err
+}
+
+// DeleteOption deletes the WordPress option
+func DeleteOption(c context.Context, name string) error {
+	c, span := startSpan(c, "/wordpress.DeleteOption")
+	defer span.End()
+
+	spanString(span, "wp/option/name", name)
+
+	stmt, args, err := sqrl.Delete(table(c, "options")).
+		Where(sqrl.Eq{"option_name": name}).ToSql()
+	if err != nil {
+		return err
+	}
+
+	spanString(span, "wp/query", stmt)
+
+	_, err = database(c).ExecContext(c, stmt, args...)
+
+	return err
+}
@@ -0,0 +1,444 @@
+package wordpress
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	// the tests run against an in-memory sqlite database with the WordPress schema
+	"github.com/mattn/go-sqlite3"
+	"golang.org/x/net/context"
+)
+
+// testSchema is the subset of the WordPress schema used by the tests, translated for sqlite
+const testSchema = `
+CREATE TABLE wp_options (
+	option_id INTEGER PRIMARY KEY AUTOINCREMENT,
+	option_name TEXT NOT NULL UNIQUE,
+	option_value TEXT NOT NULL DEFAULT '',
+	autoload TEXT NOT NULL DEFAULT 'yes'
+);
+
+CREATE TABLE wp_posts (
+	ID INTEGER PRIMARY KEY AUTOINCREMENT,
+	post_author INTEGER NOT NULL DEFAULT 0,
+	post_date TEXT NOT NULL DEFAULT '0000-00-00 00:00:00',
+	post_date_gmt TEXT NOT NULL DEFAULT '0000-00-00 00:00:00',
+	post_content TEXT NOT NULL DEFAULT '',
+	post_title TEXT NOT NULL DEFAULT '',
+	post_excerpt TEXT NOT NULL DEFAULT '',
+	post_status TEXT NOT NULL DEFAULT 'publish',
+	comment_status TEXT NOT NULL DEFAULT 'open',
+	ping_status TEXT NOT NULL DEFAULT 'open',
+	post_password TEXT NOT NULL DEFAULT '',
+	post_name TEXT NOT NULL DEFAULT '',
+	to_ping TEXT NOT NULL DEFAULT '',
+	pinged TEXT NOT NULL DEFAULT '',
+	post_modified TEXT NOT NULL DEFAULT '0000-00-00 00:00:00',
+	post_modified_gmt TEXT NOT NULL DEFAULT '0000-00-00 00:00:00',
+	post_content_filtered TEXT NOT NULL DEFAULT '',
+	post_parent INTEGER NOT NULL DEFAULT 0,
+	guid TEXT NOT NULL DEFAULT '',
+	menu_order INTEGER NOT NULL DEFAULT 0,
+	post_type TEXT NOT NULL DEFAULT 'post',
+	post_mime_type TEXT NOT NULL DEFAULT '',
+	comment_count INTEGER NOT NULL DEFAULT 0
+);
+
+CREATE TABLE wp_postmeta (
+	meta_id INTEGER PRIMARY KEY AUTOINCREMENT,
+	post_id INTEGER NOT NULL DEFAULT 0,
+	meta_key TEXT,
+	meta_value TEXT
+);
+
+CREATE TABLE wp_terms (
+	term_id INTEGER PRIMARY KEY AUTOINCREMENT,
+	name TEXT NOT NULL DEFAULT '',
+	slug TEXT NOT NULL DEFAULT '',
+	term_group INTEGER NOT NULL DEFAULT 0
+);
+
+CREATE TABLE wp_termmeta (
+	meta_id INTEGER PRIMARY KEY AUTOINCREMENT,
+	term_id INTEGER NOT NULL DEFAULT 0,
+	meta_key TEXT,
+	meta_value TEXT
+);
+
+CREATE TABLE wp_term_taxonomy (
+	term_taxonomy_id INTEGER PRIMARY KEY AUTOINCREMENT,
+	term_id INTEGER NOT NULL DEFAULT 0,
+	taxonomy TEXT NOT NULL DEFAULT '',
+	description TEXT NOT NULL DEFAULT '',
+	parent INTEGER NOT NULL DEFAULT 0,
+	count INTEGER NOT NULL DEFAULT 0
+);
+
+CREATE TABLE wp_term_relationships (
+	object_id INTEGER NOT NULL DEFAULT 0,
+	term_taxonomy_id INTEGER NOT NULL DEFAULT 0,
+	term_order INTEGER NOT NULL DEFAULT 0,
+	PRIMARY KEY (object_id, term_taxonomy_id)
+);
+
+CREATE TABLE wp_users (
+	ID INTEGER PRIMARY KEY AUTOINCREMENT,
+	user_login TEXT NOT NULL DEFAULT '',
+	user_pass TEXT NOT NULL DEFAULT '',
+	user_nicename TEXT NOT NULL DEFAULT '',
+	user_email TEXT NOT NULL DEFAULT '',
+	user_url TEXT NOT NULL DEFAULT '',
+	user_registered TEXT NOT NULL DEFAULT '0000-00-00 00:00:00',
+	user_activation_key TEXT NOT NULL DEFAULT '',
+	user_status INTEGER NOT NULL DEFAULT 0,
+	display_name TEXT NOT NULL DEFAULT ''
+);
+
+CREATE TABLE wp_usermeta (
+	umeta_id INTEGER PRIMARY KEY AUTOINCREMENT,
+	user_id INTEGER NOT NULL DEFAULT 0,
+	meta_key TEXT,
+	meta_value TEXT
+);
+`
+
+func init() {
+	sql.Register("sqlite3_mysql", &mysqlDriver{})
+}
+
+// mysqlDriver is a sqlite driver that translates the MySQL-only statements used for writing,
+// binds arguments and returns text like the MySQL driver
+type mysqlDriver struct {
+	sqlite3.SQLiteDriver
+}
+
+func (d *mysqlDriver) Open(name string) (driver.Conn, error) {
+	conn, err := d.SQLiteDriver.Open(name)
+	if err != nil {
+		return nil, err
+	}
+
+	return &mysqlConn{conn.(*sqlite3.SQLiteConn)}, nil
+}
+
+type mysqlConn struct {
+	*sqlite3.SQLiteConn
+}
+
+func (conn *mysqlConn) Prepare(stmt string) (driver.Stmt, error) {
+	return conn.SQLiteConn.Prepare(translateMySQL(stmt))
+}
+
+func (conn *mysqlConn) PrepareContext(c context.Context, stmt string) (driver.Stmt, error) {
+	return conn.SQLiteConn.PrepareContext(c, translateMySQL(stmt))
+}
+
+func (conn *mysqlConn) QueryContext(c context.Context, stmt string, args []driver.NamedValue) (driver.Rows, error) {
+	rows, err := conn.SQLiteConn.QueryContext(c, translateMySQL(stmt), mysqlArgs(args))
+	if err != nil {
+		return nil, err
+	}
+
+	return mysqlRows{rows}, nil
+}
+
+func (conn *mysqlConn) ExecContext(c context.Context, stmt string, args []driver.NamedValue) (driver.Result, error) {
+	return conn.SQLiteConn.ExecContext(c, translateMySQL(stmt), mysqlArgs(args))
+}
+
+// mysqlArgs binds times like the MySQL driver does by default, converted to UTC
+func mysqlArgs(args []driver.NamedValue) []driver.NamedValue {
+	for i, arg := range args {
+		if t, ok := arg.Value.(time.Time); ok {
+			args[i].Value = t.UTC().Format(mysqlDateFormat)
+		}
+	}
+
+	return args
+}
+
+var regexpInsertedValue = regexp.MustCompile(`VALUES\((\w+)\)`)
+
+// translateMySQL rewrites the row locks and upserts, which sqlite does not support in MySQL's syntax
+//
+// sqlite locks the whole database for writing anyway.
+func translateMySQL(stmt string) string {
+	stmt = strings.TrimSuffix(stmt, " FOR UPDATE")
+
+	if i := strings.Index(stmt, " ON DUPLICATE KEY UPDATE "); i >= 0 {
+		update := stmt[i+len(" ON DUPLICATE KEY UPDATE "):]
+		stmt = stmt[:i] + " ON CONFLICT DO UPDATE SET " + regexpInsertedValue.ReplaceAllString(update, "excluded.$1")
+	}
+
+	return stmt
+}
+
+// mysqlRows returns text as bytes, like the MySQL driver does
+type mysqlRows struct {
+	driver.Rows
+}
+
+func (rows mysqlRows) Next(dest []driver.Value) error {
+	if err := rows.Rows.Next(dest); err != nil {
+		return err
+	}
+
+	for i, value := range dest {
+		if str, ok := value.(string); ok {
+			dest[i] = []byte(str)
+		}
+	}
+
+	return nil
+}
+
+var testDatabases int64
+
+// newTestContext returns a context for a new empty database
+func newTestContext(tb testing.TB) context.Context {
+	tb.Helper()
+
+	wp := newTestWordPress(tb)
+	wp.DisableTracing = true
+
+	return NewContext(context.Background(), wp)
+}
+
+// newTestWordPress returns a connection to a new empty database
+func newTestWordPress(tb testing.TB) *WordPress {
+	tb.Helper()
+
+	// every connection to a named shared-cache database sees the same data
+	name := "file:wordpress" + strconv.FormatInt(atomic.AddInt64(&testDatabases, 1), 10) + "?mode=memory&cache=shared"
+
+	db, err := sql.Open("sqlite3_mysql", name)
+	if err != nil {
+		tb.Fatal(err)
+	}
+
+	tb.Cleanup(func() { db.Close() })
+
+	if _, err := db.Exec(testSchema); err != nil {
+		tb.Fatal(err)
+	}
+
+	return NewWithDB(db, "wp_")
+}
+
+// testExec runs the statement against the test database, failing the test on error
+func testExec(tb testing.TB, c context.Context, stmt string, args ...interface{}) sql.Result {
+	tb.Helper()
+
+	res, err := database(c).ExecContext(c, stmt, args...)
+	if err != nil {
+		tb.Fatal(err)
+	}
+
+	return res
+}
+
+// testPost inserts a published post and returns its id
+func testPost(tb testing.TB, c context.Context, title string, date time.Time) int64 {
+	tb.Helper()
+
+	return testObject(tb, c, map[string]interface{}{
+		"post_title":    title,
+		"post_name":     strings.ToLower(strings.Replace(title, " ", "-", -1)),
+		"post_date":     date.Format("2006-01-02 15:04:05"),
+		"post_date_gmt": date.UTC().Format("2006-01-02 15:04:05"),
+	})
+}
+
+// testObject inserts a row into the posts table and returns its id
+func testObject(tb testing.TB, c context.Context, values map[string]interface{}) int64 {
+	tb.Helper()
+
+	var columns []string
+	var args []interface{}
+	for column, value := range values {
+		columns = append(columns, column)
+		args = append(args, value)
+	}
+
+	stmt := "INSERT INTO wp_posts (" + strings.Join(columns, ", ") + ") VALUES (" + placeholders(len(columns)) + ")"
+	if len(columns) == 0 {
+		stmt = "INSERT INTO wp_posts DEFAULT VALUES"
+	}
+
+	id, err := testExec(tb, c, stmt, args...).LastInsertId()
+	if err != nil {
+		tb.Fatal(err)
+	}
+
+	return id
+}
+
+// testTerm inserts a term of the taxonomy and returns its id
+func testTerm(tb testing.TB, c context.Context, taxonomy Taxonomy, name string, parent int64) int64 {
+	tb.Helper()
+
+	slug := strings.ToLower(strings.Replace(name, " ", "-", -1))
+
+	id, err := testExec(tb, c, "INSERT INTO wp_terms (name, slug) VALUES (?, ?)", name, slug).LastInsertId()
+	if err != nil {
+		tb.Fatal(err)
+	}
+
+	testExec(tb, c, "INSERT INTO wp_term_taxonomy (term_taxonomy_id, term_id, taxonomy, parent) VALUES (?, ?, ?, ?)",
+		id, id, string(taxonomy), parent)
+
+	return id
+}
+
+// testRelate adds the object to the terms, whose term taxonomy ids are the same as their term ids
+func testRelate(tb testing.TB, c context.Context, objectId int64, termIds ...int64) {
+	tb.Helper()
+
+	for _, termId := range termIds {
+		testExec(tb, c, "INSERT INTO wp_term_relationships (object_id, term_taxonomy_id) VALUES (?, ?)", objectId, termId)
+		testExec(tb, c, "UPDATE wp_term_taxonomy SET count = count + 1 WHERE term_taxonomy_id = ?", termId)
+	}
+}
+
+// testOption sets the option
+func testOption(tb testing.TB, c context.Context, name, value string) {
+	tb.Helper()
+
+	testExec(tb, c, "INSERT INTO wp_options (option_name, option_value) VALUES (?, ?)", name, value)
+}
+
+// testMeta sets the metadata of the object
+func testMeta(tb testing.TB, c context.Context, objectId int64, meta map[string]string) {
+	tb.Helper()
+
+	for key, value := range meta {
+		testExec(tb, c, "INSERT INTO wp_postmeta (post_id, meta_key, meta_value) VALUES (?, ?, ?)", objectId, key, value)
+	}
+}
+
+// testTermCount returns the stored count of the term
+func testTermCount(tb testing.TB, c context.Context, termId int64) int {
+	tb.Helper()
+
+	var count int
+	if err := database(c).QueryRowContext(c, "SELECT count FROM wp_term_taxonomy WHERE term_id = ?", termId).Scan(&count); err != nil {
+		tb.Fatal(err)
+	}
+
+	return count
+}
+
+// testDate is the date of posts whose date does not matter
+var testDate = time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+
+func TestQueryTimeout(t *testing.T) {
+	c := newTestContext(t)
+
+	postId := testPost(t, c, "Hello World", testDate)
+	catId := testTerm(t, c, TaxonomyCategory, "News", 0)
+	testOption(t, c, "blogname", "Test")
+
+	c, cancel := context.WithTimeout(c, 0)
+	defer cancel()
+
+	calls := map[string]func() error{
+		"GetOption": func() error {
+			_, err := GetOption(c, "blogname")
+			return err
+		},
+		"GetPosts": func() error {
+			_, err := GetPosts(c, postId)
+			return err
+		},
+		"QueryPosts": func() error {
+			_, err := QueryPosts(c, &ObjectQueryOptions{})
+			return err
+		},
+		"GetCategories": func() error {
+			_, err := GetCategories(c, catId)
+			return err
+		},
+	}
+
+	for name, call := range calls {
+		if err := call(); !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("expected %s to fail with the context error, got %v", name, err)
+		}
+	}
+}
+
+func TestSetOption(t *testing.T) {
+	// sqlite understands the upserts of both dialects
+	for _, d := range []Dialect{DialectMySQL, DialectPostgres} {
+		t.Run(string(d), func(t *testing.T) {
+			wp := newTestWordPress(t)
+			wp.DisableTracing = true
+			wp.Dialect = d
+
+			c := NewContext(context.Background(), wp)
+
+			option := func(name string) (string, string) {
+				t.Helper()
+
+				var value, autoload string
+				if err := database(c).QueryRowContext(c, "SELECT option_value, autoload FROM wp_options WHERE option_name = ?", name).Scan(&value, &autoload); err != nil {
+					t.Fatal(err)
+				}
+
+				return value, autoload
+			}
+
+			if err := SetOptionAutoload(c, "blogname", "Test", false); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			// existing options keep their autoload setting
+			if err := SetOption(c, "blogname", "Updated"); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if value, autoload := option("blogname"); value != "Updated" || autoload != "no" {
+				t.Errorf("expected the updated value without autoload, got %q %q", value, autoload)
+			}
+
+			if err := SetOptionAutoload(c, "blogname", "Autoloaded", true); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if value, err := GetOption(c, "blogname"); err != nil || value != "Autoloaded" {
+				t.Errorf("expected %q, got %q, %v", "Autoloaded", value, err)
+			}
+
+			if _, autoload := option("blogname"); autoload != "yes" {
+				t.Errorf("expected the option to be autoloaded, got %q", autoload)
+			}
+
+			if err := SetOption(c, "blogdescription", "Just another site"); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if value, autoload := option("blogdescription"); value != "Just another site" || autoload != "yes" {
+				t.Errorf("expected a new autoloaded option, got %q %q", value, autoload)
+			}
+
+			if err := DeleteOption(c, "blogname"); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if value, err := GetOption(c, "blogname"); err != sql.ErrNoRows {
+				t.Errorf("expected the option to be deleted, got %q, %v", value, err)
+			}
+
+			if value, err := GetOption(c, "blogdescription"); err != nil || value != "Just another site" {
+				t.Errorf("expected the other option to be kept, got %q, %v", value, err)
+			}
+		})
+	}
+}
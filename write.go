@@ -0,0 +1,354 @@
+package wordpress
+
+import (
+	"database/sql"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/elgris/sqrl"
+	"golang.org/x/net/context"
+)
+
+// mysqlDateFormat is the format of the datetime columns
+const mysqlDateFormat = "2006-01-02 15:04:05"
+
+// inTx runs fn in a transaction, which is committed if fn succeeds and rolled back otherwise
+func inTx(c context.Context, fn func(tx *sql.Tx) error) error {
+	tx, err := database(c).BeginTx(c, nil)
+	if err != nil {
+		return err
+	}
+
+	if err := fn(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// execTx executes the statement built by q in the transaction
+func execTx(c context.Context, tx *sql.Tx, q sqrl.Sqlizer) (sql.Result, error) {
+	stmt, args, err := q.ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	spanString(spanFromContext(c), "wp/write/query", stmt)
+
+	return tx.ExecContext(c, stmt, args...)
+}
+
+// formatDate formats the wall clock of the time for a datetime column
+//
+// Local times are stored as-is rather than being converted by the driver.
+func formatDate(t time.Time) string {
+	if t.IsZero() {
+		return "0000-00-00 00:00:00"
+	}
+
+	return t.Format(mysqlDateFormat)
+}
+
+func openClosed(open bool) string {
+	if open {
+		return "open"
+	}
+
+	return "closed"
+}
+
+// objectColumns returns the `posts` columns of the object, excluding its id
+func objectColumns(obj *Object) map[string]interface{} {
+	return map[string]interface{}{
+		"post_author":           obj.AuthorId,
+		"post_date":             formatDate(obj.Date),
+		"post_date_gmt":         formatDate(obj.DateGmt),
+		"post_content":          obj.Content,
+		"post_title":            obj.Title,
+		"post_excerpt":          obj.Excerpt,
+		"post_status":           string(obj.Status),
+		"comment_status":        openClosed(obj.CommentStatus),
+		"ping_status":           openClosed(obj.PingStatus),
+		"post_password":         obj.Password,
+		"post_name":             obj.Name,
+		"to_ping":               strings.Join(obj.ToPing, " "),
+		"pinged":                strings.Join(obj.Pinged, " "),
+		"post_modified":         formatDate(obj.Modified),
+		"post_modified_gmt":     formatDate(obj.ModifiedGmt),
+		"post_content_filtered": obj.ContentFiltered,
+		"post_parent":           obj.ParentId,
+		"guid":                  obj.Guid,
+		"menu_order":            obj.MenuOrder,
+		"post_type":             string(obj.Type),
+		"post_mime_type":        obj.MimeType,
+		"comment_count":         obj.CommentCount,
+	}
+}
+
+// setMetaTx writes all of the metadata in the transaction
+func setMetaTx(c context.Context, tx *sql.Tx, objectId int64, meta map[string]string, opts *SetMetaOptions) error {
+	if len(meta) == 0 {
+		return nil
+	}
+
+	keys := make([]string, 0, len(meta))
+	for key := range meta {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+
+	return setMetaMulti(c, tx, objectId, keys, meta, opts)
+}
+
+// setObjectTerms makes the given terms the only terms of the taxonomy related to the object
+//
+// The counts of all terms that were added or removed are recalculated.
+func setObjectTerms(c context.Context, tx *sql.Tx, objectId int64, taxonomy Taxonomy, termIds []int64) error {
+	termIds, _ = dedupe(termIds)
+
+	wanted := make(map[int64]bool)
+	if len(termIds) > 0 {
+		ttIds, err := termTaxonomyIds(c, tx, taxonomy, termIds)
+		if err != nil {
+			return err
+		}
+
+		for _, ttId := range ttIds {
+			wanted[ttId] = true
+		}
+	}
+
+	stmt, args, err := sqrl.Select("tr.term_taxonomy_id").
+		From(table(c, "term_relationships") + " AS tr").
+		Join(table(c, "term_taxonomy") + " AS tt ON tt.term_taxonomy_id = tr.term_taxonomy_id").
+		Where(sqrl.Eq{"tr.object_id": objectId, "tt.taxonomy": string(taxonomy)}).ToSql()
+	if err != nil {
+		return err
+	}
+
+	existing, err := queryIdsTx(c, tx, stmt, args)
+	if err != nil {
+		return err
+	}
+
+	var removed, changed []int64
+	for _, ttId := range existing {
+		if wanted[ttId] {
+			delete(wanted, ttId)
+		} else {
+			removed = append(removed, ttId)
+		}
+	}
+
+	if len(removed) > 0 {
+		if _, err := execTx(c, tx, sqrl.Delete(table(c, "term_relationships")).
+			Where(sqrl.Eq{"object_id": objectId, "term_taxonomy_id": removed})); err != nil {
+			return err
+		}
+
+		changed = append(changed, removed...)
+	}
+
+	if len(wanted) > 0 {
+		insert := sqrl.Insert(table(c, "term_relationships")).Columns("object_id", "term_taxonomy_id")
+		for ttId := range wanted {
+			insert = insert.Values(objectId, ttId)
+			changed = append(changed, ttId)
+		}
+
+		if _, err := execTx(c, tx, insert); err != nil {
+			return err
+		}
+	}
+
+	return updateTermCounts(c, tx, changed)
+}
+
+// termTaxonomyIds returns the term taxonomy ids of the terms in the taxonomy,
+// or in any taxonomy if taxonomy is empty
+func termTaxonomyIds(c context.Context, tx *sql.Tx, taxonomy Taxonomy, termIds []int64) ([]int64, error) {
+	q := sqrl.Select("term_taxonomy_id", "term_id").
+		From(table(c, "term_taxonomy")).
+		Where(sqrl.Eq{"term_id": termIds})
+
+	if taxonomy != "" {
+		q = q.Where(sqrl.Eq{"taxonomy": string(taxonomy)})
+	}
+
+	stmt, args, err := q.ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := tx.QueryContext(c, stmt, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	var ttIds []int64
+	found := make(map[int64]bool)
+	for rows.Next() {
+		var ttId, termId int64
+		if err := rows.Scan(&ttId, &termId); err != nil {
+			return nil, err
+		}
+
+		ttIds = append(ttIds, ttId)
+		found[termId] = true
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	var mre MissingResourcesError
+	for _, termId := range termIds {
+		if !found[termId] {
+			mre = append(mre, termId)
+		}
+	}
+
+	if len(mre) > 0 {
+		return nil, mre
+	}
+
+	return ttIds, nil
+}
+
+// objectTermTaxonomyIdsTx returns the term taxonomy ids of all the terms related to the object
+func objectTermTaxonomyIdsTx(c context.Context, tx *sql.Tx, objectId int64) ([]int64, error) {
+	stmt, args, err := sqrl.Select("term_taxonomy_id").
+		From(table(c, "term_relationships")).
+		Where(sqrl.Eq{"object_id": objectId}).ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	return queryIdsTx(c, tx, stmt, args)
+}
+
+// updateTermCounts recalculates the number of published objects related to each term taxonomy
+func updateTermCounts(c context.Context, tx *sql.Tx, ttIds []int64) error {
+	if len(ttIds) == 0 {
+		return nil
+	}
+
+	count := sqrl.Expr("(SELECT COUNT(*) FROM "+table(c, "term_relationships")+" AS tr "+
+		"JOIN "+table(c, "posts")+" AS p ON p.ID = tr.object_id "+
+		"WHERE tr.term_taxonomy_id = "+table(c, "term_taxonomy")+".term_taxonomy_id AND p.post_status = ?)", string(PostStatusPublish))
+
+	if _, err := execTx(c, tx, sqrl.Update(table(c, "term_taxonomy")).
+		Set("count", count).
+		Where(sqrl.Eq{"term_taxonomy_id": ttIds})); err != nil {
+		return err
+	}
+
+	// the cached terms have stale counts
+	if cacheManager(c) == nil {
+		return nil
+	}
+
+	stmt, args, err := sqrl.Select("term_id").
+		From(table(c, "term_taxonomy")).
+		Where(sqrl.Eq{"term_taxonomy_id": ttIds}).ToSql()
+	if err != nil {
+		return err
+	}
+
+	termIds, err := queryIdsTx(c, tx, stmt, args)
+	if err != nil {
+		return err
+	}
+
+	return InvalidateTerm(c, termIds...)
+}
+
+// getObjectTx reads the object in the transaction, locking its row until the transaction ends
+func getObjectTx(c context.Context, tx *sql.Tx, id int64) (*Object, error) {
+	stmt, args, err := sqrl.Select(objectColumnNames...).
+		From(table(c, "posts")).
+		Where(sqrl.Eq{"ID": id}).
+		Suffix("FOR UPDATE").ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	spanString(spanFromContext(c), "wp/write/query", stmt)
+
+	rows, err := tx.QueryContext(c, stmt, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	if !rows.Next() {
+		if err := rows.Err(); err != nil {
+			return nil, err
+		}
+
+		return nil, MissingResourcesError{id}
+	}
+
+	return scanObject(rows)
+}
+
+// getMetaTx reads all of the object's metadata in the transaction
+func getMetaTx(c context.Context, tx *sql.Tx, objectId int64) (map[string]string, error) {
+	stmt, args, err := sqrl.Select("meta_key", "meta_value").
+		From(table(c, "postmeta")).
+		Where(sqrl.Eq{"post_id": objectId}).ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	spanString(spanFromContext(c), "wp/write/query", stmt)
+
+	rows, err := tx.QueryContext(c, stmt, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	meta := make(map[string]string)
+	for rows.Next() {
+		var key, value string
+		if err := rows.Scan(&key, &value); err != nil {
+			return nil, err
+		}
+
+		meta[key] = value
+	}
+
+	return meta, rows.Err()
+}
+
+// queryIdsTx returns the first column of every row as an id
+func queryIdsTx(c context.Context, tx *sql.Tx, stmt string, args []interface{}) ([]int64, error) {
+	spanString(spanFromContext(c), "wp/write/query", stmt)
+
+	rows, err := tx.QueryContext(c, stmt, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+
+		ids = append(ids, id)
+	}
+
+	return ids, rows.Err()
+}